@@ -3,8 +3,9 @@ package config
 import "time"
 
 type Clients struct {
-	Implementation string `yaml:"implementation"`
-	SecretDuration string `yaml:"secretDuration"`
+	Implementation    string `yaml:"implementation"`
+	SecretDuration    string `yaml:"secretDuration"`
+	SecretGracePeriod string `yaml:"secretGracePeriod"`
 }
 
 func (c *Clients) GetSecretDuration() time.Duration {
@@ -15,3 +16,13 @@ func (c *Clients) GetSecretDuration() time.Duration {
 		return time.Hour
 	}
 }
+
+//GetSecretGracePeriod returns the time a rotated secret is still accepted
+func (c *Clients) GetSecretGracePeriod() time.Duration {
+	dur, err := time.ParseDuration(c.SecretGracePeriod)
+	if err == nil {
+		return dur
+	} else {
+		return time.Hour * 24
+	}
+}
@@ -8,13 +8,17 @@ import (
 )
 
 type FixedConfig struct {
-	Server   Server     `yaml:"server"`
-	Logger   zap.Config `yaml:"logger"`
-	Users    Users      `yaml:"users"`
-	Clients  Clients    `yaml:"clients"`
-	Groups   Groups     `yaml:"groups"`
-	Sessions Sessions   `yaml:"sessions"`
-	Tokens   Tokens     `yaml:"tokens"`
+	Server    Server     `yaml:"server"`
+	Logger    zap.Config `yaml:"logger"`
+	Users     Users      `yaml:"users"`
+	Clients   Clients    `yaml:"clients"`
+	Groups    Groups     `yaml:"groups"`
+	Sessions  Sessions   `yaml:"sessions"`
+	Cookies   Cookies    `yaml:"cookies"`
+	Tokens    Tokens     `yaml:"tokens"`
+	Keys      Keys       `yaml:"keys"`
+	Passwords Passwords  `yaml:"passwords"`
+	Scim      Scim       `yaml:"scim"`
 }
 
 var (
@@ -24,7 +28,7 @@ var (
 )
 
 func Init(configFilePath string) {
-	log, _ := zap.NewDevelopment()
+	log, _ = zap.NewDevelopment()
 	if len(configFilePath) == 0 {
 		configFilePath = "config.yml"
 	}
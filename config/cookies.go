@@ -0,0 +1,102 @@
+package config
+
+import (
+	"encoding/base64"
+	"go.uber.org/zap"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type Cookies struct {
+	Keys           []CookieKey `yaml:"keys"`
+	RotationPeriod string      `yaml:"rotationPeriod"`
+	Secure         *bool       `yaml:"secure"`
+	HttpOnly       *bool       `yaml:"httpOnly"`
+	SameSite       string      `yaml:"sameSite"`
+	Domain         string      `yaml:"domain"`
+	MaxAge         string      `yaml:"maxAge"`
+}
+
+//CookieKey is a key pair of the session cookies shared by all the nodes. Keys are base64 encoded
+type CookieKey struct {
+	AuthenticationKey string `yaml:"authenticationKey"` //HMAC key, 32 or 64 bytes are recommended
+	EncryptionKey     string `yaml:"encryptionKey"`     //AES key of 16, 24 or 32 bytes, empty to only sign the cookies
+}
+
+//GetKeyPairs returns the decoded authentication and encryption keys of the configured key pairs. The first pair
+//encodes the cookies and all of them decode the cookies. Key pairs that can not be decoded are ignored
+func (c *Cookies) GetKeyPairs() [][]byte {
+	var pairs [][]byte
+	for i, key := range c.Keys {
+		authenticationKey, err := base64.StdEncoding.DecodeString(key.AuthenticationKey)
+		if err != nil || len(authenticationKey) == 0 {
+			log.Error("can not decode cookie authentication key from config. The key pair will be ignored", zap.Int("key", i))
+			continue
+		}
+		encryptionKey, err := base64.StdEncoding.DecodeString(key.EncryptionKey)
+		if err != nil {
+			log.Error("can not decode cookie encryption key from config. The key pair will be ignored", zap.Int("key", i))
+			continue
+		}
+		switch len(encryptionKey) {
+		case 0:
+			encryptionKey = nil
+		case 16, 24, 32:
+		default:
+			log.Error("cookie encryption key must have 16, 24 or 32 bytes. The key pair will be ignored", zap.Int("key", i))
+			continue
+		}
+		pairs = append(pairs, authenticationKey, encryptionKey)
+	}
+	return pairs
+}
+
+//GetRotationPeriod returns the time a generated cookie key pair encodes cookies before it is replaced by a new pair
+func (c *Cookies) GetRotationPeriod() time.Duration {
+	dur, err := time.ParseDuration(c.RotationPeriod)
+	if err == nil && dur > 0 {
+		return dur
+	}
+	if len(c.RotationPeriod) > 0 {
+		log.Error("can not parse cookie key rotation period from config. 30 days will be used")
+	}
+	return time.Hour * 24 * 30
+}
+
+//GetSecure returns true if the cookies are only sent over https. Defaults to true
+func (c *Cookies) GetSecure() bool {
+	return c.Secure == nil || *c.Secure
+}
+
+//GetHttpOnly returns true if the cookies are not available to scripts. Defaults to true
+func (c *Cookies) GetHttpOnly() bool {
+	return c.HttpOnly == nil || *c.HttpOnly
+}
+
+//GetSameSite returns the SameSite attribute of the cookies, lax, strict or none. Defaults to lax
+func (c *Cookies) GetSameSite() http.SameSite {
+	switch strings.ToLower(c.SameSite) {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	case "lax", "":
+		return http.SameSiteLaxMode
+	default:
+		log.Error("cookie SameSite attribute not supported. lax will be used", zap.String("sameSite", c.SameSite))
+		return http.SameSiteLaxMode
+	}
+}
+
+//GetMaxAge returns the time a cookie is kept by the browser. 0 keeps the cookies until the browser is closed
+func (c *Cookies) GetMaxAge() time.Duration {
+	dur, err := time.ParseDuration(c.MaxAge)
+	if err == nil && dur >= 0 {
+		return dur
+	}
+	if len(c.MaxAge) > 0 {
+		log.Error("can not parse cookie max age from config. 12 hours will be used")
+	}
+	return time.Hour * 12
+}
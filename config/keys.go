@@ -0,0 +1,60 @@
+package config
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type Keys struct {
+	Implementation       string `yaml:"implementation"`
+	Algorithm            string `yaml:"algorithm"`
+	RotationPeriod       string `yaml:"rotationPeriod"`
+	PrepublicationPeriod string `yaml:"prepublicationPeriod"`
+}
+
+//GetAlgorithm returns the algorithm of new signing keys, RS256 or ES256
+func (k *Keys) GetAlgorithm() string {
+	switch k.Algorithm {
+	case "RS256", "ES256":
+		return k.Algorithm
+	case "":
+		return "RS256"
+	default:
+		log.Error("signing key algorithm not supported. RS256 will be used", zap.String("algorithm", k.Algorithm))
+		return "RS256"
+	}
+}
+
+//GetRotationPeriod returns the time a signing key is used before it is retired and replaced by a new key
+func (k *Keys) GetRotationPeriod() time.Duration {
+	dur, err := time.ParseDuration(k.RotationPeriod)
+	if err == nil && dur > 0 {
+		return dur
+	}
+	if len(k.RotationPeriod) > 0 {
+		log.Error("can not parse signing key rotation period from config. 30 days will be used")
+	}
+	return time.Hour * 24 * 30
+}
+
+//GetPrepublicationPeriod returns the time the next signing key is published before it signs tokens. It is limited to
+//half of the rotation period
+func (k *Keys) GetPrepublicationPeriod() time.Duration {
+	limit := k.GetRotationPeriod() / 2
+	if len(k.PrepublicationPeriod) == 0 {
+		if limit < time.Hour*24 {
+			return limit
+		}
+		return time.Hour * 24
+	}
+	dur, err := time.ParseDuration(k.PrepublicationPeriod)
+	if err != nil || dur <= 0 {
+		log.Error("can not parse signing key prepublication period from config. 24 hours will be used")
+		dur = time.Hour * 24
+	}
+	if dur > limit {
+		return limit
+	}
+	return dur
+}
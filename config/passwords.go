@@ -0,0 +1,10 @@
+package config
+
+type Passwords struct {
+	Algorithm         string `yaml:"algorithm"`
+	BcryptCost        int    `yaml:"bcryptCost"`
+	Argon2Memory      uint32 `yaml:"argon2Memory"`
+	Argon2Iterations  uint32 `yaml:"argon2Iterations"`
+	Argon2Parallelism uint8  `yaml:"argon2Parallelism"`
+	PBKDF2Iterations  int    `yaml:"pbkdf2Iterations"`
+}
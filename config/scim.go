@@ -0,0 +1,109 @@
+package config
+
+import (
+	"go.uber.org/zap"
+	"strings"
+)
+
+type Scim struct {
+	MaxResults         int             `yaml:"maxResults"`
+	BulkMaxOperations  int             `yaml:"bulkMaxOperations"`
+	BulkMaxPayloadSize int             `yaml:"bulkMaxPayloadSize"`
+	Extensions         []ScimExtension `yaml:"extensions"`
+}
+
+//ScimExtension is a schema extension of the user resource declared by the deployment
+type ScimExtension struct {
+	Schema      string          `yaml:"schema"` //schema URI, e.g. urn:example:params:scim:schemas:extension:acme:2.0:User
+	Name        string          `yaml:"name"`
+	Description string          `yaml:"description"`
+	Required    bool            `yaml:"required"` //every user must have the extension
+	Attributes  []ScimAttribute `yaml:"attributes"`
+}
+
+//ScimAttribute is a single-valued or multi-valued attribute of simple type of a schema extension
+type ScimAttribute struct {
+	Name            string   `yaml:"name"`
+	Type            string   `yaml:"type"` //string, boolean, decimal, integer, dateTime, binary or reference
+	Description     string   `yaml:"description"`
+	MultiValued     bool     `yaml:"multiValued"`
+	Required        bool     `yaml:"required"`
+	CaseExact       bool     `yaml:"caseExact"`
+	Mutability      string   `yaml:"mutability"` //readWrite, immutable or readOnly
+	Uniqueness      string   `yaml:"uniqueness"` //none or server
+	CanonicalValues []string `yaml:"canonicalValues"`
+}
+
+//GetMaxResults returns the maximum number of resources returned by a list request
+func (s *Scim) GetMaxResults() int {
+	if s.MaxResults > 0 {
+		return s.MaxResults
+	}
+	return 100
+}
+
+//GetBulkMaxOperations returns the maximum number of operations of a bulk request
+func (s *Scim) GetBulkMaxOperations() int {
+	if s.BulkMaxOperations > 0 {
+		return s.BulkMaxOperations
+	}
+	return 1000
+}
+
+//GetBulkMaxPayloadSize returns the maximum size in bytes of the body of a bulk request
+func (s *Scim) GetBulkMaxPayloadSize() int {
+	if s.BulkMaxPayloadSize > 0 {
+		return s.BulkMaxPayloadSize
+	}
+	return 1048576
+}
+
+//GetExtensions returns the valid user schema extensions. Extensions without schema URI and attributes with an unknown
+//name or type are ignored, unknown mutability and uniqueness values are replaced by readWrite and none
+func (s *Scim) GetExtensions() []ScimExtension {
+	extensions := make([]ScimExtension, 0, len(s.Extensions))
+	for _, extension := range s.Extensions {
+		if !strings.HasPrefix(strings.ToLower(extension.Schema), "urn:") {
+			log.Error("scim extension schema must be an urn. The extension will be ignored", zap.String("schema", extension.Schema))
+			continue
+		}
+		attributes := make([]ScimAttribute, 0, len(extension.Attributes))
+		for _, attribute := range extension.Attributes {
+			if len(attribute.Name) == 0 || strings.ContainsAny(attribute.Name, ":. ") {
+				log.Error("scim extension attribute name not valid. The attribute will be ignored", zap.String("schema", extension.Schema), zap.String("name", attribute.Name))
+				continue
+			}
+			switch attribute.Type {
+			case "":
+				attribute.Type = "string"
+			case "string", "boolean", "decimal", "integer", "dateTime", "binary", "reference":
+			default:
+				log.Error("scim extension attribute type not supported. The attribute will be ignored", zap.String("name", attribute.Name), zap.String("type", attribute.Type))
+				continue
+			}
+			switch attribute.Mutability {
+			case "readWrite", "immutable", "readOnly":
+			case "":
+				attribute.Mutability = "readWrite"
+			default:
+				log.Error("scim extension attribute mutability not supported. readWrite will be used", zap.String("name", attribute.Name), zap.String("mutability", attribute.Mutability))
+				attribute.Mutability = "readWrite"
+			}
+			switch attribute.Uniqueness {
+			case "none", "server":
+			case "":
+				attribute.Uniqueness = "none"
+			default:
+				log.Error("scim extension attribute uniqueness not supported. none will be used", zap.String("name", attribute.Name), zap.String("uniqueness", attribute.Uniqueness))
+				attribute.Uniqueness = "none"
+			}
+			attributes = append(attributes, attribute)
+		}
+		extension.Attributes = attributes
+		if len(extension.Name) == 0 {
+			extension.Name = extension.Schema[strings.LastIndex(extension.Schema, ":")+1:]
+		}
+		extensions = append(extensions, extension)
+	}
+	return extensions
+}
@@ -6,3 +6,8 @@ type Server struct {
 	Certificate string `yaml:"certificate"`
 	PrivateKey  string `yaml:"privateKey"`
 }
+
+//GetIssuer returns the https URL identifying this server as token issuer
+func (s *Server) GetIssuer() string {
+	return "https://" + s.Hostname + ":" + s.Port
+}
@@ -1,5 +1,46 @@
 package config
 
-type Sessions struct{
-	Implementation	string	`yaml:"implementation"`
+import "time"
+
+type Sessions struct {
+	Implementation  string `yaml:"implementation"`
+	IdleTimeout     string `yaml:"idleTimeout"`
+	AbsoluteTimeout string `yaml:"absoluteTimeout"`
+	SweepInterval   string `yaml:"sweepInterval"`
+}
+
+//GetIdleTimeout returns the time a session is kept without requests. 0 keeps idle sessions
+func (s *Sessions) GetIdleTimeout() time.Duration {
+	dur, err := time.ParseDuration(s.IdleTimeout)
+	if err == nil && dur >= 0 {
+		return dur
+	}
+	if len(s.IdleTimeout) > 0 {
+		log.Error("can not parse session idle timeout from config. 30 minutes will be used")
+	}
+	return time.Minute * 30
+}
+
+//GetAbsoluteTimeout returns the time a session is kept after the login. 0 keeps sessions until logout
+func (s *Sessions) GetAbsoluteTimeout() time.Duration {
+	dur, err := time.ParseDuration(s.AbsoluteTimeout)
+	if err == nil && dur >= 0 {
+		return dur
+	}
+	if len(s.AbsoluteTimeout) > 0 {
+		log.Error("can not parse session absolute timeout from config. 12 hours will be used")
+	}
+	return time.Hour * 12
+}
+
+//GetSweepInterval returns the time between the removals of expired sessions. 0 disables the removals
+func (s *Sessions) GetSweepInterval() time.Duration {
+	dur, err := time.ParseDuration(s.SweepInterval)
+	if err == nil && dur >= 0 {
+		return dur
+	}
+	if len(s.SweepInterval) > 0 {
+		log.Error("can not parse session sweep interval from config. 10 minutes will be used")
+	}
+	return time.Minute * 10
 }
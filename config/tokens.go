@@ -2,12 +2,19 @@ package config
 
 import (
 	"time"
+
+	"go.uber.org/zap"
 )
 
 type Tokens struct {
 	Implementation  string `yaml:"implementation"`
 	AccessDuration  string `yaml:"accessDuration"`
 	RefreshDuration string `yaml:"refreshDuration"`
+	IDTokenDuration string `yaml:"idTokenDuration"`
+	Format          string `yaml:"format"`
+	Resource        string `yaml:"resource"`
+	Entropy         int    `yaml:"entropy"`
+	Encoding        string `yaml:"encoding"`
 }
 
 func (t *Tokens) GetAccessDuration() time.Duration {
@@ -20,6 +27,38 @@ func (t *Tokens) GetAccessDuration() time.Duration {
 	}
 }
 
+//GetFormat returns the default access token format, opaque or jwt
+func (t *Tokens) GetFormat() string {
+	switch t.Format {
+	case "opaque", "jwt":
+		return t.Format
+	case "":
+		return "opaque"
+	default:
+		log.Error("access token format not supported. opaque will be used", zap.String("format", t.Format))
+		return "opaque"
+	}
+}
+
+func (t *Tokens) GetIDTokenDuration() time.Duration {
+	dur, err := time.ParseDuration(t.IDTokenDuration)
+	if err == nil {
+		return dur
+	} else {
+		log.Error("can not parse id_token duration from config. 1 hour will be used")
+		return time.Hour
+	}
+}
+
+//GetResource returns the default audience of the access tokens requested without resource indicator. The issuer is
+//used if no resource is configured
+func (t *Tokens) GetResource() string {
+	if len(t.Resource) == 0 {
+		return IAM.Server.GetIssuer()
+	}
+	return t.Resource
+}
+
 func (t *Tokens) GetRefreshDuration() time.Duration {
 	dur, err := time.ParseDuration(t.RefreshDuration)
 	if err == nil {
@@ -85,6 +85,7 @@ Location: http://example.com/cb#access_token=2YotnFZFEjr1zCsicMWpAA&state=xyz&to
 
 type TokenUnit struct {
 	Active         bool
+	Audience       string    //resource the access token is intended for
 	ClientID       uuid.UUID //client_id of the Relying Party as an client value
 	ExpirationTime time.Time //Expiration time on or after which the ID TokenUnit MUST NOT be accepted for processing
 	IssuedAt       time.Time
@@ -95,17 +96,23 @@ type TokenUnit struct {
 	OwnerID        uuid.UUID //user_id A locally unique and never reassigned identifier within the Issuer for the End-User
 	ParentToken    []byte
 	Token          []byte        //access_token or refresh_token
+	TokenFormat    TokenFormat   //opaque or jwt
+	TokenID        string        //jti of JWT access tokens
 	TokenAuthType  TokenAuthType //bearer, mac
 	TokenHintType  TokenHintType //access_token, refresh_token
 }
 
 type AccessTokenOptions struct {
 	AddRefreshToken bool      //include refresh TokenUnit
+	Audience        string    //resource the access token is intended for
 	ClientID        uuid.UUID //client_id
 	Issuer          string    //server host
+	Nonce           string    //OpenID Connect nonce to include in the ID Token
 	Scope           []byte
-	State           string    //client State
-	OwnerID         uuid.UUID //user_id
+	State           string            //client State
+	OwnerID         uuid.UUID         //user_id
+	SigningKey      *token.SigningKey //key used to sign JWT access tokens
+	TokenFormat     TokenFormat       //opaque or jwt access token
 }
 
 type AccessTokenHint struct {
@@ -118,8 +125,9 @@ type AccessTokenResponse struct {
 	TokenAuthType string `json:"token_type"`
 	ExpiresIn     int64  `json:"expires_in"`
 	RefreshToken  string `json:"refresh_token,omitempty"`
-	Scope         string `json:"Scope,omitempty"`
-	State         string `json:"State,omitempty"`
+	IDToken       string `json:"id_token,omitempty"`
+	Scope         string `json:"scope,omitempty"`
+	State         string `json:"state,omitempty"`
 }
 
 /**
@@ -174,16 +182,17 @@ Location: https://client.example.com/cb#error=access_denied&state=xyz
 */
 
 type AccessTokenErrorResponse struct {
-	Error            string `json:error`
-	ErrorDescription string `json:error_description`
-	ErrorURI         string `json:error_uri`
-	State            string `json:State`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+	ErrorURI         string `json:"error_uri,omitempty"`
+	State            string `json:"state,omitempty"`
 }
 
 func NewTokenSet(opt *AccessTokenOptions, accessDuration time.Duration, refreshDuration time.Duration) (accessToken *TokenUnit, refreshToken *TokenUnit) {
 	creationTime := time.Now()
 	accessToken = &TokenUnit{
 		Active:         true,
+		Audience:       opt.Audience,
 		ClientID:       opt.ClientID,
 		ExpirationTime: creationTime.Add(accessDuration),
 		IssuedAt:       creationTime,
@@ -194,9 +203,16 @@ func NewTokenSet(opt *AccessTokenOptions, accessDuration time.Duration, refreshD
 		OwnerID:        opt.OwnerID,
 		ParentToken:    nil,
 		Token:          token.GetToken(),
+		TokenFormat:    OpaqueTokenFormat,
 		TokenAuthType:  NewTokenAuthType("Bearer"),
 		TokenHintType:  NewTokenHintType("access_token"),
 	}
+	//self-contained access tokens can be validated by resource servers without introspection
+	if opt.TokenFormat == JWTTokenFormat {
+		if !accessToken.setJWT(opt) {
+			return nil, nil
+		}
+	}
 	if !opt.AddRefreshToken {
 		return accessToken, nil
 	}
@@ -213,6 +229,7 @@ func NewTokenSet(opt *AccessTokenOptions, accessDuration time.Duration, refreshD
 		OwnerID:        opt.OwnerID,
 		ParentToken:    accessToken.Token,
 		Token:          token.GetToken(),
+		TokenFormat:    OpaqueTokenFormat,
 		TokenAuthType:  NewTokenAuthType("Bearer"),
 		TokenHintType:  NewTokenHintType("refresh_token"),
 	}
@@ -220,6 +237,25 @@ func NewTokenSet(opt *AccessTokenOptions, accessDuration time.Duration, refreshD
 	return accessToken, refreshToken
 }
 
+//setJWT replaces the opaque token value with a signed RFC9068 JWT
+func (t *TokenUnit) setJWT(opt *AccessTokenOptions) bool {
+	if opt.SigningKey == nil {
+		return false
+	}
+	jti, err := uuid.NewV4()
+	if err != nil {
+		return false
+	}
+	t.TokenID = jti.String()
+	jwt, err := opt.SigningKey.SignWithType(t.GetAccessTokenClaims(), AccessTokenJWTType)
+	if err != nil {
+		return false
+	}
+	t.Token = []byte(jwt)
+	t.TokenFormat = JWTTokenFormat
+	return true
+}
+
 func NewRefreshToken(accessToken *TokenUnit, duration time.Duration) (refreshToken *TokenUnit) {
 	creationTime := time.Now()
 	refreshToken = &TokenUnit{
@@ -234,6 +270,7 @@ func NewRefreshToken(accessToken *TokenUnit, duration time.Duration) (refreshTok
 		OwnerID:        accessToken.OwnerID,
 		ParentToken:    accessToken.Token,
 		Token:          token.GetToken(),
+		TokenFormat:    OpaqueTokenFormat,
 		TokenAuthType:  NewTokenAuthType("Bearer"),
 		TokenHintType:  NewTokenHintType("refresh_token"),
 	}
@@ -287,6 +324,7 @@ func (t *TokenUnit) GetIntrospectionResponse() (response *IntrospectionResponse)
 	}
 	response = &IntrospectionResponse{
 		Active:        t.Active,
+		Audience:      t.Audience,
 		ClientID:      t.GetClient().String(),
 		Expires:       t.GetExpirationTime(),
 		IssuedAt:      t.GetIssuedAt(),
@@ -315,6 +353,12 @@ func (t *TokenUnit) GetScope() (scope string) {
 	return string(t.Scope)
 }
 
+//HasScope returns true if the given scope value was granted to the token
+func (t *TokenUnit) HasScope(scope string) bool {
+	_, ok := getScopesMap(string(t.Scope))[scope]
+	return ok
+}
+
 func (t *TokenUnit) GetResourceOwner() (owner uuid.UUID) {
 	return t.OwnerID
 }
@@ -0,0 +1,52 @@
+package oauth2
+
+/**
+RFC9068 - JSON Web Token (JWT) Profile for OAuth 2.0 Access Tokens
+
+	iss
+		REQUIRED. Issuer Identifier of the authorization server.
+	exp
+		REQUIRED. Expiration time on or after which the access token MUST NOT be accepted for processing.
+	aud
+		REQUIRED. Audience of the access token, the resource the token is intended for.
+	sub
+		REQUIRED. Resource owner the token was issued for. In client credentials grant it is the client itself.
+	client_id
+		REQUIRED. The OAuth 2.0 client that requested the token.
+	iat
+		REQUIRED. Time at which the JWT was issued.
+	jti
+		REQUIRED. Unique identifier of the JWT.
+	scope
+		Scope granted to the access token.
+
+The JWT header "typ" value MUST be "at+jwt".
+*/
+type AccessTokenClaims struct {
+	Issuer   string `json:"iss"`
+	Expires  int64  `json:"exp"`
+	Audience string `json:"aud"`
+	Subject  string `json:"sub"`
+	ClientID string `json:"client_id"`
+	IssuedAt int64  `json:"iat"`
+	TokenID  string `json:"jti"`
+	Scope    string `json:"scope,omitempty"`
+}
+
+//AccessTokenJWTType is the JWT header type of access tokens
+const AccessTokenJWTType = "at+jwt"
+
+//GetAccessTokenClaims returns the RFC9068 claims of the access token
+func (t *TokenUnit) GetAccessTokenClaims() *AccessTokenClaims {
+	claims := &AccessTokenClaims{
+		Issuer:   t.Issuer,
+		Expires:  t.GetExpirationTime(),
+		Audience: t.Audience,
+		Subject:  t.OwnerID.String(),
+		ClientID: t.ClientID.String(),
+		IssuedAt: t.GetIssuedAt(),
+		TokenID:  t.TokenID,
+		Scope:    t.GetScope(),
+	}
+	return claims
+}
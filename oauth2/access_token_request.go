@@ -1,5 +1,7 @@
 package oauth2
 
+import "net/url"
+
 /**
 Code Grant Access TokenUnit Request
 
@@ -15,6 +17,9 @@ Section 4.1.1, and their values MUST be identical.
 
 client_id: REQUIRED, if the client is not authenticating with the authorization server as described in Section 3.2.1.
 
+resource: OPTIONAL. RFC8707 absolute URI of the protected resource the access token is intended for, accepted by all the
+token requests. The configured default resource is the audience of the access token if it is omitted.
+
 For example, the client makes the following HTTP request using TLS (with extra line breaks for display purposes only):
 POST /token HTTP/1.1
 Host: server.example.com
@@ -23,10 +28,12 @@ Content-Type: application/x-www-form-urlencoded
 grant_type=authorization_code&code=SplxlOBeZQQYbYS6WxSbIA&redirect_uri=https%3A%2F%2Fclient%2Eexample%2Ecom%2Fcb
 */
 type AuthorizationCodeAccessTokenRequest struct {
-	ClientID    string `schema:"client_id,required"`
-	Code        string `schema:"code,required"`
-	GrantType   string `schema:"grant_type,required"`
-	RedirectURI string `schema:"redirect_uri,required"`
+	ClientID     string `schema:"client_id,required"`
+	Code         string `schema:"code,required"`
+	CodeVerifier string `schema:"code_verifier"` //RFC7636 proof key for code exchange
+	GrantType    string `schema:"grant_type,required"`
+	RedirectURI  string `schema:"redirect_uri,required"`
+	Resource     string `schema:"resource"` //RFC8707 resource indicator
 }
 
 /**
@@ -53,7 +60,8 @@ The authorization server MUST authenticate the client.
 */
 type ClientCredentialsAccessTokenRequest struct {
 	GrantType string `schema:"grant_type,required"`
-	Scope     string `schema:"Scope"`
+	Resource  string `schema:"resource"` //RFC8707 resource indicator
+	Scope     string `schema:"scope"`
 }
 
 /**
@@ -87,7 +95,8 @@ type OwnerPasswordAccessTokenRequest struct {
 	GrantType string `schema:"grant_type,required"`
 	Username  string `schema:"username,required"`
 	Password  string `schema:"password,required"`
-	Scope     string `schema:"Scope"`
+	Resource  string `schema:"resource"` //RFC8707 resource indicator
+	Scope     string `schema:"scope"`
 }
 
 /*
@@ -133,7 +142,14 @@ refresh token included by the client in the request.
 type RefreshAccessTokenRequest struct {
 	GrantType    string `schema:"grant_type,required"`
 	RefreshToken string `schema:"refresh_token,required"`
-	Scope        string `schema:"Scope"`
+	Resource     string `schema:"resource"` //RFC8707 resource indicator
+	Scope        string `schema:"scope"`
+}
+
+//IsValidResource returns true if the RFC8707 resource indicator is an absolute URI without fragment
+func IsValidResource(resource string) bool {
+	uri, err := url.Parse(resource)
+	return err == nil && uri.IsAbs() && len(uri.Fragment) == 0
 }
 
 func (atr *AuthorizationCodeAccessTokenRequest) GetGrantType() GrantType {
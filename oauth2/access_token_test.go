@@ -0,0 +1,84 @@
+package oauth2
+
+import (
+	"bounzr/iam/token"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+func TestNewTokenSetJWT(t *testing.T) {
+	token.Init()
+	key, err := token.NewSigningKey(token.ES256)
+	if err != nil {
+		t.Fatalf("can not create signing key: %v", err)
+	}
+	options := &AccessTokenOptions{
+		AddRefreshToken: true,
+		Audience:        "https://api.example.com",
+		ClientID:        uuid.Must(uuid.NewV4()),
+		Issuer:          "https://localhost:8443",
+		Scope:           []byte("openid profile"),
+		OwnerID:         uuid.Must(uuid.NewV4()),
+		SigningKey:      key,
+		TokenFormat:     JWTTokenFormat,
+	}
+	access, refresh := NewTokenSet(options, time.Minute, time.Hour)
+	if access == nil || refresh == nil {
+		t.Fatalf("want access and refresh token got nil")
+	}
+	if strings.Count(string(access.Token), ".") != 2 {
+		t.Errorf("want JWT access token got %s", access.Token)
+	}
+	if refresh.TokenFormat != OpaqueTokenFormat {
+		t.Errorf("want opaque refresh token got %s", refresh.TokenFormat.String())
+	}
+	payload, err := token.Verify(string(access.Token), key.PrivateKey.Public())
+	if err != nil {
+		t.Fatalf("want valid signature got %v", err)
+	}
+	claims := &AccessTokenClaims{}
+	if err = json.Unmarshal(payload, claims); err != nil {
+		t.Fatalf("can not decode claims: %v", err)
+	}
+	if claims.Subject != options.OwnerID.String() || claims.ClientID != options.ClientID.String() {
+		t.Errorf("want sub=%s client_id=%s got sub=%s client_id=%s", options.OwnerID, options.ClientID, claims.Subject, claims.ClientID)
+	}
+	if claims.Audience != options.Audience || access.GetIntrospectionResponse().Audience != options.Audience {
+		t.Errorf("want aud=%s got %s", options.Audience, claims.Audience)
+	}
+	if claims.Scope != "openid profile" || claims.Issuer != options.Issuer {
+		t.Errorf("unexpected scope=%s iss=%s", claims.Scope, claims.Issuer)
+	}
+	if len(claims.TokenID) == 0 || claims.TokenID != access.TokenID {
+		t.Errorf("want jti=%s got %s", access.TokenID, claims.TokenID)
+	}
+	if claims.Expires != access.GetExpirationTime() || claims.IssuedAt != access.GetIssuedAt() {
+		t.Errorf("want exp and iat of the access token")
+	}
+
+	options.SigningKey = nil
+	access, refresh = NewTokenSet(options, time.Minute, time.Hour)
+	if access != nil || refresh != nil {
+		t.Errorf("want no tokens without signing key")
+	}
+}
+
+func TestIsValidResource(t *testing.T) {
+	resources := map[string]bool{
+		"https://api.example.com":           true,
+		"https://api.example.com/users?a=b": true,
+		"urn:example:resource":              true,
+		"/users":                            false,
+		"https://api.example.com#users":     false,
+		"":                                  false,
+	}
+	for resource, valid := range resources {
+		if IsValidResource(resource) != valid {
+			t.Errorf("%s: want valid %v", resource, valid)
+		}
+	}
+}
@@ -5,7 +5,7 @@ import (
 	"strings"
 	"time"
 
-	"bounzr/iam/utils"
+	"bounzr/iam/token"
 )
 
 /*
@@ -85,7 +85,11 @@ type AuthorizationCode struct {
 	RedirectionURI string
 	Scope          []byte
 	State          string
+	Nonce          string    //OpenID Connect nonce from the authorization request
 	OwnerID        uuid.UUID //resources owner
+	//RFC7636 code challenge to be verified with the code_verifier of the access token request
+	CodeChallenge       string
+	CodeChallengeMethod string
 }
 
 type AuthorizationCodeResponse struct {
@@ -103,9 +107,9 @@ type AuthorizationError struct{
 
 //todo in case of wrong value throw an AuthorizationError
 //NewAuthorizationCode returns an new authorization code and its authorization code response
-func NewAuthorizationCode(ownerID uuid.UUID, authReq *AuthorizationRequest) *AuthorizationCode {
+func NewAuthorizationCode(ownerID uuid.UUID, authReq *AuthorizationRequest) (*AuthorizationCode, error) {
 
-	code := utils.GetRandomString(22)
+	code := token.GetTokenString()
 	expiration := time.Now().Add(time.Minute * 10)
 	uuid := uuid.FromStringOrNil(authReq.ClientID)
 
@@ -117,8 +121,17 @@ func NewAuthorizationCode(ownerID uuid.UUID, authReq *AuthorizationRequest) *Aut
 		RedirectionURI: authReq.RedirectURI,
 		Scope:          []byte(authReq.Scope),
 		State:          authReq.State,
+		Nonce:          authReq.Nonce,
 	}
-	return ac
+	if len(authReq.CodeChallenge) > 0 {
+		method, err := NewCodeChallengeMethod(authReq.CodeChallengeMethod)
+		if err != nil {
+			return nil, err
+		}
+		ac.CodeChallenge = authReq.CodeChallenge
+		ac.CodeChallengeMethod = method.String()
+	}
+	return ac, nil
 }
 
 func (ac *AuthorizationCode) GetAuthorizationCodeResponse() *AuthorizationCodeResponse {
@@ -143,5 +156,17 @@ func (ac *AuthorizationCode) ValidateAccessTokenRequest(accTokenReq *Authorizati
 	if strings.Compare(accTokenReq.RedirectURI, ac.RedirectionURI) != 0 {
 		return false
 	}
+	if len(ac.CodeChallenge) > 0 {
+		return VerifyCodeChallenge(ac.CodeChallenge, ac.CodeChallengeMethod, accTokenReq.CodeVerifier)
+	}
+	//a code verifier without a previous challenge is not accepted
+	if len(accTokenReq.CodeVerifier) > 0 {
+		return false
+	}
 	return true
 }
+
+//HasCodeChallenge returns true if the authorization request was bound to a RFC7636 code challenge
+func (ac *AuthorizationCode) HasCodeChallenge() bool {
+	return len(ac.CodeChallenge) > 0
+}
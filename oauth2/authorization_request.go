@@ -62,8 +62,12 @@ type AuthorizationRequest struct {
 	ResponseType string `schema:"response_type,required"` //token or code
 	ClientID     string `schema:"client_id,required"`
 	RedirectURI  string `schema:"redirect_uri,required"`
-	Scope        string `schema:"Scope"`
-	State        string `schema:"State"`
+	Scope        string `schema:"scope"`
+	State        string `schema:"state"`
+	Nonce        string `schema:"nonce"` //OpenID Connect value bound to the ID Token
+	//RFC7636 proof key for code exchange
+	CodeChallenge       string `schema:"code_challenge"`
+	CodeChallengeMethod string `schema:"code_challenge_method"` //plain or S256
 }
 
 //GetScopesList returns a slice of the AuthorizationRequest scopes
@@ -33,6 +33,7 @@ type Client struct {
 type ClientCtx struct {
 	ID      uuid.UUID
 	Name    string
+	Public  bool //client authenticated without secret, token_endpoint_auth_method none
 	logoURI string
 }
 
@@ -232,3 +233,8 @@ func (cliCtx *ClientCtx) GetClientName() string {
 func (cliCtx *ClientCtx) GetLogoURI() string {
 	return cliCtx.logoURI
 }
+
+//IsPublic returns true if the client can not keep a secret
+func (cliCtx *ClientCtx) IsPublic() bool {
+	return cliCtx.Public
+}
@@ -0,0 +1,91 @@
+package oauth2
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"regexp"
+	"sort"
+)
+
+/**
+RFC7636 - Proof Key for Code Exchange by OAuth Public Clients
+
+	code_challenge_method
+		OPTIONAL, defaults to "plain" if not present in the request. Code verifier transformation method is "S256"
+		or "plain".
+			plain
+				code_challenge = code_verifier
+			S256
+				code_challenge = BASE64URL-ENCODE(SHA256(ASCII(code_verifier)))
+
+	code_verifier = high-entropy cryptographic random STRING using the unreserved characters
+		[A-Z] / [a-z] / [0-9] / "-" / "." / "_" / "~" with a minimum length of 43 characters and a maximum length of
+		128 characters.
+*/
+type CodeChallengeMethod int
+
+const (
+	NullCodeChallengeMethod  CodeChallengeMethod = -1
+	PlainCodeChallengeMethod CodeChallengeMethod = iota
+	S256CodeChallengeMethod
+)
+
+var codeChallengeMethodValueMap = map[string]CodeChallengeMethod{
+	"plain": PlainCodeChallengeMethod,
+	"S256":  S256CodeChallengeMethod,
+}
+
+var (
+	ErrCodeChallengeMethodNotFound = errors.New("code challenge method not supported")
+	codeVerifierRegexp             = regexp.MustCompile(`^[A-Za-z0-9\-._~]{43,128}$`)
+)
+
+//NewCodeChallengeMethod returns the code challenge method. An empty value defaults to plain
+func NewCodeChallengeMethod(s string) (CodeChallengeMethod, error) {
+	if len(s) == 0 {
+		return PlainCodeChallengeMethod, nil
+	}
+	if val, ok := codeChallengeMethodValueMap[s]; ok {
+		return val, nil
+	}
+	return NullCodeChallengeMethod, ErrCodeChallengeMethodNotFound
+}
+
+func (m CodeChallengeMethod) String() string {
+	switch m {
+	case PlainCodeChallengeMethod:
+		return "plain"
+	case S256CodeChallengeMethod:
+		return "S256"
+	}
+	return "null"
+}
+
+//GetCodeChallengeMethods returns the code challenge methods supported by the authorization endpoint
+func GetCodeChallengeMethods() []string {
+	var methods []string
+	for name := range codeChallengeMethodValueMap {
+		methods = append(methods, name)
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+//VerifyCodeChallenge returns true if the code verifier matches the code challenge with the given method
+func VerifyCodeChallenge(challenge string, method string, verifier string) bool {
+	if !codeVerifierRegexp.MatchString(verifier) {
+		return false
+	}
+	m, err := NewCodeChallengeMethod(method)
+	if err != nil {
+		return false
+	}
+	computed := verifier
+	if m == S256CodeChallengeMethod {
+		sum := sha256.Sum256([]byte(verifier))
+		computed = base64.RawURLEncoding.EncodeToString(sum[:])
+	}
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(challenge)) == 1
+}
@@ -0,0 +1,45 @@
+package oauth2
+
+import (
+	"github.com/gofrs/uuid"
+	"testing"
+)
+
+//RFC7636 Appendix B example
+const (
+	testCodeVerifier  = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	testCodeChallenge = "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+)
+
+func TestVerifyCodeChallenge(t *testing.T) {
+	if !VerifyCodeChallenge(testCodeChallenge, "S256", testCodeVerifier) {
+		t.Errorf("want valid S256 code verifier")
+	}
+	if !VerifyCodeChallenge(testCodeVerifier, "", testCodeVerifier) {
+		t.Errorf("want valid plain code verifier")
+	}
+	if VerifyCodeChallenge(testCodeChallenge, "plain", testCodeVerifier) {
+		t.Errorf("want invalid plain code verifier for S256 challenge")
+	}
+	if VerifyCodeChallenge("short", "plain", "short") {
+		t.Errorf("want invalid code verifier shorter than 43 characters")
+	}
+	if VerifyCodeChallenge(testCodeChallenge, "S512", testCodeVerifier) {
+		t.Errorf("want unsupported code challenge method")
+	}
+}
+
+func TestNewAuthorizationCodeChallengeMethod(t *testing.T) {
+	request := &AuthorizationRequest{CodeChallenge: testCodeChallenge}
+	code, err := NewAuthorizationCode(uuid.Nil, request)
+	if err != nil {
+		t.Fatalf("want code for default challenge method, got %v", err)
+	}
+	if code.CodeChallengeMethod != "plain" {
+		t.Errorf("want plain code challenge method, got %s", code.CodeChallengeMethod)
+	}
+	request.CodeChallengeMethod = "S512"
+	if _, err = NewAuthorizationCode(uuid.Nil, request); err != ErrCodeChallengeMethodNotFound {
+		t.Errorf("want unsupported code challenge method error, got %v", err)
+	}
+}
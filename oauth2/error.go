@@ -81,4 +81,9 @@ var (
 	ErrUnsupportedResponseTypeInfo = errors.New("the authorization server does not support obtaining an access token using this method")
 	ErrUnsupportedTokenType        = errors.New("unsupported_token_type")
 	ErrUnsupportedTokenTypeInfo    = errors.New("the authorization server does not support the revocation of the presented token type")
+
+	//RFC8707 Resource Indicators Error Responses\\
+
+	ErrInvalidTarget     = errors.New("invalid_target")
+	ErrInvalidTargetInfo = errors.New("the requested resource is invalid, missing, unknown, or malformed")
 )
@@ -1,6 +1,9 @@
 package oauth2
 
-import "errors"
+import (
+	"errors"
+	"sort"
+)
 
 type GrantType int
 
@@ -53,3 +56,17 @@ func (t GrantType) String() string {
 	}
 	return "null"
 }
+
+//GetSupportedGrantTypes returns the grant types processed by the token and authorization endpoints
+func GetSupportedGrantTypes() []string {
+	var grantTypes []string
+	for name, grantType := range grantTypeValueMap {
+		//assertion grants are not processed by the token endpoint
+		if grantType == JwtBearerGrantType || grantType == Saml2BearerGrantType {
+			continue
+		}
+		grantTypes = append(grantTypes, name)
+	}
+	sort.Strings(grantTypes)
+	return grantTypes
+}
@@ -0,0 +1,68 @@
+package oauth2
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"time"
+)
+
+/**
+OpenID Connect Core 1.0 - 2. ID Token
+
+The ID Token is a security token that contains Claims about the Authentication of an End-User by an Authorization
+Server when using a Client, and potentially other requested Claims. The ID Token is represented as a JSON Web Token
+(JWT).
+
+	iss
+		REQUIRED. Issuer Identifier for the Issuer of the response.
+	sub
+		REQUIRED. Subject Identifier. A locally unique and never reassigned identifier within the Issuer for the
+		End-User, which is intended to be consumed by the Client.
+	aud
+		REQUIRED. Audience(s) that this ID Token is intended for. It MUST contain the OAuth 2.0 client_id of the
+		Relying Party as an audience value.
+	exp
+		REQUIRED. Expiration time on or after which the ID Token MUST NOT be accepted for processing.
+	iat
+		REQUIRED. Time at which the JWT was issued.
+	nonce
+		String value used to associate a Client session with an ID Token, and to mitigate replay attacks. If present
+		in the Authentication Request, Authorization Servers MUST include a nonce Claim in the ID Token with the Claim
+		Value being the nonce value sent in the Authentication Request.
+	at_hash
+		OPTIONAL. Access Token hash value. Its value is the base64url encoding of the left-most half of the hash of
+		the octets of the ASCII representation of the access_token value, where the hash algorithm used is the hash
+		algorithm used in the alg Header Parameter of the ID Token's JOSE Header.
+*/
+type IDTokenClaims struct {
+	Issuer          string `json:"iss"`
+	Subject         string `json:"sub"`
+	Audience        string `json:"aud"`
+	Expires         int64  `json:"exp"`
+	IssuedAt        int64  `json:"iat"`
+	Nonce           string `json:"nonce,omitempty"`
+	AccessTokenHash string `json:"at_hash,omitempty"`
+}
+
+//NewIDTokenClaims returns the claims of an ID Token issued together with the given access token response
+func NewIDTokenClaims(opt *AccessTokenOptions, response *AccessTokenResponse, duration time.Duration) *IDTokenClaims {
+	creationTime := time.Now()
+	claims := &IDTokenClaims{
+		Issuer:   opt.Issuer,
+		Subject:  opt.OwnerID.String(),
+		Audience: opt.ClientID.String(),
+		Expires:  creationTime.Add(duration).Unix(),
+		IssuedAt: creationTime.Unix(),
+		Nonce:    opt.Nonce,
+	}
+	if response != nil && len(response.AccessToken) > 0 {
+		claims.AccessTokenHash = getAccessTokenHash(response.AccessToken)
+	}
+	return claims
+}
+
+//getAccessTokenHash returns the at_hash value for tokens signed with SHA-256 based algorithms (RS256, ES256)
+func getAccessTokenHash(accessToken string) string {
+	sum := sha256.Sum256([]byte(accessToken))
+	return base64.RawURLEncoding.EncodeToString(sum[:len(sum)/2])
+}
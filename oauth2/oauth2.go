@@ -31,3 +31,9 @@ func getScopesMap(scope string) map[string]struct{} {
 	}
 	return m
 }
+
+//HasScope returns true if value is one of the space-separated values of scope
+func HasScope(scope string, value string) bool {
+	_, ok := getScopesMap(scope)[value]
+	return ok
+}
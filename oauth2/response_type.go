@@ -1,6 +1,9 @@
 package oauth2
 
-import "errors"
+import (
+	"errors"
+	"sort"
+)
 
 type ResponseType int
 
@@ -35,3 +38,13 @@ func (rt ResponseType)	String() string{
 }
 
 
+
+//GetResponseTypes returns the response types supported by the authorization endpoint
+func GetResponseTypes() []string {
+	var responseTypes []string
+	for name := range responseTypeValueMap {
+		responseTypes = append(responseTypes, name)
+	}
+	sort.Strings(responseTypes)
+	return responseTypes
+}
@@ -0,0 +1,69 @@
+package oauth2
+
+/**
+RFC8414 - OAuth 2.0 Authorization Server Metadata
+OpenID Connect Discovery 1.0 - 3. OpenID Provider Metadata
+
+Authorization servers can have metadata describing their configuration. The metadata is published at the well-known
+locations /.well-known/oauth-authorization-server and /.well-known/openid-configuration.
+
+	issuer
+		REQUIRED. The authorization server's issuer identifier, which is a URL that uses the "https" scheme and has no
+		query or fragment components.
+	authorization_endpoint
+		URL of the authorization server's authorization endpoint.
+	token_endpoint
+		URL of the authorization server's token endpoint.
+	jwks_uri
+		OPTIONAL. URL of the authorization server's JWK Set document. REQUIRED for OpenID Providers.
+	registration_endpoint
+		OPTIONAL. URL of the authorization server's OAuth 2.0 Dynamic Client Registration endpoint.
+	scopes_supported
+		RECOMMENDED. JSON array containing a list of the OAuth 2.0 "scope" values that this authorization server
+		supports.
+	response_types_supported
+		REQUIRED. JSON array containing a list of the OAuth 2.0 "response_type" values that this authorization server
+		supports.
+	grant_types_supported
+		OPTIONAL. JSON array containing a list of the OAuth 2.0 grant type values that this authorization server
+		supports.
+	token_endpoint_auth_methods_supported
+		OPTIONAL. JSON array containing a list of client authentication methods supported by this token endpoint.
+	revocation_endpoint
+		OPTIONAL. URL of the authorization server's OAuth 2.0 revocation endpoint.
+	introspection_endpoint
+		OPTIONAL. URL of the authorization server's OAuth 2.0 introspection endpoint.
+*/
+type ServerMetadata struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint,omitempty"`
+	TokenEndpoint                     string   `json:"token_endpoint,omitempty"`
+	UserInfoEndpoint                  string   `json:"userinfo_endpoint,omitempty"`
+	JwksURI                           string   `json:"jwks_uri,omitempty"`
+	RegistrationEndpoint              string   `json:"registration_endpoint,omitempty"`
+	RevocationEndpoint                string   `json:"revocation_endpoint,omitempty"`
+	IntrospectionEndpoint             string   `json:"introspection_endpoint,omitempty"`
+	ScopesSupported                   []string `json:"scopes_supported,omitempty"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	ResponseModesSupported            []string `json:"response_modes_supported,omitempty"`
+	GrantTypesSupported               []string `json:"grant_types_supported,omitempty"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported,omitempty"`
+	SubjectTypesSupported             []string `json:"subject_types_supported,omitempty"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported,omitempty"`
+	ClaimsSupported                   []string `json:"claims_supported,omitempty"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported,omitempty"`
+}
+
+//GetScopesSupported returns the OpenID Connect scope values understood by the server
+func GetScopesSupported() []string {
+	return []string{OpenIDScope, ProfileScope, EmailScope, AddressScope, PhoneScope}
+}
+
+//GetClaimsSupported returns the claims that can be returned in ID Tokens and UserInfo responses
+func GetClaimsSupported() []string {
+	return []string{
+		"sub", "iss", "aud", "exp", "iat", "nonce", "at_hash",
+		"name", "given_name", "family_name", "middle_name", "nickname", "preferred_username", "profile", "picture",
+		"zoneinfo", "locale", "updated_at", "email", "phone_number", "address",
+	}
+}
@@ -2,6 +2,7 @@ package oauth2
 
 import (
 	"errors"
+	"sort"
 )
 
 type TokenEndpointAuthMethod int
@@ -39,3 +40,19 @@ func (m TokenEndpointAuthMethod) Parse(s string) (TokenEndpointAuthMethod, error
 	}
 	return clientSecretPost, ErrTokenEndpointAuthMethodNotFound
 }
+
+//GetTokenEndpointAuthMethods returns the client authentication methods supported by the token endpoint
+func GetTokenEndpointAuthMethods() []string {
+	var methods []string
+	for name := range tokenEndpointAuthMethodValueMap {
+		methods = append(methods, name)
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+//IsNoneTokenEndpointAuthMethod returns true if s is the authentication method of public clients
+func IsNoneTokenEndpointAuthMethod(s string) bool {
+	m, err := none.Parse(s)
+	return err == nil && m == none
+}
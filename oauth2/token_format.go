@@ -0,0 +1,31 @@
+package oauth2
+
+type TokenFormat int
+
+const (
+	NullTokenFormat   TokenFormat = -1
+	OpaqueTokenFormat TokenFormat = iota
+	JWTTokenFormat
+)
+
+var tokenFormatValueMap = map[string]TokenFormat{
+	"opaque": OpaqueTokenFormat,
+	"jwt":    JWTTokenFormat,
+}
+
+func NewTokenFormat(f string) TokenFormat {
+	if val, ok := tokenFormatValueMap[f]; ok {
+		return val
+	}
+	return NullTokenFormat
+}
+
+func (f TokenFormat) String() string {
+	switch f {
+	case OpaqueTokenFormat:
+		return "opaque"
+	case JWTTokenFormat:
+		return "jwt"
+	}
+	return "null"
+}
@@ -0,0 +1,69 @@
+package oauth2
+
+//OpenID Connect scope values used to request claims
+const (
+	OpenIDScope  = "openid"
+	ProfileScope = "profile"
+	EmailScope   = "email"
+	AddressScope = "address"
+	PhoneScope   = "phone"
+)
+
+/**
+OpenID Connect Core 1.0 - 5.3.2. Successful UserInfo Response
+
+The UserInfo Claims MUST be returned as the members of a JSON object. The sub (subject) Claim MUST always be returned in
+the UserInfo Response. If a Claim is not returned, that Claim Name SHOULD be omitted from the JSON object representing
+the Claims; it SHOULD NOT be present with a null or empty string value.
+
+	profile
+		name, family_name, given_name, middle_name, nickname, preferred_username, profile, picture, website, gender,
+		birthdate, zoneinfo, locale, and updated_at.
+	email
+		email and email_verified Claims.
+	address
+		address Claim.
+	phone
+		phone_number and phone_number_verified Claims.
+
+The following is a non-normative example of a UserInfo Response:
+	HTTP/1.1 200 OK
+	Content-Type: application/json
+
+	{
+	"sub": "248289761001",
+	"name": "Jane Doe",
+	"given_name": "Jane",
+	"family_name": "Doe",
+	"preferred_username": "j.doe",
+	"email": "janedoe@example.com",
+	"picture": "http://example.com/janedoe/me.jpg"
+	}
+*/
+type UserInfo struct {
+	Subject           string           `json:"sub"`
+	Name              string           `json:"name,omitempty"`
+	GivenName         string           `json:"given_name,omitempty"`
+	FamilyName        string           `json:"family_name,omitempty"`
+	MiddleName        string           `json:"middle_name,omitempty"`
+	Nickname          string           `json:"nickname,omitempty"`
+	PreferredUsername string           `json:"preferred_username,omitempty"`
+	Profile           string           `json:"profile,omitempty"`
+	Picture           string           `json:"picture,omitempty"`
+	Zoneinfo          string           `json:"zoneinfo,omitempty"`
+	Locale            string           `json:"locale,omitempty"`
+	UpdatedAt         int64            `json:"updated_at,omitempty"`
+	Email             string           `json:"email,omitempty"`
+	PhoneNumber       string           `json:"phone_number,omitempty"`
+	Address           *UserInfoAddress `json:"address,omitempty"`
+}
+
+//UserInfoAddress represents the address claim of the UserInfo response
+type UserInfoAddress struct {
+	Formatted     string `json:"formatted,omitempty"`
+	StreetAddress string `json:"street_address,omitempty"`
+	Locality      string `json:"locality,omitempty"`
+	Region        string `json:"region,omitempty"`
+	PostalCode    string `json:"postal_code,omitempty"`
+	Country       string `json:"country,omitempty"`
+}
@@ -0,0 +1,101 @@
+package otp
+
+import (
+	"bounzr/iam/token"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+/**
+RFC6238 TOTP: Time-Based One-Time Password Algorithm
+
+The codes are the HOTP values (RFC4226) of the number of time steps since the Unix epoch:
+    TOTP = HOTP(K, T) where T = (Current Unix time - T0) / X
+The parameters are the ones supported by the common authenticator apps: HMAC-SHA1, 6 digits and 30 seconds steps.
+*/
+
+const (
+	Digits     = 6
+	Period     = 30 * time.Second
+	secretSize = 20 //RFC4226 recommends 160 bits
+	skew       = 1  //accepted steps before and after the current step, for clock drifts and typing time
+)
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+//NewSecret returns a new random secret base32 encoded as expected by the authenticator apps
+func NewSecret() string {
+	return encoding.EncodeToString(token.GetRandomBytes(secretSize))
+}
+
+//GetCounter returns the time step of the given time
+func GetCounter(t time.Time) uint64 {
+	return uint64(t.Unix() / int64(Period/time.Second))
+}
+
+//GenerateCode returns the code of the secret for the time step
+func GenerateCode(secret string, counter uint64) (string, error) {
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return "", err
+	}
+	var message [8]byte
+	binary.BigEndian.PutUint64(message[:], counter)
+	mac := hmac.New(sha1.New, key)
+	mac.Write(message[:])
+	sum := mac.Sum(nil)
+	//RFC4226 5.3. dynamic truncation
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	modulo := uint32(1)
+	for i := 0; i < Digits; i++ {
+		modulo *= 10
+	}
+	return fmt.Sprintf("%0*d", Digits, value%modulo), nil
+}
+
+//Validate returns the time step of the code if it is valid at the given time. Only steps after lastCounter are
+//accepted so that a code can not be used twice
+func Validate(secret string, code string, now time.Time, lastCounter uint64) (uint64, bool) {
+	code = strings.TrimSpace(code)
+	if len(code) != Digits {
+		return 0, false
+	}
+	current := GetCounter(now)
+	for counter := current - skew; counter <= current+skew; counter++ {
+		if counter <= lastCounter {
+			continue
+		}
+		expected, err := GenerateCode(secret, counter)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return counter, true
+		}
+	}
+	return 0, false
+}
+
+//GetProvisioningURI returns the otpauth URI of the secret, shown as QR code to enroll the authenticator apps
+func GetProvisioningURI(issuer string, account string, secret string) string {
+	values := url.Values{}
+	values.Set("secret", secret)
+	values.Set("issuer", issuer)
+	values.Set("algorithm", "SHA1")
+	values.Set("digits", fmt.Sprint(Digits))
+	values.Set("period", fmt.Sprint(int(Period/time.Second)))
+	label := url.PathEscape(issuer) + ":" + url.PathEscape(account)
+	return "otpauth://totp/" + label + "?" + values.Encode()
+}
+
+func decodeSecret(secret string) ([]byte, error) {
+	return encoding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+}
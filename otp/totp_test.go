@@ -0,0 +1,61 @@
+package otp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+//secret of the RFC6238 test vectors, "12345678901234567890"
+const testSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestGenerateCode(t *testing.T) {
+	//RFC6238 Appendix B SHA1 values truncated to 6 digits
+	vectors := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1111111111: "050471",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+	for seconds, want := range vectors {
+		code, err := GenerateCode(testSecret, GetCounter(time.Unix(seconds, 0)))
+		if err != nil {
+			t.Fatalf("can not generate code - %s", err.Error())
+		}
+		if code != want {
+			t.Errorf("time %d: want %s got %s", seconds, want, code)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	counter := GetCounter(now)
+	previous, _ := GenerateCode(testSecret, counter-1)
+	if step, ok := Validate(testSecret, previous, now, 0); !ok || step != counter-1 {
+		t.Errorf("want code of the previous step accepted")
+	}
+	//a code can not be used twice
+	if _, ok := Validate(testSecret, previous, now, counter-1); ok {
+		t.Errorf("want used code rejected")
+	}
+	old, _ := GenerateCode(testSecret, counter-2)
+	if _, ok := Validate(testSecret, old, now, 0); ok {
+		t.Errorf("want code out of the window rejected")
+	}
+	if _, ok := Validate(testSecret, "12345", now, 0); ok {
+		t.Errorf("want short code rejected")
+	}
+}
+
+func TestNewSecret(t *testing.T) {
+	secret := NewSecret()
+	if len(secret) != 32 || secret == NewSecret() {
+		t.Errorf("want random 160 bits secret got %s", secret)
+	}
+	uri := GetProvisioningURI("Bounzr", "bjensen", secret)
+	if !strings.HasPrefix(uri, "otpauth://totp/Bounzr:bjensen?") || !strings.Contains(uri, "secret="+secret) {
+		t.Errorf("want provisioning uri of the secret got %s", uri)
+	}
+}
@@ -0,0 +1,20 @@
+package pages
+
+import "html/template"
+
+//MfaEnrollPage contains data for mfa_enroll.html
+type MfaEnrollPage struct {
+	CurrentCodeRequired bool //the user replaces an authenticator and must confirm the current second factor
+	Error               string
+	ProvisioningURI     template.URL //otpauth URI, trusted so that the link is not sanitized
+	RecoveryCodes       []string     //shown once after the verification step
+	Secret              string
+}
+
+//NewMfaEnrollPage returns the enrollment page of the authenticator secret
+func NewMfaEnrollPage(secret string, provisioningURI string) *MfaEnrollPage {
+	return &MfaEnrollPage{
+		ProvisioningURI: template.URL(provisioningURI),
+		Secret:          secret,
+	}
+}
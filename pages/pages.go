@@ -16,10 +16,12 @@ var (
 )
 
 var defaultPages = map[string]string{
-	"authorize": "./html/authorize.html",
-	"index":     "./html/index.html",
-	"login":     "./html/login.html",
-	"signup":    "./html/signup.html",
+	"authorize":  "./html/authorize.html",
+	"index":      "./html/index.html",
+	"login":      "./html/login.html",
+	"mfa":        "./html/mfa.html",
+	"mfa_enroll": "./html/mfa_enroll.html",
+	"signup":     "./html/signup.html",
 }
 
 func Init() {
@@ -36,6 +38,10 @@ func RenderPage(w http.ResponseWriter, name string, data interface{}) error {
 	templates := config.CustomConf["webpages"]
 	templatesMap := templates.(map[interface{}]interface{})
 	templatePath, found := templatesMap[name].(string)
+	if !found {
+		//pages added after the configuration was written
+		templatePath, found = defaultPages[name]
+	}
 	if !found {
 		log.Error("web template not found", zap.String("name", name))
 		return errors.New("missing web template " + name)
@@ -0,0 +1,58 @@
+package password
+
+import (
+	"bounzr/iam/token"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+var (
+	ErrHashNotSupported = errors.New("password hash algorithm not supported")
+	ErrMalformedHash    = errors.New("password hash is malformed")
+)
+
+//Hasher hashes passwords into encoded strings that include the algorithm and its parameters
+type Hasher interface {
+	//hash returns the encoded hash of the password
+	hash(password string) (string, error)
+	//matches returns true if the encoded hash was generated by the hasher algorithm
+	matches(encoded string) bool
+	//needsRehash returns true if the encoded hash parameters differ from the hasher parameters
+	needsRehash(encoded string) bool
+	//verify returns true if the password matches the encoded hash
+	verify(password string, encoded string) (bool, error)
+}
+
+//getSalt returns a random salt of the given size
+func getSalt(size int) ([]byte, error) {
+	return token.GetRandomBytes(size), nil
+}
+
+//encodeHash returns the PHC string format $id$params$salt$hash
+func encodeHash(id string, params string, salt []byte, key []byte) string {
+	return "$" + id + "$" + params + "$" + base64.RawStdEncoding.EncodeToString(salt) + "$" + base64.RawStdEncoding.EncodeToString(key)
+}
+
+//decodeHash returns the parameters, salt and hash of a PHC string
+func decodeHash(id string, encoded string) (params []string, salt []byte, key []byte, err error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) < 4 || parts[1] != id {
+		return nil, nil, nil, ErrMalformedHash
+	}
+	salt, err = base64.RawStdEncoding.DecodeString(parts[len(parts)-2])
+	if err != nil {
+		return nil, nil, nil, ErrMalformedHash
+	}
+	key, err = base64.RawStdEncoding.DecodeString(parts[len(parts)-1])
+	if err != nil {
+		return nil, nil, nil, ErrMalformedHash
+	}
+	return parts[2 : len(parts)-2], salt, key, nil
+}
+
+//compareKeys compares two derived keys in constant time
+func compareKeys(k1 []byte, k2 []byte) bool {
+	return subtle.ConstantTimeCompare(k1, k2) == 1
+}
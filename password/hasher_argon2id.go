@@ -0,0 +1,77 @@
+package password
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	argon2idID      = "argon2id"
+	argon2idKeySize = 32
+	argon2idSalt    = 16
+)
+
+type HasherArgon2id struct {
+	memory      uint32 //KiB
+	iterations  uint32
+	parallelism uint8
+}
+
+func NewHasherArgon2id(memory uint32, iterations uint32, parallelism uint8) Hasher {
+	if memory == 0 {
+		memory = 64 * 1024
+	}
+	if iterations == 0 {
+		iterations = 3
+	}
+	if parallelism == 0 {
+		parallelism = 2
+	}
+	return &HasherArgon2id{memory: memory, iterations: iterations, parallelism: parallelism}
+}
+
+func (h *HasherArgon2id) hash(password string) (string, error) {
+	salt, err := getSalt(argon2idSalt)
+	if err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, h.iterations, h.memory, h.parallelism, argon2idKeySize)
+	return encodeHash(argon2idID, h.getParams(), salt, key), nil
+}
+
+func (h *HasherArgon2id) matches(encoded string) bool {
+	return strings.HasPrefix(encoded, "$"+argon2idID+"$")
+}
+
+func (h *HasherArgon2id) needsRehash(encoded string) bool {
+	params, _, key, err := decodeHash(argon2idID, encoded)
+	if err != nil || len(params) != 2 {
+		return true
+	}
+	return strings.Join(params, "$") != h.getParams() || len(key) != argon2idKeySize
+}
+
+func (h *HasherArgon2id) verify(password string, encoded string) (bool, error) {
+	params, salt, key, err := decodeHash(argon2idID, encoded)
+	if err != nil || len(params) != 2 {
+		return false, ErrMalformedHash
+	}
+	var version int
+	if _, err = fmt.Sscanf(params[0], "v=%d", &version); err != nil || version != argon2.Version {
+		return false, ErrHashNotSupported
+	}
+	var memory, iterations uint32
+	var parallelism uint8
+	if _, err = fmt.Sscanf(params[1], "m=%d,t=%d,p=%d", &memory, &iterations, &parallelism); err != nil {
+		return false, ErrMalformedHash
+	}
+	other := argon2.IDKey([]byte(password), salt, iterations, memory, parallelism, uint32(len(key)))
+	return compareKeys(key, other), nil
+}
+
+//getParams returns the version and parameters as encoded in the hash
+func (h *HasherArgon2id) getParams() string {
+	return fmt.Sprintf("v=%d$m=%d,t=%d,p=%d", argon2.Version, h.memory, h.iterations, h.parallelism)
+}
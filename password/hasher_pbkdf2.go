@@ -0,0 +1,65 @@
+package password
+
+import (
+	"crypto/pbkdf2"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+)
+
+const (
+	pbkdf2ID      = "pbkdf2-sha256"
+	pbkdf2KeySize = 32
+	pbkdf2Salt    = 16
+)
+
+type HasherPBKDF2 struct {
+	iterations int
+}
+
+func NewHasherPBKDF2(iterations int) Hasher {
+	if iterations <= 0 {
+		iterations = 600000
+	}
+	return &HasherPBKDF2{iterations: iterations}
+}
+
+func (h *HasherPBKDF2) hash(password string) (string, error) {
+	salt, err := getSalt(pbkdf2Salt)
+	if err != nil {
+		return "", err
+	}
+	key, err := pbkdf2.Key(sha256.New, password, salt, h.iterations, pbkdf2KeySize)
+	if err != nil {
+		return "", err
+	}
+	return encodeHash(pbkdf2ID, fmt.Sprintf("i=%d", h.iterations), salt, key), nil
+}
+
+func (h *HasherPBKDF2) matches(encoded string) bool {
+	return strings.HasPrefix(encoded, "$"+pbkdf2ID+"$")
+}
+
+func (h *HasherPBKDF2) needsRehash(encoded string) bool {
+	params, _, key, err := decodeHash(pbkdf2ID, encoded)
+	if err != nil || len(params) != 1 {
+		return true
+	}
+	return params[0] != fmt.Sprintf("i=%d", h.iterations) || len(key) != pbkdf2KeySize
+}
+
+func (h *HasherPBKDF2) verify(password string, encoded string) (bool, error) {
+	params, salt, key, err := decodeHash(pbkdf2ID, encoded)
+	if err != nil || len(params) != 1 {
+		return false, ErrMalformedHash
+	}
+	var iterations int
+	if _, err = fmt.Sscanf(params[0], "i=%d", &iterations); err != nil || iterations <= 0 {
+		return false, ErrMalformedHash
+	}
+	other, err := pbkdf2.Key(sha256.New, password, salt, iterations, len(key))
+	if err != nil {
+		return false, err
+	}
+	return compareKeys(key, other), nil
+}
@@ -0,0 +1,67 @@
+package password
+
+import (
+	"strings"
+	"testing"
+)
+
+type HasherDataProvider struct {
+	hasher  Hasher
+	prefix  string
+	changed Hasher
+}
+
+var hasherDataProvider = []HasherDataProvider{
+	{NewHasherBcrypt(4), "$2a$04$", NewHasherBcrypt(5)},
+	{NewHasherArgon2id(1024, 1, 1), "$argon2id$v=19$m=1024,t=1,p=1$", NewHasherArgon2id(1024, 2, 1)},
+	{NewHasherPBKDF2(1000), "$pbkdf2-sha256$i=1000$", NewHasherPBKDF2(2000)},
+}
+
+func TestHashAndVerify(t *testing.T) {
+	for _, provider := range hasherDataProvider {
+		hasher = provider.hasher
+		encoded, err := Hash("secret password")
+		if err != nil {
+			t.Fatalf("can not hash password - %s", err.Error())
+		}
+		if !strings.HasPrefix(encoded, provider.prefix) {
+			t.Errorf("want prefix %s got %s", provider.prefix, encoded)
+		}
+		if !IsHashed(encoded) {
+			t.Errorf("want hashed value %s", encoded)
+		}
+		other, _ := Hash("secret password")
+		if other == encoded {
+			t.Errorf("want different salt for each hash")
+		}
+		ok, rehash := Verify("secret password", encoded)
+		if !ok || rehash {
+			t.Errorf("want valid=true rehash=false got valid=%v rehash=%v", ok, rehash)
+		}
+		if ok, _ = Verify("wrong password", encoded); ok {
+			t.Errorf("want invalid password")
+		}
+		//parameters changed in config
+		hasher = provider.changed
+		ok, rehash = Verify("secret password", encoded)
+		if !ok || !rehash {
+			t.Errorf("want valid=true rehash=true got valid=%v rehash=%v", ok, rehash)
+		}
+	}
+}
+
+func TestVerifyOtherAlgorithm(t *testing.T) {
+	hasher = NewHasherPBKDF2(1000)
+	encoded, _ := Hash("secret password")
+	hasher = NewHasherBcrypt(4)
+	ok, rehash := Verify("secret password", encoded)
+	if !ok || !rehash {
+		t.Errorf("want valid=true rehash=true got valid=%v rehash=%v", ok, rehash)
+	}
+	if IsHashed("plaintext") {
+		t.Errorf("want plaintext not hashed")
+	}
+	if ok, _ = Verify("plaintext", "plaintext"); ok {
+		t.Errorf("want plaintext not verified")
+	}
+}
@@ -0,0 +1,68 @@
+package password
+
+import (
+	"bounzr/iam/config"
+	"bounzr/iam/logger"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+var (
+	log    *zap.Logger
+	hasher Hasher = NewHasherArgon2id(0, 0, 0)
+)
+
+//Init sets the hasher of new passwords from config
+func Init() {
+	log = logger.GetLogger()
+	cfg := config.IAM.Passwords
+	switch strings.ToLower(cfg.Algorithm) {
+	case "bcrypt":
+		hasher = NewHasherBcrypt(cfg.BcryptCost)
+	case "pbkdf2":
+		hasher = NewHasherPBKDF2(cfg.PBKDF2Iterations)
+	case "argon2id", "":
+		hasher = NewHasherArgon2id(cfg.Argon2Memory, cfg.Argon2Iterations, cfg.Argon2Parallelism)
+	default:
+		log.Error("password hash algorithm not supported. argon2id will be used", zap.String("algorithm", cfg.Algorithm))
+		hasher = NewHasherArgon2id(cfg.Argon2Memory, cfg.Argon2Iterations, cfg.Argon2Parallelism)
+	}
+}
+
+//Hash returns the encoded hash of the password with the configured algorithm
+func Hash(password string) (string, error) {
+	return hasher.hash(password)
+}
+
+//IsHashed returns true if the stored value is an encoded hash of a supported algorithm
+func IsHashed(encoded string) bool {
+	return getHasher(encoded) != nil
+}
+
+//Verify returns true if the password matches the encoded hash. rehash is true if the hash must be replaced because
+//the configured algorithm or its parameters changed
+func Verify(password string, encoded string) (ok bool, rehash bool) {
+	h := getHasher(encoded)
+	if h == nil {
+		return false, false
+	}
+	ok, err := h.verify(password, encoded)
+	if err != nil || !ok {
+		return false, false
+	}
+	return true, !hasher.matches(encoded) || hasher.needsRehash(encoded)
+}
+
+//getHasher returns a hasher able to verify the encoded hash
+func getHasher(encoded string) Hasher {
+	if hasher.matches(encoded) {
+		return hasher
+	}
+	for _, h := range []Hasher{&HasherBcrypt{}, &HasherArgon2id{}, &HasherPBKDF2{}} {
+		if h.matches(encoded) {
+			return h
+		}
+	}
+	return nil
+}
@@ -4,15 +4,22 @@ import (
 	"bounzr/iam/config"
 	"bounzr/iam/oauth2"
 	"bounzr/iam/scim2"
-	"bounzr/iam/utils"
+	"bounzr/iam/token"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
 	"github.com/gofrs/uuid"
 	"go.uber.org/zap"
 	"strings"
 	"time"
 )
 
+//prefix of the stored client secret digests
+const clientSecretHashPrefix = "$sha256$"
+
 type Client struct {
 	AccessToken             *oauth2.AccessTokenHint
+	AccessTokenFormat       string //opaque or jwt. Empty uses the tokens format from config
 	Contacts                []string
 	Created                 time.Time
 	GrantTypes              map[string]struct{}
@@ -24,16 +31,21 @@ type Client struct {
 	Name                    string
 	OwnerID                 uuid.UUID
 	PolicyURI               string
+	PreviousSecret          string //digest of the rotated secret, valid until PreviousSecretExpiresAt
+	PreviousSecretExpiresAt time.Time
 	RedirectURIs            map[string]struct{}
+	RequirePKCE             bool //authorization code requests must include a RFC7636 code challenge
 	ResponseTypes           map[string]struct{}
 	Scope                   string
-	Secret                  string
+	Secret                  string //SHA-256 digest of the client secret
 	SecretExpiresAt         time.Time
+	SecretIssuedAt          time.Time
 	SoftwareID              string
 	SoftwareVersion         string
 	TokenEndpointAuthMethod string
 	TosURI                  string
 	URI                     string
+	Version                 uint64 //incremented on every write of the client
 }
 
 func (c *Client) DeleteClientAccessToken(clientID uuid.UUID) {
@@ -86,6 +98,7 @@ func (c *Client) GetResourceTag() *ResourceTag {
 		ResourceType:   "Client",
 		ID:             c.ID,
 		Name:           c.Name,
+		Version:        c.Version,
 	}
 	return resourceTag
 }
@@ -96,8 +109,9 @@ func (c *Client) GetScim() *scim2.Client {
 	groups := FindGroupAssignments(memberGroupFilter)
 
 	client := &scim2.Client{
+		AccessTokenFormat:       c.AccessTokenFormat,
 		Name:                    c.Name,
-		PasswordExpiresAt:       c.SecretExpiresAt.Unix(),
+		PasswordExpiresAt:       c.GetSecretExpiresAt(),
 		URI:                     c.URI,
 		Contacts:                c.Contacts,
 		GrantTypes:              c.GetGrantTypes(),
@@ -109,8 +123,9 @@ func (c *Client) GetScim() *scim2.Client {
 		Metadata:                c.GetResourceTag().GetScimMetadata(),
 		PolicyUri:               c.PolicyURI,
 		RedirectUris:            c.GetRedirectUris(),
+		RequirePkce:             c.RequirePKCE,
 		ResponseTypes:           c.GetResponseTypes(),
-		Schemas:                 []string{"org:bounzer:iam:scim2:1.0:Client"},
+		Schemas:                 []string{scim2.ClientSchema},
 		Scope:                   c.Scope,
 		SoftwareId:              c.SoftwareID,
 		SoftwareVersion:         c.SoftwareVersion,
@@ -156,16 +171,17 @@ func getSliceToMap(slice []string) map[string]struct{} {
 }
 
 func (c *Client) GetClientCtx() *oauth2.ClientCtx {
-	return oauth2.NewClientCtx(c.ID, c.Name, c.LogoURI)
+	ctx := oauth2.NewClientCtx(c.ID, c.Name, c.LogoURI)
+	ctx.Public = c.IsPublic()
+	return ctx
 }
 
 func (c *Client) GetClientInformationResponse() (*oauth2.ClientInformationResponse, error) {
 	//todo verify that the client is not expired!
 	cir := &oauth2.ClientInformationResponse{
 		ClientId:                c.ID.String(),
-		ClientSecret:            c.Secret,
 		ClientIdIssuedAt:        c.Created.Unix(),
-		ClientSecretExpiresAt:   c.SecretExpiresAt.Unix(), //todo expired client secret?
+		ClientSecretExpiresAt:   c.GetSecretExpiresAt(),
 		RedirectUris:            c.GetRedirectUris(),
 		TokenEndpointAuthMethod: c.TokenEndpointAuthMethod,
 		GrantTypes:              c.GetGrantTypes(),
@@ -217,6 +233,24 @@ func (c *Client) GetResponseTypes() []string {
 	return rts
 }
 
+//GetAccessTokenFormat returns the format of the access tokens issued to the client
+func (c *Client) GetAccessTokenFormat() oauth2.TokenFormat {
+	if format := oauth2.NewTokenFormat(c.AccessTokenFormat); format != oauth2.NullTokenFormat {
+		return format
+	}
+	return oauth2.NewTokenFormat(config.IAM.Tokens.GetFormat())
+}
+
+//IsPublic returns true if the client is registered with the "none" token endpoint authentication method
+func (c *Client) IsPublic() bool {
+	return oauth2.IsNoneTokenEndpointAuthMethod(c.TokenEndpointAuthMethod)
+}
+
+//RequiresPKCE returns true if authorization code requests of the client must include a code challenge
+func (c *Client) RequiresPKCE() bool {
+	return c.RequirePKCE || c.IsPublic()
+}
+
 //HasGrantType returns true if the requested Grant Type is registered for the client
 func (c *Client) HasGrantType(grantType string) (ok bool) {
 	_, ok = c.GrantTypes[grantType]
@@ -237,7 +271,11 @@ func (c *Client) HasRedirectURI(redirectURI string) (ok bool) {
 
 func (c *Client) SetScim(scim *scim2.Client) {
 	c.Name = scim.Name
-	c.SecretExpiresAt = time.Unix(scim.PasswordExpiresAt, 0)
+	c.SecretExpiresAt = time.Time{}
+	if scim.PasswordExpiresAt > 0 {
+		c.SecretExpiresAt = time.Unix(scim.PasswordExpiresAt, 0)
+	}
+	c.AccessTokenFormat = scim.AccessTokenFormat
 	c.URI = scim.URI
 	c.Contacts = scim.Contacts
 	c.GrantTypes = getSliceToMap(scim.GrantTypes)
@@ -245,21 +283,27 @@ func (c *Client) SetScim(scim *scim2.Client) {
 	c.JwksURI = scim.JwksURI
 	c.Jwks = scim.Jwks
 	c.LastModified = time.Now()
+	c.Version++
 	c.LogoURI = scim.LogoUri
 	c.PolicyURI = scim.PolicyUri
 	c.RedirectURIs = getSliceToMap(scim.RedirectUris)
+	c.RequirePKCE = scim.RequirePkce
 	c.ResponseTypes = getSliceToMap(scim.ResponseTypes)
 	c.Scope = scim.Scope
 	c.SoftwareID = scim.SoftwareId
 	c.SoftwareVersion = scim.SoftwareVersion
 	c.TokenEndpointAuthMethod = scim.TokenEndpointAuthMethod
 	c.TosURI = scim.TosUri
+	c.setPublicSecret()
 }
 
-func NewClientFromOauth(request *oauth2.ClientRegistrationRequest) (*Client, error) {
+//NewClientFromOauth returns a new client and its secret. The secret is only stored hashed and can not be retrieved later
+func NewClientFromOauth(request *oauth2.ClientRegistrationRequest) (*Client, string, error) {
 	clientID, err := uuid.NewV4()
+	if err != nil {
+		return nil, "", err
+	}
 	issuedAt := time.Now()
-	secretExpiresAt := issuedAt.Add(config.IAM.Clients.GetSecretDuration())
 
 	cli := &Client{
 		Contacts:                request.Contacts,
@@ -275,23 +319,30 @@ func NewClientFromOauth(request *oauth2.ClientRegistrationRequest) (*Client, err
 		RedirectURIs:            getSliceToMap(request.RedirectUris),
 		ResponseTypes:           getSliceToMap(request.ResponseTypes),
 		Scope:                   strings.TrimSpace(request.Scope),
-		Secret:                  utils.GetRandomPassword(16), //todo password generator
-		SecretExpiresAt:         secretExpiresAt,
 		SoftwareID:              request.SoftwareId,
 		SoftwareVersion:         request.SoftwareVersion,
 		TokenEndpointAuthMethod: request.TokenEndpointAuthMethod,
 		TosURI:                  request.TosUri,
 		URI:                     request.ClientUri,
+		Version:                 1,
+	}
+	secret, err := cli.newSecret(issuedAt)
+	if err != nil {
+		return nil, "", err
 	}
-	return cli, err
+	return cli, secret, nil
 }
 
-func NewClientFromScim(request *scim2.Client) (*Client, error) {
+//NewClientFromScim returns a new client and its secret. The secret is only stored hashed and can not be retrieved later
+func NewClientFromScim(request *scim2.Client) (*Client, string, error) {
 	clientID, err := uuid.NewV4()
+	if err != nil {
+		return nil, "", err
+	}
 	issuedAt := time.Now()
-	secretExpiresAt := issuedAt.Add(config.IAM.Clients.GetSecretDuration())
 
 	cli := &Client{
+		AccessTokenFormat:       request.AccessTokenFormat,
 		Contacts:                request.Contacts,
 		Created:                 issuedAt,
 		GrantTypes:              getSliceToMap(request.GrantTypes),
@@ -303,30 +354,124 @@ func NewClientFromScim(request *scim2.Client) (*Client, error) {
 		Name:                    request.Name,
 		PolicyURI:               request.PolicyUri,
 		RedirectURIs:            getSliceToMap(request.RedirectUris),
+		RequirePKCE:             request.RequirePkce,
 		ResponseTypes:           getSliceToMap(request.ResponseTypes),
 		Scope:                   strings.TrimSpace(request.Scope),
-		Secret:                  utils.GetRandomPassword(16),
-		SecretExpiresAt:         secretExpiresAt,
 		SoftwareID:              request.SoftwareId,
 		SoftwareVersion:         request.SoftwareVersion,
 		TokenEndpointAuthMethod: request.TokenEndpointAuthMethod,
 		TosURI:                  request.TosUri,
 		URI:                     request.URI,
+		Version:                 1,
+	}
+	secret, err := cli.newSecret(issuedAt)
+	if err != nil {
+		return nil, "", err
+	}
+	return cli, secret, nil
+}
+
+//newSecret generates a new secret and stores its digest. Public clients do not get a secret
+func (c *Client) newSecret(issuedAt time.Time) (secret string, err error) {
+	if c.IsPublic() {
+		c.setPublicSecret()
+		return "", nil
 	}
-	return cli, err
+	secret = token.GetTokenString()
+	c.Secret = hashClientSecret(secret)
+	c.SecretIssuedAt = issuedAt
+	c.SecretExpiresAt = time.Time{}
+	//a zero duration secret does not expire
+	if duration := config.IAM.Clients.GetSecretDuration(); duration > 0 {
+		c.SecretExpiresAt = issuedAt.Add(duration)
+	}
+	return secret, nil
 }
 
-//todo Secret must be encrypted + salted
-//todo client secret must be modifiable and the secretExpiresAt reset
-func (c *Client) ValidateClientSecret(secret string) (ok bool) {
-	if !utils.InTimeSpan(c.Created, c.SecretExpiresAt, time.Now()) {
+//rotateSecret generates a new secret. The previous secret stays valid until the grace period ends or it expires
+func (c *Client) rotateSecret(now time.Time, gracePeriod time.Duration) (secret string, err error) {
+	if c.IsPublic() {
+		return "", ErrInvalidRequest
+	}
+	previous, previousExpiresAt := c.Secret, c.SecretExpiresAt
+	secret, err = c.newSecret(now)
+	if err != nil {
+		return "", err
+	}
+	c.PreviousSecret = ""
+	c.PreviousSecretExpiresAt = time.Time{}
+	if gracePeriod > 0 && len(previous) > 0 && isSecretValid(previousExpiresAt, now) {
+		c.PreviousSecret = previous
+		c.PreviousSecretExpiresAt = now.Add(gracePeriod)
+		if !previousExpiresAt.IsZero() && previousExpiresAt.Before(c.PreviousSecretExpiresAt) {
+			c.PreviousSecretExpiresAt = previousExpiresAt
+		}
+	}
+	c.LastModified = now
+	c.Version++
+	return secret, nil
+}
+
+//setPublicSecret removes the secret of public clients as they authenticate only with the client_id
+func (c *Client) setPublicSecret() {
+	if c.IsPublic() {
+		c.Secret = ""
+		c.SecretExpiresAt = time.Time{}
+		c.SecretIssuedAt = time.Time{}
+		c.PreviousSecret = ""
+		c.PreviousSecretExpiresAt = time.Time{}
+	}
+}
+
+//GetSecretExpiresAt returns the time at which the client secret will expire or 0 if it will not expire
+func (c *Client) GetSecretExpiresAt() int64 {
+	if c.SecretExpiresAt.IsZero() {
+		return 0
+	}
+	return c.SecretExpiresAt.Unix()
+}
+
+//hashClientSecret returns the SHA-256 digest of the secret. Client secrets are random tokens with enough entropy,
+//so they do not need a slow password hash that would make the token endpoint expensive to call
+func hashClientSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return clientSecretHashPrefix + hex.EncodeToString(sum[:])
+}
+
+//isClientSecretHash returns true if the stored secret is a digest of hashClientSecret
+func isClientSecretHash(stored string) bool {
+	return strings.HasPrefix(stored, clientSecretHashPrefix)
+}
+
+//verifyClientSecret compares the digest of the secret with the stored digest in constant time
+func verifyClientSecret(secret string, stored string) bool {
+	return subtle.ConstantTimeCompare([]byte(hashClientSecret(secret)), []byte(stored)) == 1
+}
+
+//isSecretValid returns true if a secret with the given expiration time is still valid. Zero time does not expire
+func isSecretValid(expiresAt time.Time, now time.Time) bool {
+	return expiresAt.IsZero() || now.Before(expiresAt)
+}
+
+//ValidateClientSecret returns true if the secret matches the current secret or the rotated secret within its grace period
+func (c *Client) ValidateClientSecret(secret string) bool {
+	//public clients do not have a secret
+	if c.IsPublic() || len(c.Secret) == 0 || len(secret) == 0 {
 		return false
 	}
-	ok = false
-	if strings.Compare(c.Secret, secret) == 0 {
-		ok = true
+	now := time.Now()
+	if isSecretValid(c.SecretExpiresAt, now) {
+		if verifyClientSecret(secret, c.Secret) {
+			return true
+		}
 	}
-	return
+	if len(c.PreviousSecret) > 0 && isSecretValid(c.PreviousSecretExpiresAt, now) {
+		if verifyClientSecret(secret, c.PreviousSecret) {
+			log.Debug("client authenticated with rotated secret", zap.String("client ID", c.ID.String()))
+			return true
+		}
+	}
+	return false
 }
 
 func (c *Client) ValidateScope(requestedScope string) (validatedScope string) {
@@ -5,31 +5,49 @@ import (
 	"bounzr/iam/scim2"
 	"github.com/gofrs/uuid"
 	"go.uber.org/zap"
+	"sync"
+	"time"
 )
 
 type ClientManager interface {
 	close()
 	deleteClient(id uuid.UUID)
-	findClients() ([]Client, error)
+	findClients(query *scim2.ListQuery) ([]Client, int, error)
 	getClient(id uuid.UUID) (client *Client, found bool)
 	init()
 	setClient(cli *Client)
 }
 
-func DeleteClient(clientID uuid.UUID) {
+//clientsMutex serializes the writes of the clients, so that the preconditions are validated against the version being
+//replaced
+var clientsMutex sync.Mutex
+
+//DeleteClient deletes the client if the preconditions hold for its current version
+func DeleteClient(clientID uuid.UUID, preconditions *scim2.Preconditions) error {
+	clientsMutex.Lock()
+	defer clientsMutex.Unlock()
+	client, err := getVersionedClient(clientID, preconditions)
+	if err != nil {
+		return err
+	}
 	clientManager.deleteClient(clientID)
+	deleteResourceGroups(client.GetResourceTag())
+	return nil
 }
 
-func FindClients() []scim2.Client {
+//FindClients returns the SCIM representation of the page of clients requested by the query and the total number of
+//clients matching it. A nil query returns all clients
+func FindClients(query *scim2.ListQuery) ([]scim2.Client, int, error) {
 	var clients []scim2.Client
-	repClients, err := clientManager.findClients()
+	repClients, total, err := clientManager.findClients(query)
 	if err != nil {
 		log.Error("can not add clients from repository", zap.Error(err))
+		return nil, 0, err
 	}
 	for _, client := range repClients {
 		clients = append(clients, *client.GetScim())
 	}
-	return clients
+	return clients, total, nil
 }
 
 func GetClient(id interface{}) (client *Client, found bool) {
@@ -48,19 +66,93 @@ func initClients() {
 		clientManager = &ClientManagerBasic{}
 	}
 	clientManager.init()
+	migrateClientSecrets()
 }
 
-func ReplaceClientByScim(clientID uuid.UUID, scim *scim2.Client) error {
-	client, found := GetClient(clientID)
-	if !found {
-		log.Debug("client not found", zap.String("id", clientID.String()))
-		return ErrInvalidRequest
+//migrateClientSecrets replaces the plaintext secrets stored in the repository by their digest
+func migrateClientSecrets() {
+	clients, _, err := clientManager.findClients(nil)
+	if err != nil {
+		log.Error("can not migrate client secrets", zap.Error(err))
+		return
+	}
+	for i := range clients {
+		client := &clients[i]
+		if len(client.Secret) == 0 || isClientSecretHash(client.Secret) {
+			continue
+		}
+		client.Secret = hashClientSecret(client.Secret)
+		clientManager.setClient(client)
+		log.Info("plaintext client secret migrated", zap.String("id", client.ID.String()))
+	}
+}
+
+//PatchClient applies the SCIM patch operations to the client if the preconditions hold for its current version
+func PatchClient(clientID uuid.UUID, patch *scim2.PatchOp, preconditions *scim2.Preconditions) error {
+	clientsMutex.Lock()
+	defer clientsMutex.Unlock()
+	client, err := getVersionedClient(clientID, preconditions)
+	if err != nil {
+		return err
+	}
+	scimClient := client.GetScim()
+	err = patch.Apply(scimClient)
+	if err != nil {
+		log.Debug("can not patch client", zap.String("id", clientID.String()), zap.Error(err))
+		return err
+	}
+	client.SetScim(scimClient)
+	SetClient(client)
+	return nil
+}
+
+//ReplaceClientByScim replaces the client attributes if the preconditions hold for its current version
+func ReplaceClientByScim(clientID uuid.UUID, scim *scim2.Client, preconditions *scim2.Preconditions) error {
+	clientsMutex.Lock()
+	defer clientsMutex.Unlock()
+	client, err := getVersionedClient(clientID, preconditions)
+	if err != nil {
+		return err
 	}
 	client.SetScim(scim)
 	SetClient(client)
 	return nil
 }
 
+//getVersionedClient returns the client to be modified if the preconditions hold for its current version. The caller
+//must hold clientsMutex until the client is written
+func getVersionedClient(clientID uuid.UUID, preconditions *scim2.Preconditions) (*Client, error) {
+	client, found := GetClient(clientID)
+	if !found {
+		log.Debug("client not found", zap.String("id", clientID.String()))
+		return nil, ErrClientNotFound
+	}
+	if err := preconditions.Validate(scim2.GetETag(client.Version)); err != nil {
+		log.Debug("client precondition failed", zap.String("id", clientID.String()), zap.Uint64("version", client.Version))
+		return nil, err
+	}
+	return client, nil
+}
+
+//RotateClientSecret issues a new secret for the client. The previous secret is valid during the secret grace period
+func RotateClientSecret(clientID uuid.UUID) (*Client, string, error) {
+	clientsMutex.Lock()
+	defer clientsMutex.Unlock()
+	client, found := GetClient(clientID)
+	if !found {
+		log.Debug("client not found", zap.String("id", clientID.String()))
+		return nil, "", ErrClientNotFound
+	}
+	secret, err := client.rotateSecret(time.Now(), config.IAM.Clients.GetSecretGracePeriod())
+	if err != nil {
+		log.Error("can not rotate client secret", zap.String("id", clientID.String()), zap.Error(err))
+		return nil, "", err
+	}
+	clientManager.setClient(client)
+	log.Info("client secret rotated", zap.String("id", clientID.String()), zap.Time("previous secret expires", client.PreviousSecretExpiresAt))
+	return client, secret, nil
+}
+
 func SetClient(cli *Client) {
 	clientManager.setClient(cli)
 	AddGroupResource(privateGroups["Clients"], cli.GetResourceTag())
@@ -1,7 +1,10 @@
 package repository
 
 import (
+	"bounzr/iam/scim2"
+	"bytes"
 	"github.com/gofrs/uuid"
+	"sort"
 )
 
 type ClientManagerBasic struct {
@@ -16,14 +19,29 @@ func (r *ClientManagerBasic) deleteClient(id uuid.UUID) {
 	delete(r.clients, id)
 }
 
-func (r *ClientManagerBasic) findClients() ([]Client, error) {
-	clients := make([]Client, len(r.clients))
-	idx := 0
-	for _, client := range r.clients {
-		clients[idx] = *client
-		idx++
+//findClients returns the page of clients requested by the query, ordered by ID if it is not sorted, and the total number of clients matching it
+func (r *ClientManagerBasic) findClients(query *scim2.ListQuery) ([]Client, int, error) {
+	ids := make([]uuid.UUID, 0, len(r.clients))
+	for id := range r.clients {
+		ids = append(ids, id)
 	}
-	return clients, nil
+	sort.Slice(ids, func(i, j int) bool {
+		return bytes.Compare(ids[i].Bytes(), ids[j].Bytes()) < 0
+	})
+	page := scim2.NewListPage(query)
+	for _, id := range ids {
+		if page.IsResourceRequired() {
+			page.Add(id, r.clients[id].GetScim())
+		} else {
+			page.Add(id, nil)
+		}
+	}
+	keys, total := page.Keys()
+	clients := make([]Client, len(keys))
+	for idx, key := range keys {
+		clients[idx] = *r.clients[key.(uuid.UUID)]
+	}
+	return clients, total, nil
 }
 
 func (r *ClientManagerBasic) init() {
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"bounzr/iam/scim2"
 	"bytes"
 	"encoding/gob"
 	"github.com/gofrs/uuid"
@@ -71,27 +72,40 @@ func (r *ClientManagerLeveldb) getClient(ID uuid.UUID) (*Client, bool) {
 	return &client, true
 }
 
-//todo findClients(search parameters)
-func (r *ClientManagerLeveldb) findClients() ([]Client, error) {
-	var clients []Client
+//findClients returns the page of clients requested by the query, ordered by ID if it is not sorted, and the total number of clients matching it.
+//Clients are only decoded if the query has a filter or a sortBy attribute, or if they are in the requested page
+func (r *ClientManagerLeveldb) findClients(query *scim2.ListQuery) ([]Client, int, error) {
+	page := scim2.NewListPage(query)
 	iter := r.db.NewIterator(nil, nil)
 	for iter.Next() {
-		dataBytes := iter.Value()
-		data := bytes.NewBuffer(dataBytes)
+		id := uuid.FromBytesOrNil(iter.Key())
+		if !page.IsResourceRequired() {
+			page.Add(id, nil)
+			continue
+		}
+		data := bytes.NewBuffer(iter.Value())
 		dec := gob.NewDecoder(data)
 		var client Client
 		err := dec.Decode(&client)
 		if err != nil {
-			log.Error("can not decode client", zap.ByteString("id", iter.Key()), zap.Error(err))
+			log.Error("can not decode client", zap.String("id", id.String()), zap.Error(err))
+			continue
 		}
-		log.Debug("client decoded", zap.ByteString("id", iter.Key()))
-		clients = append(clients, client)
+		page.Add(id, client.GetScim())
 	}
 	iter.Release()
 	if err := iter.Error(); err != nil {
-		return nil, err
+		return nil, 0, err
+	}
+	keys, total := page.Keys()
+	clients := make([]Client, 0, len(keys))
+	for _, key := range keys {
+		client, ok := r.getClient(key.(uuid.UUID))
+		if ok {
+			clients = append(clients, *client)
+		}
 	}
-	return clients, nil
+	return clients, total, nil
 }
 
 func (r *ClientManagerLeveldb) setClient(cli *Client) {
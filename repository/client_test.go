@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"bounzr/iam/config"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+func TestClientSecretRotation(t *testing.T) {
+	log, _ = zap.NewDevelopment()
+	config.IAM.Clients.SecretDuration = "1h"
+	client := &Client{TokenEndpointAuthMethod: "client_secret_basic"}
+	secret, err := client.newSecret(time.Now())
+	if err != nil {
+		t.Fatalf("can not create client secret - %s", err.Error())
+	}
+	if client.Secret == secret || !isClientSecretHash(client.Secret) {
+		t.Errorf("want hashed secret got %s", client.Secret)
+	}
+	if !client.ValidateClientSecret(secret) {
+		t.Errorf("want valid secret")
+	}
+	rotated, err := client.rotateSecret(time.Now(), time.Minute)
+	if err != nil {
+		t.Fatalf("can not rotate client secret - %s", err.Error())
+	}
+	if !client.ValidateClientSecret(rotated) {
+		t.Errorf("want valid rotated secret")
+	}
+	if !client.ValidateClientSecret(secret) {
+		t.Errorf("want previous secret valid during grace period")
+	}
+	//grace period is over
+	client.PreviousSecretExpiresAt = time.Now().Add(-time.Second)
+	if client.ValidateClientSecret(secret) {
+		t.Errorf("want previous secret invalid after grace period")
+	}
+	if client.ValidateClientSecret("wrongsecret") {
+		t.Errorf("want invalid secret")
+	}
+	client.SecretExpiresAt = time.Now().Add(-time.Second)
+	if client.ValidateClientSecret(rotated) {
+		t.Errorf("want expired secret invalid")
+	}
+}
+
+func TestPublicClientSecret(t *testing.T) {
+	log, _ = zap.NewDevelopment()
+	client := &Client{TokenEndpointAuthMethod: "none"}
+	secret, err := client.newSecret(time.Now())
+	if err != nil || len(secret) != 0 || len(client.Secret) != 0 {
+		t.Errorf("want no secret for public client")
+	}
+	if _, err = client.rotateSecret(time.Now(), time.Minute); err == nil {
+		t.Errorf("want error rotating public client secret")
+	}
+	if client.ValidateClientSecret("") {
+		t.Errorf("want public client without valid secret")
+	}
+}
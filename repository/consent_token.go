@@ -1,7 +1,7 @@
 package repository
 
 import (
-	"bounzr/iam/utils"
+	"bounzr/iam/token"
 	"github.com/gofrs/uuid"
 )
 
@@ -11,6 +11,5 @@ type ConsentToken struct {
 }
 
 func newConsentToken(clientID uuid.UUID) *ConsentToken {
-	token := utils.GetRandomString(10)
-	return &ConsentToken{clientID, token}
+	return &ConsentToken{clientID, token.GetTokenString()}
 }
@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"bounzr/iam/config"
+	"github.com/gofrs/uuid"
+	"github.com/gorilla/securecookie"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+//CookieKeyRecord is a persisted key pair of the session cookies with its lifecycle dates. The keys are stored
+//unencrypted, like the signing keys, and are only protected by the permissions of the key store directory
+type CookieKeyRecord struct {
+	ID                string
+	AuthenticationKey []byte //HMAC-SHA256 key
+	EncryptionKey     []byte //AES-256 key
+	Created           time.Time
+	RetiresAt         time.Time //the key pair does not encode new cookies from this date
+	ExpiresAt         time.Time //the key pair decodes cookies until this date
+}
+
+//CookieKeys are the key pairs of the session cookies. The first pair encodes the cookies, all of them decode them
+type CookieKeys struct {
+	KeyPairs  [][]byte
+	RefreshAt time.Time //the key pairs must be read again from this date, zero if they do not change
+}
+
+//GetCookieKeys returns the cookie key pairs of the configuration, shared by all the nodes. Without configured keys the
+//pairs of the key store are returned, a new pair is generated every rotation period
+func GetCookieKeys() (*CookieKeys, error) {
+	if pairs := config.IAM.Cookies.GetKeyPairs(); len(pairs) > 0 {
+		return &CookieKeys{KeyPairs: pairs}, nil
+	}
+	return rotateCookieKeys(time.Now())
+}
+
+//getCookieKeyRetention returns the time a retired key pair decodes cookies so that the cookies encoded with it can be
+//read until they expire
+func getCookieKeyRetention() time.Duration {
+	if maxAge := config.IAM.Cookies.GetMaxAge(); maxAge > 0 {
+		return maxAge
+	}
+	return config.IAM.Cookies.GetRotationPeriod()
+}
+
+//newCookieKeyRecord generates a new cookie key pair active from the given time
+func newCookieKeyRecord(now time.Time) (*CookieKeyRecord, error) {
+	id, err := uuid.NewV4()
+	if err != nil {
+		return nil, err
+	}
+	retiresAt := now.Add(config.IAM.Cookies.GetRotationPeriod())
+	return &CookieKeyRecord{
+		ID:                id.String(),
+		AuthenticationKey: securecookie.GenerateRandomKey(64),
+		EncryptionKey:     securecookie.GenerateRandomKey(32),
+		Created:           now,
+		RetiresAt:         retiresAt,
+		ExpiresAt:         retiresAt.Add(getCookieKeyRetention()),
+	}, nil
+}
+
+//rotateCookieKeys removes expired key pairs, generates a new pair if there is no active pair and returns the pairs
+//that are not expired, the active pair first
+func rotateCookieKeys(now time.Time) (*CookieKeys, error) {
+	keysMutex.Lock()
+	defer keysMutex.Unlock()
+	records := keyManager.getCookieKeys()
+	//newest keys first
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Created.After(records[j].Created)
+	})
+	var active *CookieKeyRecord
+	valid := make([]*CookieKeyRecord, 0, len(records))
+	for _, record := range records {
+		if now.After(record.ExpiresAt) {
+			log.Debug("cookie key expired", zap.String("id", record.ID))
+			if err := keyManager.deleteCookieKey(record.ID); err != nil {
+				log.Error("can not delete expired cookie key", zap.String("id", record.ID), zap.Error(err))
+			}
+			continue
+		}
+		if active == nil && !now.Before(record.Created) && now.Before(record.RetiresAt) {
+			active = record
+			continue
+		}
+		valid = append(valid, record)
+	}
+	if active == nil {
+		record, err := newCookieKeyRecord(now)
+		if err != nil {
+			return nil, err
+		}
+		if err = keyManager.setCookieKey(record); err != nil {
+			return nil, err
+		}
+		log.Info("new cookie key activated", zap.String("id", record.ID), zap.Time("retires", record.RetiresAt))
+		active = record
+	}
+	keys := &CookieKeys{
+		KeyPairs:  [][]byte{active.AuthenticationKey, active.EncryptionKey},
+		RefreshAt: active.RetiresAt,
+	}
+	for _, record := range valid {
+		keys.KeyPairs = append(keys.KeyPairs, record.AuthenticationKey, record.EncryptionKey)
+		if record.ExpiresAt.Before(keys.RefreshAt) {
+			keys.RefreshAt = record.ExpiresAt
+		}
+	}
+	return keys, nil
+}
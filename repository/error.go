@@ -5,6 +5,7 @@ import "errors"
 var (
 	ErrClientNotFound       = errors.New("clientID not found")
 	ErrGroupNotFound        = errors.New("groupID not found")
+	ErrGroupProtected       = errors.New("private group can not be deleted or renamed")
 	ErrResourceNotAvailable = errors.New("resource not available")
 	ErrResourceNotFound     = errors.New("resource not found")
 	ErrUsernameNotAvailable = errors.New("username not available")
@@ -18,4 +19,13 @@ var (
 	//SessionsStore errors
 	ErrSessionNotFound = errors.New("session not found for user")
 	ErrSessionInvalid  = errors.New("session not found for token")
+	ErrSessionExpired  = errors.New("session expired")
+
+	//multi-factor authentication errors
+	ErrMfaInvalidCode = errors.New("invalid authentication code")
+	ErrMfaLocked      = errors.New("too many invalid authentication codes")
+	ErrMfaNotEnrolled = errors.New("authenticator not enrolled")
+
+	//signing key errors
+	ErrSigningKeyNotFound = errors.New("signing key not found")
 )
@@ -7,8 +7,9 @@ import (
 )
 
 type Group struct {
-	Metadata *ResourceTag
-	Members  map[uuid.UUID]interface{}
+	Metadata    *ResourceTag
+	Members     map[uuid.UUID]interface{}
+	MfaRequired bool //members must log in with a second factor
 }
 
 func NewGroup(id uuid.UUID, name string) *Group {
@@ -20,6 +21,7 @@ func NewGroup(id uuid.UUID, name string) *Group {
 		LastModified: currentTime,
 		Name:         name,
 		ResourceType: "Group",
+		Version:      1,
 	}
 	group := &Group{
 		Metadata: metadata,
@@ -39,13 +41,16 @@ func (g *Group) DeleteResource(resource uuid.UUID) {
 
 func (g *Group) GetScim() *scim2.Group {
 	var scimMembers []scim2.GroupMember
-	//todo ref
-	for m, _ := range g.Members {
+	for m, member := range g.Members {
 		sm := scim2.GroupMember{
 			Ref:   "https://localhost/scim2/users/" + m.String(),
 			Type:  "User",
 			Value: m.String(),
 		}
+		if resource, ok := member.(*ResourceTag); ok && len(resource.ResourceType) > 0 {
+			sm.Ref = resource.GetScimMetadata().Location
+			sm.Type = resource.ResourceType
+		}
 		scimMembers = append(scimMembers, sm)
 	}
 
@@ -54,7 +59,7 @@ func (g *Group) GetScim() *scim2.Group {
 		ID:          g.Metadata.ID.String(),
 		Members:     scimMembers,
 		Metadata:    g.Metadata.GetScimMetadata(),
-		Schemas:     []string{"urn:ietf:params:scim:schemas:core:2.0:Group"},
+		Schemas:     []string{scim2.GroupSchema},
 	}
 	return group
 }
@@ -5,6 +5,9 @@ import (
 	"bounzr/iam/scim2"
 	"github.com/gofrs/uuid"
 	"go.uber.org/zap"
+	"strings"
+	"sync"
+	"time"
 )
 
 type GroupManager interface {
@@ -12,8 +15,7 @@ type GroupManager interface {
 	close()
 	deleteGroup(groupID uuid.UUID)
 	deleteGroupResource(groupID uuid.UUID, resource uuid.UUID)
-	deleteResource(resource uuid.UUID)
-	findGroups(filter map[string]interface{}) ([]Group, error)
+	findGroups(conditions map[string]interface{}, query *scim2.ListQuery) ([]Group, int, error)
 	getGroup(groupID uuid.UUID) (*Group, bool)
 	init()
 	setGroup(group *Group)
@@ -22,6 +24,10 @@ type GroupManager interface {
 var privateGroupsList = [...]string{"Admins", "Clients", "ProtectedResources"}
 var privateGroups = make(map[string]uuid.UUID)
 
+//groupsMutex serializes the writes of the groups, so that the preconditions are validated against the version being
+//replaced
+var groupsMutex sync.Mutex
+
 func initGroups() {
 	implementation := config.IAM.Groups.Implementation
 	switch implementation {
@@ -53,7 +59,7 @@ func addPrivateGroups() {
 	for _, group := range privateGroupsList {
 		groupFilter := make(map[string]interface{})
 		groupFilter["name"] = group
-		groups, err := groupManager.findGroups(groupFilter)
+		groups, _, err := groupManager.findGroups(groupFilter, nil)
 		if err != nil {
 			log.Debug("group not found. The server will try to add it", zap.String("name", group), zap.Error(err))
 		}
@@ -71,7 +77,32 @@ func addPrivateGroups() {
 	}
 }
 
+//DeleteGroup deletes the group if the preconditions hold for its current version. Private groups can not be deleted
+func DeleteGroup(groupID uuid.UUID, preconditions *scim2.Preconditions) error {
+	groupsMutex.Lock()
+	defer groupsMutex.Unlock()
+	if _, err := getVersionedGroup(groupID, preconditions); err != nil {
+		return err
+	}
+	if isPrivateGroup(groupID) {
+		log.Debug("private group can not be deleted", zap.String("group ID", groupID.String()))
+		return ErrGroupProtected
+	}
+	groupManager.deleteGroup(groupID)
+	return nil
+}
+
 func AddScimGroup(scimGroup *scim2.Group) (uuid.UUID, error) {
+	groupsMutex.Lock()
+	defer groupsMutex.Unlock()
+	if isPrivateGroupName(scimGroup.DisplayName) {
+		log.Debug("private group name can not be used", zap.String("name", scimGroup.DisplayName))
+		return uuid.Nil, scim2.ErrBadRequestUniqueness
+	}
+	if isGroupNameTaken(scimGroup.DisplayName) {
+		log.Debug("group name is already in use", zap.String("name", scimGroup.DisplayName))
+		return uuid.Nil, scim2.ErrBadRequestUniqueness
+	}
 	id, err := uuid.NewV4()
 	if err != nil {
 		log.Error("can not generate uuid", zap.Error(err))
@@ -92,21 +123,24 @@ func AddScimGroup(scimGroup *scim2.Group) (uuid.UUID, error) {
 	return id, nil
 }
 
-func FindGroups(conditions map[string]interface{}) []scim2.Group {
+//FindGroups returns the SCIM representation of the page of groups requested by the query and the total number of
+//groups matching it. A nil query returns all groups
+func FindGroups(query *scim2.ListQuery) ([]scim2.Group, int, error) {
 	var groups []scim2.Group
-	repGroups, err := groupManager.findGroups(conditions)
+	repGroups, total, err := groupManager.findGroups(make(map[string]interface{}), query)
 	if err != nil {
 		log.Error("can not get groups from repository", zap.Error(err))
+		return nil, 0, err
 	}
 	for _, group := range repGroups {
 		groups = append(groups, *group.GetScim())
 	}
-	return groups
+	return groups, total, nil
 }
 
 func FindGroupAssignments(conditions map[string]interface{}) []scim2.GroupAssignment {
 	var groupAssignments []scim2.GroupAssignment
-	repGroups, err := groupManager.findGroups(conditions)
+	repGroups, _, err := groupManager.findGroups(conditions, nil)
 	if err != nil {
 		log.Error("can not get groups from repository", zap.Error(err))
 	}
@@ -116,6 +150,23 @@ func FindGroupAssignments(conditions map[string]interface{}) []scim2.GroupAssign
 	return groupAssignments
 }
 
+//matchGroupConditions returns true if the group has the name and the member of the conditions. Empty conditions match every group
+func matchGroupConditions(group *Group, conditions map[string]interface{}) bool {
+	if name, ok := conditions["name"].(string); ok && len(name) > 0 {
+		log.Debug("searching groups with name condition", zap.String("name", name))
+		if strings.Compare(name, group.Metadata.Name) != 0 {
+			return false
+		}
+	}
+	if member, ok := conditions["member"].(uuid.UUID); ok {
+		log.Debug("searching groups with member condition", zap.String("member", member.String()))
+		if _, found := group.Members[member]; !found {
+			return false
+		}
+	}
+	return true
+}
+
 func GetGroup(group uuid.UUID) (*Group, error) {
 	retGroup, ok := groupManager.getGroup(group)
 	if ok {
@@ -124,21 +175,205 @@ func GetGroup(group uuid.UUID) (*Group, error) {
 	return nil, ErrGroupNotFound
 }
 
+//PatchGroup applies the SCIM patch operations to the group if the preconditions hold for its current version
+func PatchGroup(groupID uuid.UUID, patch *scim2.PatchOp, preconditions *scim2.Preconditions) error {
+	groupsMutex.Lock()
+	defer groupsMutex.Unlock()
+	group, err := getVersionedGroup(groupID, preconditions)
+	if err != nil {
+		return err
+	}
+	scimGroup := group.GetScim()
+	err = patch.Apply(scimGroup)
+	if err != nil {
+		log.Debug("can not patch group", zap.String("group ID", groupID.String()), zap.Error(err))
+		return err
+	}
+	return replaceGroupByScim(group, scimGroup)
+}
+
+//ReplaceGroupByScim replaces the name and the members of the group if the preconditions hold for its current version.
+//Members must be existing users or clients. Private groups can not be renamed and their names can not be used by
+//other groups
+func ReplaceGroupByScim(groupID uuid.UUID, scimGroup *scim2.Group, preconditions *scim2.Preconditions) error {
+	groupsMutex.Lock()
+	defer groupsMutex.Unlock()
+	group, err := getVersionedGroup(groupID, preconditions)
+	if err != nil {
+		return err
+	}
+	return replaceGroupByScim(group, scimGroup)
+}
+
+//getVersionedGroup returns the group to be modified if the preconditions hold for its current version. The caller
+//must hold groupsMutex until the group is written
+func getVersionedGroup(groupID uuid.UUID, preconditions *scim2.Preconditions) (*Group, error) {
+	group, found := groupManager.getGroup(groupID)
+	if !found {
+		log.Debug("group not found", zap.String("group ID", groupID.String()))
+		return nil, ErrGroupNotFound
+	}
+	if err := preconditions.Validate(scim2.GetETag(group.Metadata.Version)); err != nil {
+		log.Debug("group precondition failed", zap.String("group ID", groupID.String()), zap.Uint64("version", group.Metadata.Version))
+		return nil, err
+	}
+	return group, nil
+}
+
+//replaceGroupByScim replaces the name and the members of the group. The caller must hold groupsMutex
+func replaceGroupByScim(group *Group, scimGroup *scim2.Group) error {
+	groupID := group.Metadata.ID
+	if len(scimGroup.DisplayName) == 0 {
+		log.Debug("group name is empty", zap.String("group ID", groupID.String()))
+		return scim2.ErrBadRequestInvalidValue
+	}
+	if scimGroup.DisplayName != group.Metadata.Name {
+		if isPrivateGroup(groupID) {
+			log.Debug("private group can not be renamed", zap.String("group ID", groupID.String()))
+			return ErrGroupProtected
+		}
+		if isPrivateGroupName(scimGroup.DisplayName) {
+			log.Debug("private group name can not be used", zap.String("name", scimGroup.DisplayName))
+			return scim2.ErrBadRequestUniqueness
+		}
+		if isGroupNameTaken(scimGroup.DisplayName) {
+			log.Debug("group name is already in use", zap.String("name", scimGroup.DisplayName))
+			return scim2.ErrBadRequestUniqueness
+		}
+	}
+	members := make(map[uuid.UUID]ResourceTagger)
+	for _, member := range scimGroup.Members {
+		resource, found := getMemberResource(uuid.FromStringOrNil(member.Value))
+		if !found {
+			log.Debug("group member not found", zap.String("group ID", groupID.String()), zap.String("member", member.Value))
+			return scim2.ErrBadRequestInvalidValue
+		}
+		members[resource.GetUUID()] = resource
+	}
+	group.Metadata.Name = scimGroup.DisplayName
+	group.Metadata.setModified(time.Now())
+	groupManager.setGroup(group)
+	for memberID := range group.Members {
+		if _, ok := members[memberID]; !ok {
+			groupManager.deleteGroupResource(groupID, memberID)
+		}
+	}
+	for memberID, resource := range members {
+		if _, ok := group.Members[memberID]; !ok {
+			groupManager.setGroupResource(groupID, resource)
+		}
+	}
+	return nil
+}
+
+//SetGroupMfaRequired sets whether the members of the group must log in with a second factor
+func SetGroupMfaRequired(groupID uuid.UUID, required bool) error {
+	groupsMutex.Lock()
+	defer groupsMutex.Unlock()
+	group, found := groupManager.getGroup(groupID)
+	if !found {
+		log.Debug("group not found", zap.String("group ID", groupID.String()))
+		return ErrGroupNotFound
+	}
+	group.MfaRequired = required
+	group.Metadata.setModified(time.Now())
+	groupManager.setGroup(group)
+	log.Info("group second factor requirement changed", zap.String("group ID", groupID.String()), zap.Bool("required", required))
+	return nil
+}
+
+//isGroupNameTaken returns true if a group has the name. The caller must hold groupsMutex
+func isGroupNameTaken(name string) bool {
+	groups, _, err := groupManager.findGroups(map[string]interface{}{"name": name}, nil)
+	if err != nil {
+		log.Error("can not get groups from repository", zap.Error(err))
+		return true
+	}
+	return len(groups) > 0
+}
+
+func isPrivateGroup(groupID uuid.UUID) bool {
+	for _, id := range privateGroups {
+		if id == groupID {
+			return true
+		}
+	}
+	return false
+}
+
+func isPrivateGroupName(name string) bool {
+	for _, privateName := range privateGroupsList {
+		if strings.EqualFold(name, privateName) {
+			return true
+		}
+	}
+	return false
+}
+
+//getMemberResource returns the resource tag of the user or client with the given id
+func getMemberResource(id uuid.UUID) (ResourceTagger, bool) {
+	if user, found := GetUser(id); found {
+		return user.Metadata, true
+	}
+	if client, found := GetClient(id); found {
+		return client.GetResourceTag(), true
+	}
+	return nil, false
+}
+
+//SetResourceGroups replaces the groups of the resource by the groups of the assigner. The version of the groups whose
+//members change is incremented
 func SetResourceGroups(assigner scim2.GroupAssigner, resource ResourceTagger) {
-	groupManager.deleteResource(resource.GetUUID())
-	groupAssignment := assigner.GetGroups()
-	for _, group := range groupAssignment {
+	groupsMutex.Lock()
+	defer groupsMutex.Unlock()
+	assigned := make(map[uuid.UUID]bool)
+	for _, group := range assigner.GetGroups() {
 		groupID, err := uuid.FromString(group.Value)
 		if err != nil {
 			log.Error("invalid group groupID in group assigner", zap.String("groupID", group.Value), zap.Error(err))
 			continue
 		}
-		_, err = GetGroup(groupID)
-		if err != nil {
-			log.Error("unknown group groupID in group assigner", zap.String("groupID", group.Value), zap.Error(err))
+		if _, found := groupManager.getGroup(groupID); !found {
+			log.Error("unknown group groupID in group assigner", zap.String("groupID", group.Value), zap.Error(ErrGroupNotFound))
 			continue
 		}
-		AddGroupResource(groupID, resource)
+		assigned[groupID] = true
+	}
+	setResourceGroups(resource, assigned)
+}
+
+//deleteResourceGroups removes the deleted resource from its groups and increments their version
+func deleteResourceGroups(resource ResourceTagger) {
+	groupsMutex.Lock()
+	defer groupsMutex.Unlock()
+	setResourceGroups(resource, make(map[uuid.UUID]bool))
+}
+
+//setResourceGroups removes the resource from the groups not assigned and adds it to the new ones. The caller must
+//hold groupsMutex
+func setResourceGroups(resource ResourceTagger, assigned map[uuid.UUID]bool) {
+	resourceID := resource.GetUUID()
+	current, _, err := groupManager.findGroups(map[string]interface{}{"member": resourceID}, nil)
+	if err != nil {
+		log.Error("can not get groups from repository", zap.String("resource ID", resourceID.String()), zap.Error(err))
+		return
+	}
+	now := time.Now()
+	for i := range current {
+		group := &current[i]
+		if assigned[group.Metadata.ID] {
+			delete(assigned, group.Metadata.ID)
+			continue
+		}
+		group.DeleteResource(resourceID)
+		group.Metadata.setModified(now)
+		groupManager.setGroup(group)
+	}
+	for groupID := range assigned {
+		group, _ := groupManager.getGroup(groupID)
+		group.AddResource(resource)
+		group.Metadata.setModified(now)
+		groupManager.setGroup(group)
 	}
 }
 
@@ -146,7 +381,7 @@ func ValidateResourceInGroup(resourceID uuid.UUID, groupName string) bool {
 	filter := make(map[string]interface{})
 	filter["name"] = groupName
 	filter["member"] = resourceID
-	groups, err := groupManager.findGroups(filter)
+	groups, _, err := groupManager.findGroups(filter, nil)
 	if err != nil {
 		log.Error("can not get groups from repository", zap.Error(err))
 		return false
@@ -1,10 +1,10 @@
 package repository
 
 import (
+	"bounzr/iam/scim2"
+	"bytes"
 	"github.com/gofrs/uuid"
-	"go.uber.org/zap"
-	"reflect"
-	"strings"
+	"sort"
 )
 
 type GroupManagerBasic struct {
@@ -16,8 +16,11 @@ func (g *GroupManagerBasic) init() {
 }
 
 func (g *GroupManagerBasic) setGroupResource(group uuid.UUID, resource ResourceTagger) bool {
-	g.groups[group].AddResource(resource)
-	return true
+	grObj, ok := g.groups[group]
+	if ok {
+		grObj.AddResource(resource)
+	}
+	return ok
 }
 
 func (g *GroupManagerBasic) close() {
@@ -29,16 +32,8 @@ func (g *GroupManagerBasic) deleteGroup(group uuid.UUID) {
 }
 
 func (g *GroupManagerBasic) deleteGroupResource(group uuid.UUID, resource uuid.UUID) {
-	g.groups[group].DeleteResource(resource)
-}
-
-func (g *GroupManagerBasic) deleteResource(resourceID uuid.UUID) {
-	groups := make([]Group, len(g.groups))
-	idx := 0
-	for _, group := range g.groups {
-		group.DeleteResource(resourceID)
-		groups[idx] = *group
-		idx++
+	if grObj, ok := g.groups[group]; ok {
+		grObj.DeleteResource(resource)
 	}
 }
 
@@ -47,36 +42,34 @@ func (g *GroupManagerBasic) getGroup(group uuid.UUID) (*Group, bool) {
 	return grObj, ok
 }
 
-//todo atomic operation including len(g.groups) and range(g.groups)
-func (g *GroupManagerBasic) findGroups(conditions map[string]interface{}) ([]Group, error) {
-	groups := make([]Group, len(g.groups))
-	idx := 0
-	for _, group := range g.groups {
-		nameCondition := conditions["name"]
-		var nameToFind string
-		if nameCondition != nil {
-			nameToFind = nameCondition.(string)
-		}
-		if len(nameToFind) > 0 {
-			log.Debug("searching with name condition", zap.String("name", conditions["name"].(string)))
-			if strings.Compare(nameToFind, group.Metadata.Name) != 0 {
-				continue
-			}
+//findGroups returns the page of groups matching the conditions requested by the query, ordered by ID if it is not sorted,
+//and the total number of groups matching both
+func (g *GroupManagerBasic) findGroups(conditions map[string]interface{}, query *scim2.ListQuery) ([]Group, int, error) {
+	ids := make([]uuid.UUID, 0, len(g.groups))
+	for id := range g.groups {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return bytes.Compare(ids[i].Bytes(), ids[j].Bytes()) < 0
+	})
+	page := scim2.NewListPage(query)
+	for _, id := range ids {
+		group := g.groups[id]
+		if !matchGroupConditions(group, conditions) {
+			continue
 		}
-		memberCondition := conditions["member"]
-		var memberToFind uuid.UUID
-		if memberCondition != nil && reflect.TypeOf(memberToFind) == reflect.TypeOf(uuid.UUID{}) {
-			memberToFind = memberCondition.(uuid.UUID)
-			log.Debug("searching groups with member condition", zap.String("member", conditions["member"].(uuid.UUID).String()))
-			_, ok := group.Members[memberToFind]
-			if !ok {
-				continue
-			}
+		if page.IsResourceRequired() {
+			page.Add(id, group.GetScim())
+		} else {
+			page.Add(id, nil)
 		}
-		groups[idx] = *group
-		idx++
 	}
-	return groups, nil
+	keys, total := page.Keys()
+	groups := make([]Group, len(keys))
+	for idx, key := range keys {
+		groups[idx] = *g.groups[key.(uuid.UUID)]
+	}
+	return groups, total, nil
 }
 
 func (g *GroupManagerBasic) setGroup(group *Group) {
@@ -1,13 +1,12 @@
 package repository
 
 import (
+	"bounzr/iam/scim2"
 	"bytes"
 	"encoding/gob"
 	"github.com/gofrs/uuid"
 	"github.com/syndtr/goleveldb/leveldb"
 	"go.uber.org/zap"
-	"reflect"
-	"strings"
 )
 
 type GroupManagerLeveldb struct {
@@ -39,7 +38,10 @@ func (gr *GroupManagerLeveldb) deleteGroupResource(groupID uuid.UUID, resourceID
 	}
 }
 
-func (gr *GroupManagerLeveldb) deleteResource(resourceID uuid.UUID) {
+//findGroups returns the page of groups matching the conditions requested by the query, ordered by ID if it is not sorted,
+//and the total number of groups matching both
+func (gr *GroupManagerLeveldb) findGroups(conditions map[string]interface{}, query *scim2.ListQuery) ([]Group, int, error) {
+	page := scim2.NewListPage(query)
 	iter := gr.groups.NewIterator(nil, nil)
 	for iter.Next() {
 		dataBytes := iter.Value()
@@ -49,59 +51,31 @@ func (gr *GroupManagerLeveldb) deleteResource(resourceID uuid.UUID) {
 		err := dec.Decode(&group)
 		if err != nil {
 			log.Error("can not decode group", zap.Error(err))
+			continue
 		}
 		log.Debug("group decoded", zap.String("id", group.Metadata.ID.String()))
-		delete(group.Members, resourceID)
-		gr.setGroup(&group)
-	}
-	iter.Release()
-	if err := iter.Error(); err != nil {
-		log.Error("could not release group iterator", zap.Error(err))
-	}
-}
-
-func (gr *GroupManagerLeveldb) findGroups(conditions map[string]interface{}) ([]Group, error) {
-	var groups []Group
-	iter := gr.groups.NewIterator(nil, nil)
-	for iter.Next() {
-		dataBytes := iter.Value()
-		data := bytes.NewBuffer(dataBytes)
-		dec := gob.NewDecoder(data)
-		var group Group
-		err := dec.Decode(&group)
-		if err != nil {
-			log.Error("can not decode group", zap.Error(err))
+		if !matchGroupConditions(&group, conditions) {
+			continue
 		}
-		log.Debug("group decoded", zap.String("id", group.Metadata.ID.String()))
-		nameCondition := conditions["name"]
-		var nameToFind string
-		if nameCondition != nil {
-			nameToFind = nameCondition.(string)
-		}
-		if len(nameToFind) > 0 {
-			log.Debug("searching groups with name condition", zap.String("name", conditions["name"].(string)))
-			if strings.Compare(nameToFind, group.Metadata.Name) != 0 {
-				continue
-			}
-		}
-		memberCondition := conditions["member"]
-		var memberToFind uuid.UUID
-		if memberCondition != nil && reflect.TypeOf(memberToFind) == reflect.TypeOf(uuid.UUID{}) {
-			memberToFind = memberCondition.(uuid.UUID)
-			log.Debug("searching groups with member condition", zap.String("member", conditions["member"].(uuid.UUID).String()))
-			_, ok := group.Members[memberToFind]
-			if !ok {
-				continue
-			}
+		if page.IsResourceRequired() {
+			page.Add(group.Metadata.ID, group.GetScim())
+		} else {
+			page.Add(group.Metadata.ID, nil)
 		}
-		//todo control other conditions or wrong filters
-		groups = append(groups, group)
 	}
 	iter.Release()
 	if err := iter.Error(); err != nil {
-		return nil, err
+		return nil, 0, err
+	}
+	keys, total := page.Keys()
+	groups := make([]Group, 0, len(keys))
+	for _, key := range keys {
+		group, ok := gr.getGroup(key.(uuid.UUID))
+		if ok {
+			groups = append(groups, *group)
+		}
 	}
-	return groups, nil
+	return groups, total, nil
 }
 
 func (gr *GroupManagerLeveldb) init() {
@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"bounzr/iam/scim2"
+	"encoding/gob"
+	"github.com/gofrs/uuid"
+	"testing"
+)
+
+//groupManagerProvider are the group repositories tested with each user repository of executeUserTest
+var groupManagerProvider = map[UserManager]GroupManager{
+	basicUMTest:   &GroupManagerBasic{},
+	leveldbUMTest: &GroupManagerLeveldb{groupsPath: "../test/group"},
+}
+
+func executeGroupTest(test func(user *User)) {
+	gob.Register(&ResourceTag{})
+	clientManager = &ClientManagerBasic{}
+	clientManager.init()
+	sessionManager = &SessionManagerBasic{}
+	sessionManager.init()
+	executeUserTest(func(provider UserDataProvider) {
+		groupManager = groupManagerProvider[provider.manager]
+		groupManager.init()
+		user, _ := provider.manager.getUser(provider.username)
+		user.RepositoryName = "main"
+		provider.manager.setUser(user)
+		addPrivateGroups()
+		test(user)
+		groupManager.close()
+	})
+}
+
+func TestPrivateGroups(t *testing.T) {
+	test := func(user *User) {
+		admins := privateGroups["Admins"]
+		if err := DeleteGroup(admins, nil); err != ErrGroupProtected {
+			t.Errorf("want error %v, got %v", ErrGroupProtected, err)
+		}
+		err := ReplaceGroupByScim(admins, &scim2.Group{DisplayName: "Operators"}, nil)
+		if err != ErrGroupProtected {
+			t.Errorf("want error %v, got %v", ErrGroupProtected, err)
+		}
+		if _, err = AddScimGroup(&scim2.Group{DisplayName: "admins"}); err != scim2.ErrBadRequestUniqueness {
+			t.Errorf("want error %v, got %v", scim2.ErrBadRequestUniqueness, err)
+		}
+		//members of private groups can be replaced
+		err = ReplaceGroupByScim(admins, &scim2.Group{DisplayName: "Admins", Members: []scim2.GroupMember{{Value: user.ID.String()}}}, nil)
+		if err != nil {
+			t.Fatalf("want no error, got %s", err.Error())
+		}
+		if !ValidateResourceInGroup(user.ID, "Admins") {
+			t.Errorf("want user in Admins group")
+		}
+	}
+	executeGroupTest(test)
+}
+
+func TestGroupMembership(t *testing.T) {
+	test := func(user *User) {
+		groupID, err := AddScimGroup(&scim2.Group{DisplayName: "Tour Guides"})
+		if err != nil {
+			t.Fatalf("want no error, got %s", err.Error())
+		}
+		members := []scim2.GroupMember{{Value: user.ID.String()}}
+		if err = ReplaceGroupByScim(groupID, &scim2.Group{DisplayName: "Guides", Members: members}, nil); err != nil {
+			t.Fatalf("want no error, got %s", err.Error())
+		}
+		groups := user.GetScim().Groups
+		if len(groups) != 1 || groups[0].Value != groupID.String() || groups[0].Display != "Guides" {
+			t.Errorf("want user in group Guides, got %+v", groups)
+		}
+		//group names are unique
+		otherID, _ := AddScimGroup(&scim2.Group{DisplayName: "Operators"})
+		if err = ReplaceGroupByScim(otherID, &scim2.Group{DisplayName: "Guides"}, nil); err != scim2.ErrBadRequestUniqueness {
+			t.Errorf("want error %v, got %v", scim2.ErrBadRequestUniqueness, err)
+		}
+		if _, err = AddScimGroup(&scim2.Group{DisplayName: "Guides"}); err != scim2.ErrBadRequestUniqueness {
+			t.Errorf("want error %v, got %v", scim2.ErrBadRequestUniqueness, err)
+		}
+		filter, _ := scim2.ParseFilter(`groups.display eq "guides"`)
+		users, total, err := FindUsers(&scim2.ListQuery{Filter: filter, StartIndex: 1, Count: -1})
+		if err != nil || total != 1 || users[0].ID != user.ID.String() {
+			t.Errorf("want user of group Guides, got %d users, error %v", total, err)
+		}
+		unknown := []scim2.GroupMember{{Value: uuid.Must(uuid.NewV4()).String()}}
+		if err = ReplaceGroupByScim(groupID, &scim2.Group{DisplayName: "Guides", Members: unknown}, nil); err != scim2.ErrBadRequestInvalidValue {
+			t.Errorf("want error %v, got %v", scim2.ErrBadRequestInvalidValue, err)
+		}
+		DeleteUser(user.ID, nil)
+		group, _ := GetGroup(groupID)
+		if len(group.Members) != 0 {
+			t.Errorf("want deleted user removed from group, got %d members", len(group.Members))
+		}
+		if group.Metadata.Version != 3 {
+			t.Errorf("want group version %d, got %d", 3, group.Metadata.Version)
+		}
+		if err = DeleteGroup(groupID, &scim2.Preconditions{IfMatch: `W/"1"`}); err != scim2.ErrPreconditionFailed {
+			t.Errorf("want error %v, got %v", scim2.ErrPreconditionFailed, err)
+		}
+		if err = DeleteGroup(groupID, nil); err != nil {
+			t.Errorf("want no error, got %s", err.Error())
+		}
+		if _, err = GetGroup(groupID); err != ErrGroupNotFound {
+			t.Errorf("want error %v, got %v", ErrGroupNotFound, err)
+		}
+	}
+	executeGroupTest(test)
+}
+
+func TestGroupMfaRequired(t *testing.T) {
+	test := func(user *User) {
+		groupID, _ := AddScimGroup(&scim2.Group{DisplayName: "Operators", Members: []scim2.GroupMember{{Value: user.ID.String()}}})
+		if IsMfaRequired(user.ID) || RequiresSecondFactor(user.ID) {
+			t.Errorf("want second factor not required")
+		}
+		if err := SetGroupMfaRequired(groupID, true); err != nil {
+			t.Fatalf("want no error, got %s", err.Error())
+		}
+		if !IsMfaRequired(user.ID) || !RequiresSecondFactor(user.ID) {
+			t.Errorf("want second factor required by group")
+		}
+		if group, _ := GetGroup(groupID); group.Metadata.Version != 2 {
+			t.Errorf("want group version %d, got %d", 2, group.Metadata.Version)
+		}
+		//the requirement is kept when the group is replaced
+		ReplaceGroupByScim(groupID, &scim2.Group{DisplayName: "Operators"}, nil)
+		if group, _ := GetGroup(groupID); !group.MfaRequired || IsMfaRequired(user.ID) {
+			t.Errorf("want requirement kept and user removed from the group")
+		}
+		if err := SetGroupMfaRequired(uuid.Must(uuid.NewV4()), true); err != ErrGroupNotFound {
+			t.Errorf("want error %v, got %v", ErrGroupNotFound, err)
+		}
+	}
+	executeGroupTest(test)
+}
@@ -0,0 +1,240 @@
+package repository
+
+import (
+	"bounzr/iam/config"
+	"bounzr/iam/token"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+//SigningKeyRecord is a persisted signing key with its lifecycle dates. The private key is stored unencrypted and is only
+//protected by the permissions of the key store directory
+type SigningKeyRecord struct {
+	ID          string //kid
+	Algorithm   string //RS256 or ES256
+	PrivateKey  []byte //PKCS8 DER encoded
+	Created     time.Time
+	ActivatesAt time.Time //the key signs tokens from this date
+	RetiresAt   time.Time //the key does not sign new tokens from this date
+	ExpiresAt   time.Time //the key is published for verification until this date
+}
+
+type KeyManager interface {
+	deleteCookieKey(id string) error
+	deleteKey(id string) error
+	getCookieKeys() []*CookieKeyRecord
+	getKeys() []*SigningKeyRecord
+	init()
+	close()
+	setCookieKey(key *CookieKeyRecord) error
+	setKey(key *SigningKeyRecord) error
+}
+
+var (
+	keysMutex   sync.Mutex
+	signingKeys = make(map[string]*token.SigningKey)
+	//activeSigningKey signs the tokens until signingKeysCheckAt, when the keys must be rotated or removed
+	activeSigningKey   *token.SigningKey
+	signingKeysCheckAt time.Time
+)
+
+func initSigningKeys() {
+	implementation := config.IAM.Keys.Implementation
+	switch implementation {
+	case "leveldb":
+		keyManager = &KeyManagerLeveldb{keysPath: "./rep/key", cookieKeysPath: "./rep/cookie_key"}
+	default:
+		keyManager = &KeyManagerBasic{}
+	}
+	keyManager.init()
+	if _, err := rotateSigningKeys(time.Now()); err != nil {
+		log.Error("can not init signing keys", zap.Error(err))
+		panic("signing key is required to run")
+	}
+}
+
+//GetSigningKey returns the active key used to sign tokens. The stored keys are only rotated when the cached active key
+//must be replaced, the next key prepublished or an expired key removed
+func GetSigningKey() *token.SigningKey {
+	now := time.Now()
+	keysMutex.Lock()
+	key := activeSigningKey
+	cached := key != nil && now.Before(signingKeysCheckAt)
+	keysMutex.Unlock()
+	if cached {
+		return key
+	}
+	key, err := rotateSigningKeys(now)
+	if err != nil {
+		log.Error("can not get signing key", zap.Error(err))
+		return nil
+	}
+	return key
+}
+
+//GetJSONWebKeySet returns the public keys that can be used to verify the tokens signed by the server
+func GetJSONWebKeySet() *token.JSONWebKeySet {
+	now := time.Now()
+	keySet := &token.JSONWebKeySet{
+		Keys: []token.JSONWebKey{},
+	}
+	keysMutex.Lock()
+	defer keysMutex.Unlock()
+	for _, record := range keyManager.getKeys() {
+		if now.After(record.ExpiresAt) {
+			continue
+		}
+		key, err := getSigningKey(record)
+		if err != nil {
+			continue
+		}
+		keySet.Keys = append(keySet.Keys, *key.GetJSONWebKey())
+	}
+	return keySet
+}
+
+//GetSigningAlgorithms returns the algorithms of the keys that sign tokens, now or once activated, and of the keys
+//generated with the configuration
+func GetSigningAlgorithms() []string {
+	now := time.Now()
+	found := map[string]bool{config.IAM.Keys.GetAlgorithm(): true}
+	keysMutex.Lock()
+	for _, record := range keyManager.getKeys() {
+		if now.Before(record.RetiresAt) {
+			found[record.Algorithm] = true
+		}
+	}
+	keysMutex.Unlock()
+	algorithms := make([]string, 0, len(found))
+	for algorithm := range found {
+		algorithms = append(algorithms, algorithm)
+	}
+	sort.Strings(algorithms)
+	return algorithms
+}
+
+//getSigningKeyRetention returns the time a retired key must be published so all tokens signed with it can be validated
+func getSigningKeyRetention() time.Duration {
+	retention := config.IAM.Tokens.GetAccessDuration()
+	if idDuration := config.IAM.Tokens.GetIDTokenDuration(); idDuration > retention {
+		retention = idDuration
+	}
+	return retention
+}
+
+//getSigningKey returns the decoded signing key of the record
+func getSigningKey(record *SigningKeyRecord) (*token.SigningKey, error) {
+	if key, ok := signingKeys[record.ID]; ok {
+		return key, nil
+	}
+	key, err := token.ParseSigningKey(record.ID, record.Algorithm, record.PrivateKey)
+	if err != nil {
+		log.Error("can not decode signing key", zap.String("kid", record.ID), zap.Error(err))
+		return nil, err
+	}
+	signingKeys[key.ID] = key
+	return key, nil
+}
+
+//newSigningKeyRecord generates a new signing key active from the given time
+func newSigningKeyRecord(now time.Time, activatesAt time.Time) (*SigningKeyRecord, error) {
+	key, err := token.NewSigningKey(config.IAM.Keys.GetAlgorithm())
+	if err != nil {
+		return nil, err
+	}
+	der, err := key.MarshalPrivateKey()
+	if err != nil {
+		return nil, err
+	}
+	retiresAt := activatesAt.Add(config.IAM.Keys.GetRotationPeriod())
+	record := &SigningKeyRecord{
+		ID:          key.ID,
+		Algorithm:   key.Algorithm,
+		PrivateKey:  der,
+		Created:     now,
+		ActivatesAt: activatesAt,
+		RetiresAt:   retiresAt,
+		ExpiresAt:   retiresAt.Add(getSigningKeyRetention()),
+	}
+	signingKeys[key.ID] = key
+	return record, nil
+}
+
+//rotateSigningKeys removes expired keys, generates a new key if there is no active key and returns the active key.
+//The key replacing the active key is generated a prepublication period before it signs tokens, so that it is in the
+//published key set of the relying parties when the active key retires
+func rotateSigningKeys(now time.Time) (*token.SigningKey, error) {
+	keysMutex.Lock()
+	defer keysMutex.Unlock()
+	records := keyManager.getKeys()
+	//newest keys first
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].ActivatesAt.After(records[j].ActivatesAt)
+	})
+	var active, next *SigningKeyRecord
+	checkAt := time.Time{}
+	for _, record := range records {
+		if now.After(record.ExpiresAt) {
+			log.Debug("signing key expired", zap.String("kid", record.ID))
+			delete(signingKeys, record.ID)
+			if err := keyManager.deleteKey(record.ID); err != nil {
+				log.Error("can not delete expired signing key", zap.String("kid", record.ID), zap.Error(err))
+			}
+			continue
+		}
+		if now.Before(record.ActivatesAt) {
+			next = record
+			continue
+		}
+		if active == nil && now.Before(record.RetiresAt) {
+			active = record
+			continue
+		}
+		checkAt = earliestTime(checkAt, record.ExpiresAt)
+	}
+	if active == nil {
+		record, err := newSigningKeyRecord(now, now)
+		if err != nil {
+			return nil, err
+		}
+		if err = keyManager.setKey(record); err != nil {
+			return nil, err
+		}
+		log.Info("new signing key activated", zap.String("kid", record.ID), zap.String("algorithm", record.Algorithm), zap.Time("retires", record.RetiresAt))
+		active = record
+	}
+	prepublishAt := active.RetiresAt.Add(-config.IAM.Keys.GetPrepublicationPeriod())
+	if next == nil && !now.Before(prepublishAt) {
+		record, err := newSigningKeyRecord(now, active.RetiresAt)
+		if err != nil {
+			return nil, err
+		}
+		if err = keyManager.setKey(record); err != nil {
+			return nil, err
+		}
+		log.Info("next signing key published", zap.String("kid", record.ID), zap.String("algorithm", record.Algorithm), zap.Time("activates", record.ActivatesAt))
+		next = record
+	}
+	checkAt = earliestTime(checkAt, active.RetiresAt)
+	if next == nil {
+		checkAt = earliestTime(checkAt, prepublishAt)
+	}
+	key, err := getSigningKey(active)
+	if err != nil {
+		return nil, err
+	}
+	activeSigningKey = key
+	signingKeysCheckAt = checkAt
+	return key, nil
+}
+
+//earliestTime returns the earliest of both times, a zero time is ignored
+func earliestTime(t time.Time, u time.Time) time.Time {
+	if t.IsZero() || u.Before(t) {
+		return u
+	}
+	return t
+}
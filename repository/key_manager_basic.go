@@ -0,0 +1,51 @@
+package repository
+
+type KeyManagerBasic struct {
+	cookieKeys map[string]*CookieKeyRecord
+	keys       map[string]*SigningKeyRecord
+}
+
+func (r *KeyManagerBasic) init() {
+	r.cookieKeys = make(map[string]*CookieKeyRecord)
+	r.keys = make(map[string]*SigningKeyRecord)
+}
+
+func (r *KeyManagerBasic) close() {
+	//nothing
+}
+
+func (r *KeyManagerBasic) deleteCookieKey(id string) error {
+	delete(r.cookieKeys, id)
+	return nil
+}
+
+func (r *KeyManagerBasic) deleteKey(id string) error {
+	delete(r.keys, id)
+	return nil
+}
+
+func (r *KeyManagerBasic) getCookieKeys() []*CookieKeyRecord {
+	keys := make([]*CookieKeyRecord, 0, len(r.cookieKeys))
+	for _, key := range r.cookieKeys {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func (r *KeyManagerBasic) getKeys() []*SigningKeyRecord {
+	keys := make([]*SigningKeyRecord, 0, len(r.keys))
+	for _, key := range r.keys {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func (r *KeyManagerBasic) setCookieKey(key *CookieKeyRecord) error {
+	r.cookieKeys[key.ID] = key
+	return nil
+}
+
+func (r *KeyManagerBasic) setKey(key *SigningKeyRecord) error {
+	r.keys[key.ID] = key
+	return nil
+}
@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"bytes"
+	"encoding/gob"
+	"github.com/syndtr/goleveldb/leveldb"
+	"go.uber.org/zap"
+	"os"
+)
+
+//the keys are stored unencrypted, only the owner of the process can read the key store directories
+const keyStorePermissions = 0700
+
+type KeyManagerLeveldb struct {
+	cookieKeys     *leveldb.DB
+	cookieKeysPath string
+	keys           *leveldb.DB
+	keysPath       string
+}
+
+func (r *KeyManagerLeveldb) init() {
+	var err error
+	if len(r.keysPath) == 0 {
+		r.keysPath = "./rep/key"
+	}
+	if len(r.cookieKeysPath) == 0 {
+		r.cookieKeysPath = "./rep/cookie_key"
+	}
+	r.keys, err = leveldb.OpenFile(r.keysPath, nil)
+	if err != nil {
+		log.Error("can not init key repository", zap.Error(err))
+	}
+	r.cookieKeys, err = leveldb.OpenFile(r.cookieKeysPath, nil)
+	if err != nil {
+		log.Error("can not init cookie key repository", zap.Error(err))
+	}
+	for _, path := range []string{r.keysPath, r.cookieKeysPath} {
+		if err = os.Chmod(path, keyStorePermissions); err != nil {
+			log.Error("can not restrict the permissions of the key repository", zap.String("path", path), zap.Error(err))
+		}
+	}
+}
+
+func (r *KeyManagerLeveldb) close() {
+	defer r.keys.Close()
+	defer r.cookieKeys.Close()
+}
+
+func (r *KeyManagerLeveldb) deleteCookieKey(id string) error {
+	err := r.cookieKeys.Delete([]byte(id), nil)
+	if err != nil {
+		log.Error("can not delete cookie key", zap.String("id", id), zap.Error(err))
+		return err
+	}
+	log.Debug("cookie key deleted", zap.String("id", id))
+	return nil
+}
+
+func (r *KeyManagerLeveldb) deleteKey(id string) error {
+	err := r.keys.Delete([]byte(id), nil)
+	if err != nil {
+		log.Error("can not delete signing key", zap.String("kid", id), zap.Error(err))
+		return err
+	}
+	log.Debug("signing key deleted", zap.String("kid", id))
+	return nil
+}
+
+func (r *KeyManagerLeveldb) getCookieKeys() []*CookieKeyRecord {
+	keys := make([]*CookieKeyRecord, 0)
+	iter := r.cookieKeys.NewIterator(nil, nil)
+	defer iter.Release()
+	for iter.Next() {
+		dec := gob.NewDecoder(bytes.NewBuffer(iter.Value()))
+		var key CookieKeyRecord
+		err := dec.Decode(&key)
+		if err != nil {
+			log.Error("can not decode cookie key", zap.ByteString("id", iter.Key()), zap.Error(err))
+			continue
+		}
+		keys = append(keys, &key)
+	}
+	if err := iter.Error(); err != nil {
+		log.Error("can not iterate cookie keys", zap.Error(err))
+	}
+	return keys
+}
+
+func (r *KeyManagerLeveldb) getKeys() []*SigningKeyRecord {
+	keys := make([]*SigningKeyRecord, 0)
+	iter := r.keys.NewIterator(nil, nil)
+	defer iter.Release()
+	for iter.Next() {
+		dec := gob.NewDecoder(bytes.NewBuffer(iter.Value()))
+		var key SigningKeyRecord
+		err := dec.Decode(&key)
+		if err != nil {
+			log.Error("can not decode signing key", zap.ByteString("kid", iter.Key()), zap.Error(err))
+			continue
+		}
+		keys = append(keys, &key)
+	}
+	if err := iter.Error(); err != nil {
+		log.Error("can not iterate signing keys", zap.Error(err))
+	}
+	return keys
+}
+
+func (r *KeyManagerLeveldb) setCookieKey(key *CookieKeyRecord) error {
+	var data bytes.Buffer
+	enc := gob.NewEncoder(&data)
+	err := enc.Encode(key)
+	if err != nil {
+		log.Error("can not encode cookie key", zap.String("id", key.ID), zap.Error(err))
+		return err
+	}
+	err = r.cookieKeys.Put([]byte(key.ID), data.Bytes(), nil)
+	if err != nil {
+		log.Error("can not add cookie key", zap.String("id", key.ID), zap.Error(err))
+		return err
+	}
+	log.Debug("cookie key added", zap.String("id", key.ID))
+	return nil
+}
+
+func (r *KeyManagerLeveldb) setKey(key *SigningKeyRecord) error {
+	var data bytes.Buffer
+	enc := gob.NewEncoder(&data)
+	err := enc.Encode(key)
+	if err != nil {
+		log.Error("can not encode signing key", zap.String("kid", key.ID), zap.Error(err))
+		return err
+	}
+	err = r.keys.Put([]byte(key.ID), data.Bytes(), nil)
+	if err != nil {
+		log.Error("can not add signing key", zap.String("kid", key.ID), zap.Error(err))
+		return err
+	}
+	log.Debug("signing key added", zap.String("kid", key.ID))
+	return nil
+}
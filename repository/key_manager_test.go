@@ -0,0 +1,225 @@
+package repository
+
+import (
+	"bounzr/iam/config"
+	"bounzr/iam/token"
+	"go.uber.org/zap"
+	"os"
+	"testing"
+	"time"
+)
+
+type KeyDataProvider struct {
+	manager        KeyManager
+	algorithm      string
+	rotationPeriod string
+}
+
+var (
+	basicKMTest     = &KeyManagerBasic{}
+	leveldbKMTest   = &KeyManagerLeveldb{keysPath: "../test/key", cookieKeysPath: "../test/cookie_key"}
+	keyDataProvider = []KeyDataProvider{
+		{basicKMTest, "RS256", "1h"},
+		{leveldbKMTest, "ES256", "2h"},
+	}
+)
+
+func executeKeyTest(test func(provider KeyDataProvider)) {
+	os.RemoveAll("../test/")
+	log, _ = zap.NewDevelopment()
+	config.IAM.Tokens.AccessDuration = "10m"
+	config.IAM.Tokens.IDTokenDuration = "5m"
+	for _, provider := range keyDataProvider {
+		config.IAM.Keys.Algorithm = provider.algorithm
+		config.IAM.Keys.RotationPeriod = provider.rotationPeriod
+		keyManager = provider.manager
+		activeSigningKey = nil
+		provider.manager.init()
+		test(provider)
+		provider.manager.close()
+	}
+}
+
+func TestRotateSigningKeys(t *testing.T) {
+	test := func(provider KeyDataProvider) {
+		now := time.Now()
+		rotation := config.IAM.Keys.GetRotationPeriod()
+		prepublication := config.IAM.Keys.GetPrepublicationPeriod()
+		first, err := rotateSigningKeys(now)
+		if err != nil {
+			t.Fatalf("can not create signing key - %s", err.Error())
+		}
+		if first.Algorithm != provider.algorithm {
+			t.Errorf("want %s got %s", provider.algorithm, first.Algorithm)
+		}
+		same, _ := rotateSigningKeys(now.Add(rotation / 4))
+		if same.ID != first.ID || len(provider.manager.getKeys()) != 1 {
+			t.Errorf("want only active key %s got %s of %d keys", first.ID, same.ID, len(provider.manager.getKeys()))
+		}
+		//the next key is published before the active key retires
+		same, _ = rotateSigningKeys(now.Add(rotation - prepublication + time.Minute))
+		if same.ID != first.ID {
+			t.Errorf("want active key %s got %s", first.ID, same.ID)
+		}
+		keys := provider.manager.getKeys()
+		if len(keys) != 2 {
+			t.Fatalf("want next key published got %d keys", len(keys))
+		}
+		var next *SigningKeyRecord
+		for _, record := range keys {
+			if record.ID != first.ID {
+				next = record
+			}
+		}
+		if !next.ActivatesAt.Equal(now.Add(rotation)) {
+			t.Errorf("want next key active at %v got %v", now.Add(rotation), next.ActivatesAt)
+		}
+		//the key is retired after the rotation period
+		second, _ := rotateSigningKeys(now.Add(rotation + time.Minute))
+		if second.ID != next.ID {
+			t.Errorf("want next key %s after rotation got %s", next.ID, second.ID)
+		}
+		if len(provider.manager.getKeys()) != 2 {
+			t.Errorf("want retired key kept got %d keys", len(provider.manager.getKeys()))
+		}
+		//the retired key is removed once the tokens it signed are expired
+		third, _ := rotateSigningKeys(now.Add(rotation + 11*time.Minute))
+		if third.ID != second.ID {
+			t.Errorf("want active key %s got %s", second.ID, third.ID)
+		}
+		keys = provider.manager.getKeys()
+		if len(keys) != 1 || keys[0].ID != second.ID {
+			t.Errorf("want only key %s got %d keys", second.ID, len(keys))
+		}
+	}
+	executeKeyTest(test)
+}
+
+func TestCachedSigningKey(t *testing.T) {
+	test := func(provider KeyDataProvider) {
+		key := GetSigningKey()
+		if key == nil {
+			t.Fatalf("want signing key got nil")
+		}
+		prepublishAt := time.Now().Add(config.IAM.Keys.GetRotationPeriod() - config.IAM.Keys.GetPrepublicationPeriod())
+		if signingKeysCheckAt.After(prepublishAt) {
+			t.Errorf("want keys checked before the next key is published at %v got %v", prepublishAt, signingKeysCheckAt)
+		}
+		//the cached key is returned without reading the stored keys
+		provider.manager.deleteKey(key.ID)
+		if cached := GetSigningKey(); cached != key {
+			t.Errorf("want cached key %s", key.ID)
+		}
+		signingKeysCheckAt = time.Now()
+		if rotated := GetSigningKey(); rotated == nil || rotated.ID == key.ID {
+			t.Errorf("want new key once the keys are checked again")
+		}
+	}
+	executeKeyTest(test)
+}
+
+func TestPersistedSigningKey(t *testing.T) {
+	test := func(provider KeyDataProvider) {
+		key, err := rotateSigningKeys(time.Now())
+		if err != nil {
+			t.Fatalf("can not create signing key - %s", err.Error())
+		}
+		signature, _ := key.Sign(map[string]string{"sub": "test"})
+		//decode the stored key again
+		delete(signingKeys, key.ID)
+		record := provider.manager.getKeys()[0]
+		stored, err := getSigningKey(record)
+		if err != nil {
+			t.Fatalf("can not decode signing key - %s", err.Error())
+		}
+		if _, err = token.Verify(signature, stored.PrivateKey.Public()); err != nil {
+			t.Errorf("want valid signature with stored key - %s", err.Error())
+		}
+		if provider.manager == leveldbKMTest {
+			for _, path := range []string{leveldbKMTest.keysPath, leveldbKMTest.cookieKeysPath} {
+				if info, err := os.Stat(path); err != nil || info.Mode().Perm() != keyStorePermissions {
+					t.Errorf("want key store %s readable only by the owner", path)
+				}
+			}
+		}
+	}
+	executeKeyTest(test)
+}
+
+func TestRotateCookieKeys(t *testing.T) {
+	defer func(cookies config.Cookies) { config.IAM.Cookies = cookies }(config.IAM.Cookies)
+	config.IAM.Cookies.MaxAge = "30m"
+	test := func(provider KeyDataProvider) {
+		config.IAM.Cookies.RotationPeriod = provider.rotationPeriod
+		now := time.Now()
+		rotation := config.IAM.Cookies.GetRotationPeriod()
+		first, err := rotateCookieKeys(now)
+		if err != nil {
+			t.Fatalf("can not create cookie key - %s", err.Error())
+		}
+		if len(first.KeyPairs) != 2 || len(first.KeyPairs[0]) != 64 || len(first.KeyPairs[1]) != 32 {
+			t.Fatalf("want one key pair got %d keys", len(first.KeyPairs))
+		}
+		if !first.RefreshAt.Equal(now.Add(rotation)) {
+			t.Errorf("want refresh at %v got %v", now.Add(rotation), first.RefreshAt)
+		}
+		//the retired pair decodes the cookies until they expire
+		second, _ := rotateCookieKeys(now.Add(rotation + time.Minute))
+		if len(second.KeyPairs) != 4 || string(second.KeyPairs[2]) != string(first.KeyPairs[0]) {
+			t.Fatalf("want new key pair and retired key pair got %d keys", len(second.KeyPairs))
+		}
+		if !second.RefreshAt.Equal(now.Add(rotation + 30*time.Minute)) {
+			t.Errorf("want refresh at the expiration of the retired key pair got %v", second.RefreshAt)
+		}
+		third, _ := rotateCookieKeys(now.Add(rotation + 31*time.Minute))
+		if len(third.KeyPairs) != 2 || string(third.KeyPairs[0]) != string(second.KeyPairs[0]) {
+			t.Errorf("want only the active key pair got %d keys", len(third.KeyPairs))
+		}
+		if len(provider.manager.getCookieKeys()) != 1 {
+			t.Errorf("want expired key pair removed got %d key pairs", len(provider.manager.getCookieKeys()))
+		}
+	}
+	executeKeyTest(test)
+}
+
+func TestConfiguredCookieKeys(t *testing.T) {
+	defer func(cookies config.Cookies) { config.IAM.Cookies = cookies }(config.IAM.Cookies)
+	config.IAM.Cookies.Keys = []config.CookieKey{
+		{AuthenticationKey: "c2lnbmluZy1rZXktb2YtdGhlLWN1cnJlbnQtbm9kZXM=", EncryptionKey: "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="},
+		{AuthenticationKey: "c2lnbmluZy1rZXktb2YtdGhlLXByZXZpb3VzLW5vZGVz"},
+	}
+	test := func(provider KeyDataProvider) {
+		keys, err := GetCookieKeys()
+		if err != nil {
+			t.Fatalf("want no error, got %s", err.Error())
+		}
+		if len(keys.KeyPairs) != 4 || string(keys.KeyPairs[0]) != "signing-key-of-the-current-nodes" || keys.KeyPairs[3] != nil {
+			t.Errorf("want the 2 configured key pairs got %d keys", len(keys.KeyPairs))
+		}
+		if !keys.RefreshAt.IsZero() || len(provider.manager.getCookieKeys()) != 0 {
+			t.Errorf("want no generated key pairs")
+		}
+	}
+	executeKeyTest(test)
+}
+
+func TestGetSigningAlgorithms(t *testing.T) {
+	test := func(provider KeyDataProvider) {
+		if _, err := rotateSigningKeys(time.Now()); err != nil {
+			t.Fatalf("can not create signing key - %s", err.Error())
+		}
+		algorithms := GetSigningAlgorithms()
+		if len(algorithms) != 1 || algorithms[0] != provider.algorithm {
+			t.Errorf("want %s got %v", provider.algorithm, algorithms)
+		}
+		//the keys of the new algorithm are generated at the next rotation
+		config.IAM.Keys.Algorithm = token.RS256
+		if provider.algorithm == token.RS256 {
+			config.IAM.Keys.Algorithm = token.ES256
+		}
+		if algorithms = GetSigningAlgorithms(); len(algorithms) != 2 {
+			t.Errorf("want active and configured algorithms got %v", algorithms)
+		}
+	}
+	executeKeyTest(test)
+}
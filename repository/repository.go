@@ -11,6 +11,7 @@ var (
 	log            *zap.Logger
 	clientManager  ClientManager
 	groupManager   GroupManager
+	keyManager     KeyManager
 	sessionManager SessionManager
 	tokenManager   TokenManager
 )
@@ -21,12 +22,16 @@ func Init() {
 	gob.Register(&SessionToken{})
 	gob.Register(&ConsentToken{})
 	gob.Register(&ResourceTag{})
+	//values of the custom schema extensions
+	gob.Register(map[string]interface{}{})
+	gob.Register([]interface{}{})
 
 	initClients()
 	initUsers()
 	initTokens()
 	initSessions()
 	initGroups()
+	initSigningKeys()
 
 	adminGroup, err := GetGroup(privateGroups["Admins"])
 	if err != nil {
@@ -15,16 +15,18 @@ type ResourceTag struct {
 	ResourceType   string
 	ID             uuid.UUID
 	Name           string
+	Version        uint64 //incremented on every write of the resource
 }
 
 func (m *ResourceTag) GetScimMetadata() *scim2.Metadata {
 	hostname := config.IAM.Server.Hostname
 	location := fmt.Sprintf("https://%s/scim2/%s/%s", hostname, m.ResourceType, m.ID.String())
 	meta := &scim2.Metadata{
-		Created:      m.Created.String(),
-		LastModified: m.LastModified.String(),
+		Created:      m.Created.Format(time.RFC3339),
+		LastModified: m.LastModified.Format(time.RFC3339),
 		Location:     location,
 		ResourceType: m.ResourceType,
+		Version:      scim2.GetETag(m.Version),
 	}
 	return meta
 }
@@ -48,3 +50,9 @@ func (m *ResourceTag) GetUUID() uuid.UUID {
 func (m *ResourceTag) SetResourceType(resourceType string) {
 	m.ResourceType = resourceType
 }
+
+//setModified sets the modification time and increments the version of the resource
+func (m *ResourceTag) setModified(now time.Time) {
+	m.LastModified = now
+	m.Version++
+}
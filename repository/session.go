@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"github.com/gofrs/uuid"
+	"time"
+)
+
+//RFC8176 authentication method reference values of the sessions
+const (
+	MultiFactorAuthentication  = "mfa"
+	OtpAuthentication          = "otp"
+	PasswordAuthentication     = "pwd"
+	RecoveryCodeAuthentication = "recovery" //one-time recovery code used instead of the authenticator
+)
+
+//Session is the login session of an user in the bounzr pages. The token is the secret kept in the session cookie,
+//the ID identifies the session in the administration endpoints
+type Session struct {
+	AuthenticationMethods []string //amr of the login
+	Created               time.Time
+	ID                    uuid.UUID
+	IPAddress             string
+	LastSeen              time.Time //time of the last request authenticated by the session
+	Token                 SessionToken
+	User                  *UserCtx
+	UserAgent             string
+}
+
+//newSession returns a new session of the user created now
+func newSession(token SessionToken, user *UserCtx, ipAddress, userAgent string, authenticationMethods []string) *Session {
+	id, _ := uuid.NewV4()
+	now := time.Now()
+	return &Session{
+		AuthenticationMethods: authenticationMethods,
+		Created:               now,
+		ID:                    id,
+		IPAddress:             ipAddress,
+		LastSeen:              now,
+		Token:                 token,
+		User:                  user,
+		UserAgent:             userAgent,
+	}
+}
+
+//isExpired returns true if the session was idle longer than the idle timeout or was created before the absolute
+//timeout. A timeout of 0 never expires the session
+func (s *Session) isExpired(now time.Time, idleTimeout, absoluteTimeout time.Duration) bool {
+	if idleTimeout > 0 && now.Sub(s.LastSeen) > idleTimeout {
+		return true
+	}
+	if absoluteTimeout > 0 && now.Sub(s.Created) > absoluteTimeout {
+		return true
+	}
+	return false
+}
@@ -2,17 +2,36 @@ package repository
 
 import (
 	"bounzr/iam/config"
-	"bounzr/iam/utils"
+	"bounzr/iam/token"
+	"github.com/gofrs/uuid"
+	"go.uber.org/zap"
+	"sort"
+	"time"
 )
 
 type SessionToken [32]byte
 
+//sessionLastSeenInterval is the minimum time between two writes of the last seen time of a session
+const sessionLastSeenInterval = time.Minute
+
+//newSessionToken returns a session token read from the random source of the token generator. The session cookies
+//keep the 32 bytes of the token whatever the configured entropy
+func newSessionToken() SessionToken {
+	var sessionToken SessionToken
+	copy(sessionToken[:], token.GetRandomBytes(len(sessionToken)))
+	return sessionToken
+}
+
 type SessionManager interface {
-	setSessionContext(token SessionToken, user *UserCtx)
-	deleteSessionContext(token SessionToken) error
-	getSessionContext(token SessionToken) (*UserCtx, error)
+	deleteSession(token SessionToken) error
+	getSession(token SessionToken) (*Session, error)
+	getSessionByID(id uuid.UUID) (*Session, error)
+	getSessions() []*Session
+	getUserSessions(userID uuid.UUID) []*Session
 	init()
 	close()
+	setSession(session *Session)
+	updateSession(session *Session) error //writes the session only if it was not deleted
 }
 
 func initSessions() {
@@ -24,21 +43,136 @@ func initSessions() {
 		sessionManager = &SessionManagerBasic{}
 	}
 	sessionManager.init()
+	sweepSessions(time.Now())
+	if interval := config.IAM.Sessions.GetSweepInterval(); interval > 0 {
+		go sweepSessionsPeriodically(interval)
+	}
+}
+
+//DeleteSession deletes the session with the given ID
+func DeleteSession(id uuid.UUID) error {
+	session, found := GetSession(id)
+	if !found {
+		return ErrSessionNotFound
+	}
+	return sessionManager.deleteSession(session.Token)
 }
 
 //DeleteSessionUser Delete the caches related to the given session token if the token is correct
 func DeleteSessionUser(token SessionToken) error {
-	return sessionManager.deleteSessionContext(token)
+	return sessionManager.deleteSession(token)
+}
+
+//DeleteUserSessions deletes all the sessions of the user and returns the number of deleted sessions
+func DeleteUserSessions(userID uuid.UUID) int {
+	deleted := 0
+	for _, session := range sessionManager.getUserSessions(userID) {
+		if err := sessionManager.deleteSession(session.Token); err == nil {
+			deleted++
+		}
+	}
+	log.Debug("user sessions deleted", zap.String("user id", userID.String()), zap.Int("sessions", deleted))
+	return deleted
 }
 
-//GetSessionUser Returns the user model stored in the user cache for the given ID.
+//GetSession returns the session with the given ID if it is not expired
+func GetSession(id uuid.UUID) (*Session, bool) {
+	session, err := sessionManager.getSessionByID(id)
+	if err != nil || isSessionExpired(session, time.Now()) {
+		return nil, false
+	}
+	return session, true
+}
+
+//GetSessions returns the sessions that are not expired sorted by creation time
+func GetSessions() []*Session {
+	return filterSessions(sessionManager.getSessions())
+}
+
+//GetSessionUser Returns the user of the session token and refreshes the last seen time of the session at most once
+//every sessionLastSeenInterval. Expired sessions are deleted
 func GetSessionUser(token SessionToken) (*UserCtx, error) {
-	return sessionManager.getSessionContext(token)
+	session, err := sessionManager.getSession(token)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if isSessionExpired(session, now) {
+		log.Debug("session expired", zap.String("session id", session.ID.String()), zap.String("user id", session.User.UserID.String()))
+		sessionManager.deleteSession(token)
+		return nil, ErrSessionExpired
+	}
+	if now.Sub(session.LastSeen) >= getSessionLastSeenInterval() {
+		session.LastSeen = now
+		//the session is not written back if it was deleted meanwhile
+		if err = sessionManager.updateSession(session); err != nil {
+			return nil, err
+		}
+	}
+	return session.User, nil
 }
 
-//NewSessionToken generate session token for user ctx
-func NewSessionToken(user *UserCtx) SessionToken {
-	token := utils.GetRandom32Token()
-	sessionManager.setSessionContext(token, user)
+//GetUserSessions returns the sessions of the user that are not expired sorted by creation time
+func GetUserSessions(userID uuid.UUID) []*Session {
+	return filterSessions(sessionManager.getUserSessions(userID))
+}
+
+//NewSession creates a session for the user ctx and returns its token. The IP address, user agent and authentication
+//methods of the login are kept in the session
+func NewSession(user *UserCtx, ipAddress, userAgent string, authenticationMethods []string) SessionToken {
+	token := newSessionToken()
+	sessionManager.setSession(newSession(token, user, ipAddress, userAgent, authenticationMethods))
 	return token
 }
+
+//filterSessions returns the sessions that are not expired sorted by creation time
+func filterSessions(all []*Session) []*Session {
+	now := time.Now()
+	sessions := make([]*Session, 0, len(all))
+	for _, session := range all {
+		if !isSessionExpired(session, now) {
+			sessions = append(sessions, session)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].Created.Before(sessions[j].Created) })
+	return sessions
+}
+
+//getSessionLastSeenInterval returns sessionLastSeenInterval, shortened for idle timeouts under ten intervals so that
+//active sessions do not expire
+func getSessionLastSeenInterval() time.Duration {
+	if idleTimeout := config.IAM.Sessions.GetIdleTimeout(); idleTimeout > 0 && idleTimeout < sessionLastSeenInterval*10 {
+		return idleTimeout / 10
+	}
+	return sessionLastSeenInterval
+}
+
+func isSessionExpired(session *Session, now time.Time) bool {
+	return session.isExpired(now, config.IAM.Sessions.GetIdleTimeout(), config.IAM.Sessions.GetAbsoluteTimeout())
+}
+
+//sweepSessions deletes the expired sessions and returns the number of deleted sessions
+func sweepSessions(now time.Time) int {
+	deleted := 0
+	for _, session := range sessionManager.getSessions() {
+		if !isSessionExpired(session, now) {
+			continue
+		}
+		if err := sessionManager.deleteSession(session.Token); err == nil {
+			deleted++
+		}
+	}
+	if deleted > 0 {
+		log.Debug("expired sessions deleted", zap.Int("sessions", deleted))
+	}
+	return deleted
+}
+
+//sweepSessionsPeriodically deletes the expired sessions every interval
+func sweepSessionsPeriodically(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		sweepSessions(now)
+	}
+}
@@ -1,34 +1,113 @@
 package repository
 
+import (
+	"github.com/gofrs/uuid"
+	"sync"
+)
+
 type SessionManagerBasic struct {
-	sessionCache map[SessionToken]*UserCtx
+	mutex        sync.RWMutex //sessions are read by the requests and swept in the background
+	sessionCache map[SessionToken]Session
+	sessionIDs   map[uuid.UUID]SessionToken                 //index of the sessions by ID
+	userSessions map[uuid.UUID]map[SessionToken]interface{} //index of the sessions by user ID
 }
 
 func (r *SessionManagerBasic) init() {
-	r.sessionCache = make(map[SessionToken]*UserCtx)
+	r.sessionCache = make(map[SessionToken]Session)
+	r.sessionIDs = make(map[uuid.UUID]SessionToken)
+	r.userSessions = make(map[uuid.UUID]map[SessionToken]interface{})
 }
 
 func (r *SessionManagerBasic) close() {
 	//nothing
 }
 
-func (r *SessionManagerBasic) setSessionContext(token SessionToken, user *UserCtx) {
-	r.sessionCache[token] = user
+func (r *SessionManagerBasic) setSession(session *Session) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.removeSession(session.Token)
+	r.sessionCache[session.Token] = *session
+	r.sessionIDs[session.ID] = session.Token
+	userID := session.User.UserID
+	if _, ok := r.userSessions[userID]; !ok {
+		r.userSessions[userID] = make(map[SessionToken]interface{})
+	}
+	r.userSessions[userID][session.Token] = nil
 }
 
-func (r *SessionManagerBasic) deleteSessionContext(token SessionToken) error {
-	_, ok := r.sessionCache[token]
-	if ok {
-		delete(r.sessionCache, token)
+func (r *SessionManagerBasic) updateSession(session *Session) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if _, ok := r.sessionCache[session.Token]; !ok {
+		return ErrSessionNotFound
+	}
+	r.sessionCache[session.Token] = *session
+	return nil
+}
+
+func (r *SessionManagerBasic) deleteSession(token SessionToken) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.removeSession(token) {
 		return nil
 	}
 	return ErrSessionInvalid
 }
 
-func (r *SessionManagerBasic) getSessionContext(token SessionToken) (*UserCtx, error) {
-	user, ok := r.sessionCache[token]
+func (r *SessionManagerBasic) getSession(token SessionToken) (*Session, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	session, ok := r.sessionCache[token]
+	if ok {
+		return &session, nil
+	}
+	return nil, ErrSessionNotFound
+}
+
+func (r *SessionManagerBasic) getSessionByID(id uuid.UUID) (*Session, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	session, ok := r.sessionCache[r.sessionIDs[id]]
 	if ok {
-		return user, nil
+		return &session, nil
 	}
 	return nil, ErrSessionNotFound
 }
+
+func (r *SessionManagerBasic) getSessions() []*Session {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	sessions := make([]*Session, 0, len(r.sessionCache))
+	for _, session := range r.sessionCache {
+		session := session
+		sessions = append(sessions, &session)
+	}
+	return sessions
+}
+
+func (r *SessionManagerBasic) getUserSessions(userID uuid.UUID) []*Session {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	sessions := make([]*Session, 0, len(r.userSessions[userID]))
+	for token := range r.userSessions[userID] {
+		session := r.sessionCache[token]
+		sessions = append(sessions, &session)
+	}
+	return sessions
+}
+
+//removeSession removes the session and its index entries. The caller must hold the write lock
+func (r *SessionManagerBasic) removeSession(token SessionToken) bool {
+	session, ok := r.sessionCache[token]
+	if !ok {
+		return false
+	}
+	delete(r.sessionCache, token)
+	delete(r.sessionIDs, session.ID)
+	userID := session.User.UserID
+	delete(r.userSessions[userID], token)
+	if len(r.userSessions[userID]) == 0 {
+		delete(r.userSessions, userID)
+	}
+	return true
+}
@@ -3,13 +3,21 @@ package repository
 import (
 	"bytes"
 	"encoding/gob"
+	"github.com/gofrs/uuid"
 	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
 	"go.uber.org/zap"
+	"sync"
 )
 
 type SessionManagerLeveldb struct {
+	idsDB        *leveldb.DB //index of the sessions by ID
+	idsPath      string
+	mutex        sync.Mutex //writes of the sessions and of their indexes
 	sessions     *leveldb.DB
 	sessionsPath string
+	usersDB      *leveldb.DB //index of the sessions by user ID followed by the token
+	usersPath    string
 }
 
 func (r *SessionManagerLeveldb) init() {
@@ -21,52 +29,180 @@ func (r *SessionManagerLeveldb) init() {
 	if err != nil {
 		log.Error("can not init session repository", zap.Error(err))
 	}
+	if len(r.idsPath) == 0 {
+		r.idsPath = "./rep/session_id"
+	}
+	r.idsDB, err = leveldb.OpenFile(r.idsPath, nil)
+	if err != nil {
+		log.Error("can not init session id repository", zap.Error(err))
+	}
+	if len(r.usersPath) == 0 {
+		r.usersPath = "./rep/session_user"
+	}
+	r.usersDB, err = leveldb.OpenFile(r.usersPath, nil)
+	if err != nil {
+		log.Error("can not init session user repository", zap.Error(err))
+	}
+	r.indexSessions()
 }
 
 func (r *SessionManagerLeveldb) close() {
 	defer r.sessions.Close()
+	defer r.idsDB.Close()
+	defer r.usersDB.Close()
 }
 
-func (r *SessionManagerLeveldb) setSessionContext(token SessionToken, user *UserCtx) {
-	var data bytes.Buffer
-	enc := gob.NewEncoder(&data)
-	err := enc.Encode(user)
+func (r *SessionManagerLeveldb) setSession(session *Session) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.putSession(session)
+}
+
+func (r *SessionManagerLeveldb) updateSession(session *Session) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	found, err := r.sessions.Has(session.Token[:], nil)
 	if err != nil {
-		log.Error("can not encode user context", zap.String("user id", user.UserID.String()), zap.Error(err))
+		log.Error("can not get session", zap.Error(err))
+		return err
 	}
-	err = r.sessions.Put(token[:], data.Bytes(), nil)
-	if err != nil {
-		log.Error("can not update session context", zap.String("user id", user.UserID.String()), zap.Error(err))
-	} else {
-		log.Debug("session context udpated for user", zap.String("user id", user.UserID.String()))
+	if !found {
+		log.Debug("session not found, it will not be updated")
+		return ErrSessionNotFound
 	}
+	r.putSession(session)
+	return nil
 }
 
-func (r *SessionManagerLeveldb) deleteSessionContext(token SessionToken) error {
-	err := r.sessions.Delete(token[:], nil)
+func (r *SessionManagerLeveldb) deleteSession(token SessionToken) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	session, err := r.getSession(token)
+	if err == nil {
+		r.idsDB.Delete(session.ID.Bytes(), nil)
+		r.usersDB.Delete(getUserSessionKey(session.User.UserID, token), nil)
+	}
+	err = r.sessions.Delete(token[:], nil)
 	if err != nil {
-		log.Error("can not delete session context", zap.Error(err))
+		log.Error("can not delete session", zap.Error(err))
 		return err
 	} else {
-		log.Debug("session context was deleted")
+		log.Debug("session was deleted")
 	}
 	return nil
 }
 
-func (r *SessionManagerLeveldb) getSessionContext(token SessionToken) (*UserCtx, error) {
+func (r *SessionManagerLeveldb) getSession(token SessionToken) (*Session, error) {
 	dataBytes, err := r.sessions.Get(token[:], nil)
+	if err == leveldb.ErrNotFound {
+		log.Debug("session not found")
+		return nil, ErrSessionNotFound
+	}
 	if err != nil {
-		log.Error("can not get session context", zap.Error(err))
+		log.Error("can not get session", zap.Error(err))
 		return nil, err
 	}
 	data := bytes.NewBuffer(dataBytes)
 	dec := gob.NewDecoder(data)
-	var user UserCtx
-	err = dec.Decode(&user)
+	var session Session
+	err = dec.Decode(&session)
 	if err != nil {
-		log.Error("can not decode user context", zap.Error(err))
+		log.Error("can not decode session", zap.Error(err))
 		return nil, err
 	}
-	log.Debug("user context retrieved", zap.String("user id", user.UserID.String()))
-	return &user, nil
+	log.Debug("session retrieved", zap.String("user id", session.User.UserID.String()))
+	return &session, nil
+}
+
+func (r *SessionManagerLeveldb) getSessionByID(id uuid.UUID) (*Session, error) {
+	tokenBytes, err := r.idsDB.Get(id.Bytes(), nil)
+	if err == leveldb.ErrNotFound {
+		log.Debug("session not found", zap.String("session id", id.String()))
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		log.Error("can not get session id", zap.String("session id", id.String()), zap.Error(err))
+		return nil, err
+	}
+	var token SessionToken
+	copy(token[:], tokenBytes)
+	return r.getSession(token)
+}
+
+func (r *SessionManagerLeveldb) getSessions() []*Session {
+	sessions := make([]*Session, 0)
+	iter := r.sessions.NewIterator(nil, nil)
+	defer iter.Release()
+	for iter.Next() {
+		dec := gob.NewDecoder(bytes.NewBuffer(iter.Value()))
+		var session Session
+		err := dec.Decode(&session)
+		if err != nil {
+			//sessions that can not be read are never swept otherwise
+			log.Error("can not decode session, it will be deleted", zap.Error(err))
+			r.sessions.Delete(iter.Key(), nil)
+			continue
+		}
+		sessions = append(sessions, &session)
+	}
+	if err := iter.Error(); err != nil {
+		log.Error("can not iterate sessions", zap.Error(err))
+	}
+	return sessions
+}
+
+func (r *SessionManagerLeveldb) getUserSessions(userID uuid.UUID) []*Session {
+	sessions := make([]*Session, 0)
+	iter := r.usersDB.NewIterator(util.BytesPrefix(userID.Bytes()), nil)
+	defer iter.Release()
+	for iter.Next() {
+		var token SessionToken
+		copy(token[:], iter.Key()[len(userID.Bytes()):])
+		if session, err := r.getSession(token); err == nil {
+			sessions = append(sessions, session)
+		}
+	}
+	if err := iter.Error(); err != nil {
+		log.Error("can not iterate user sessions", zap.String("user id", userID.String()), zap.Error(err))
+	}
+	return sessions
+}
+
+//indexSessions adds the sessions stored without index entries to the indexes
+func (r *SessionManagerLeveldb) indexSessions() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, session := range r.getSessions() {
+		if found, err := r.idsDB.Has(session.ID.Bytes(), nil); err == nil && !found {
+			r.putSession(session)
+		}
+	}
+}
+
+//putSession writes the session and its index entries. The caller must hold the mutex
+func (r *SessionManagerLeveldb) putSession(session *Session) {
+	var data bytes.Buffer
+	enc := gob.NewEncoder(&data)
+	err := enc.Encode(session)
+	if err != nil {
+		log.Error("can not encode session", zap.String("user id", session.User.UserID.String()), zap.Error(err))
+		return
+	}
+	err = r.sessions.Put(session.Token[:], data.Bytes(), nil)
+	if err != nil {
+		log.Error("can not update session", zap.String("user id", session.User.UserID.String()), zap.Error(err))
+		return
+	}
+	if err = r.idsDB.Put(session.ID.Bytes(), session.Token[:], nil); err != nil {
+		log.Error("can not update session id", zap.String("user id", session.User.UserID.String()), zap.Error(err))
+	}
+	if err = r.usersDB.Put(getUserSessionKey(session.User.UserID, session.Token), nil, nil); err != nil {
+		log.Error("can not update user session", zap.String("user id", session.User.UserID.String()), zap.Error(err))
+	}
+	log.Debug("session udpated for user", zap.String("user id", session.User.UserID.String()))
+}
+
+//getUserSessionKey returns the key of the session in the user index
+func getUserSessionKey(userID uuid.UUID, token SessionToken) []byte {
+	return append(userID.Bytes(), token[:]...)
 }
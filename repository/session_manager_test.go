@@ -1,11 +1,12 @@
 package repository
 
 import (
-	"bounzr/iam/utils"
+	"bounzr/iam/config"
 	"github.com/gofrs/uuid"
 	"go.uber.org/zap"
 	"os"
 	"testing"
+	"time"
 )
 
 type SessionDataProvider struct {
@@ -19,10 +20,10 @@ type SessionDataProvider struct {
 
 var (
 	basicSMTest         = &SessionManagerBasic{}
-	leveldbSMTest       = &SessionManagerLeveldb{sessionsPath: "../test/session"}
+	leveldbSMTest       = &SessionManagerLeveldb{sessionsPath: "../test/session", idsPath: "../test/session_id", usersPath: "../test/session_user"}
 	sessionDataProvider = []SessionDataProvider{
-		{basicSMTest, "basic", "testusername", "testuserpwd", uuid.FromStringOrNil("2490c31d-3005-47b4-9bc0-45952a2e505e"), utils.GetRandom32Token()},
-		{leveldbSMTest, "leveldb", "otherusername", "otheruserpwd", uuid.FromStringOrNil("68d0dffb-3dbf-4086-965f-33dd5d012995"), utils.GetRandom32Token()},
+		{basicSMTest, "basic", "testusername", "testuserpwd", uuid.FromStringOrNil("2490c31d-3005-47b4-9bc0-45952a2e505e"), newSessionToken()},
+		{leveldbSMTest, "leveldb", "otherusername", "otheruserpwd", uuid.FromStringOrNil("68d0dffb-3dbf-4086-965f-33dd5d012995"), newSessionToken()},
 	}
 )
 
@@ -38,7 +39,7 @@ func executeSessionTest(test func(provider SessionDataProvider)) {
 		}
 		provider.manager.init()
 		ctx := user.GetUserCtx()
-		provider.manager.setSessionContext(provider.token, ctx)
+		provider.manager.setSession(newSession(provider.token, ctx, "192.0.2.1", "test", []string{PasswordAuthentication}))
 		test(provider)
 		provider.manager.close()
 	}
@@ -46,14 +47,14 @@ func executeSessionTest(test func(provider SessionDataProvider)) {
 
 func TestGetSession(t *testing.T) {
 	test := func(provider SessionDataProvider) {
-		ctx, err := provider.manager.getSessionContext(provider.token)
+		session, err := provider.manager.getSession(provider.token)
 		if err != nil {
-			t.Errorf("session not found - %s", err.Error())
+			t.Fatalf("session not found - %s", err.Error())
 		}
-		if ctx.UserID != provider.id {
-			t.Errorf("want %v got %v", provider.id, ctx.UserID)
+		if session.User.UserID != provider.id {
+			t.Errorf("want %v got %v", provider.id, session.User.UserID)
 		}
-		err = provider.manager.deleteSessionContext(provider.token)
+		err = provider.manager.deleteSession(provider.token)
 		if err != nil {
 			t.Errorf("session not deleted - %s", err.Error())
 		}
@@ -68,3 +69,107 @@ func TestGetSession(t *testing.T) {
 	}
 	executeSessionTest(test)
 }
+
+func TestSessionExpired(t *testing.T) {
+	now := time.Now()
+	session := &Session{Created: now.Add(-time.Hour * 2), LastSeen: now.Add(-time.Minute * 10)}
+	tests := []struct {
+		idle     time.Duration
+		absolute time.Duration
+		expired  bool
+	}{
+		{time.Minute * 30, time.Hour * 12, false},
+		{time.Minute * 5, time.Hour * 12, true},
+		{time.Minute * 30, time.Hour, true},
+		{0, 0, false},
+	}
+	for _, test := range tests {
+		if expired := session.isExpired(now, test.idle, test.absolute); expired != test.expired {
+			t.Errorf("idle timeout %v and absolute timeout %v: want expired %v, got %v", test.idle, test.absolute, test.expired, expired)
+		}
+	}
+}
+
+func TestUserSessions(t *testing.T) {
+	defer func(sessions config.Sessions) { config.IAM.Sessions = sessions }(config.IAM.Sessions)
+	config.IAM.Sessions.IdleTimeout = "30m"
+	config.IAM.Sessions.AbsoluteTimeout = "12h"
+	test := func(provider SessionDataProvider) {
+		sessionManager = provider.manager
+		user := &UserCtx{RepositoryName: provider.repository, UserID: provider.id, UserName: provider.username}
+		token := NewSession(user, "192.0.2.2", "test", []string{PasswordAuthentication})
+		sessions := GetUserSessions(provider.id)
+		if len(sessions) != 2 || sessions[1].Token != token || sessions[1].IPAddress != "192.0.2.2" {
+			t.Fatalf("want 2 sessions of the user, got %d", len(sessions))
+		}
+		if session, found := GetSession(sessions[1].ID); !found || session.Token != token {
+			t.Errorf("want session %v found by id", sessions[1].ID)
+		}
+		if other := GetUserSessions(uuid.Must(uuid.NewV4())); len(other) != 0 {
+			t.Errorf("want no sessions of other user, got %d", len(other))
+		}
+		//the session of the provider is idle and expired
+		session, _ := provider.manager.getSession(provider.token)
+		session.LastSeen = time.Now().Add(-time.Hour)
+		provider.manager.setSession(session)
+		if _, err := GetSessionUser(provider.token); err != ErrSessionExpired {
+			t.Errorf("want error %v, got %v", ErrSessionExpired, err)
+		}
+		if ctx, err := GetSessionUser(token); err != nil || ctx.UserID != provider.id {
+			t.Errorf("want user %v, got %v", provider.id, err)
+		}
+		//the session created 13 hours ago is swept
+		session, _ = provider.manager.getSession(token)
+		session.Created = time.Now().Add(-time.Hour * 13)
+		provider.manager.setSession(session)
+		if deleted := sweepSessions(time.Now()); deleted != 1 {
+			t.Errorf("want 1 expired session deleted, got %d", deleted)
+		}
+		NewSession(user, "192.0.2.3", "test", []string{PasswordAuthentication})
+		NewSession(user, "192.0.2.4", "test", []string{PasswordAuthentication})
+		if deleted := DeleteUserSessions(provider.id); deleted != 2 || len(GetSessions()) != 0 {
+			t.Errorf("want 2 sessions deleted, got %d", deleted)
+		}
+	}
+	executeSessionTest(test)
+}
+
+func TestUpdateSession(t *testing.T) {
+	defer func(sessions config.Sessions) { config.IAM.Sessions = sessions }(config.IAM.Sessions)
+	config.IAM.Sessions.IdleTimeout = "30m"
+	test := func(provider SessionDataProvider) {
+		sessionManager = provider.manager
+		session, _ := provider.manager.getSession(provider.token)
+		//the last seen time is not written on every request
+		lastSeen := session.LastSeen
+		if _, err := GetSessionUser(provider.token); err != nil {
+			t.Fatalf("want no error, got %s", err.Error())
+		}
+		if session, _ = provider.manager.getSession(provider.token); !session.LastSeen.Equal(lastSeen) {
+			t.Errorf("want last seen time %v kept, got %v", lastSeen, session.LastSeen)
+		}
+		session.LastSeen = time.Now().Add(-sessionLastSeenInterval * 2)
+		provider.manager.setSession(session)
+		if _, err := GetSessionUser(provider.token); err != nil {
+			t.Fatalf("want no error, got %s", err.Error())
+		}
+		if session, _ = provider.manager.getSession(provider.token); time.Since(session.LastSeen) > sessionLastSeenInterval {
+			t.Errorf("want last seen time updated, got %v", session.LastSeen)
+		}
+		//a revoked session is not written back
+		DeleteSession(session.ID)
+		if err := provider.manager.updateSession(session); err != ErrSessionNotFound {
+			t.Errorf("want error %v, got %v", ErrSessionNotFound, err)
+		}
+		if _, err := provider.manager.getSession(provider.token); err != ErrSessionNotFound {
+			t.Errorf("want error %v, got %v", ErrSessionNotFound, err)
+		}
+		if _, err := provider.manager.getSessionByID(session.ID); err != ErrSessionNotFound {
+			t.Errorf("want session id %v removed from the index, got %v", session.ID, err)
+		}
+		if sessions := provider.manager.getUserSessions(provider.id); len(sessions) != 0 {
+			t.Errorf("want no sessions of the user, got %d", len(sessions))
+		}
+	}
+	executeSessionTest(test)
+}
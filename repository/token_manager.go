@@ -38,21 +38,44 @@ func AuthorizationCodeGrantOptions(cliCtx *oauth2.ClientCtx, request *oauth2.Aut
 		log.Error("invalid authorization code", zap.String("client ID", cliCtx.GetClientID().String()), zap.Error(oauth2.ErrInvalidRequestInfo))
 		return nil
 	}
+	if client.RequiresPKCE() && !authCode.HasCodeChallenge() {
+		log.Error("authorization code was not bound to a code challenge", zap.String("client ID", cliCtx.GetClientID().String()), zap.Error(oauth2.ErrInvalidRequestInfo))
+		return nil
+	}
+	audience, ok := getTokenAudience(request.Resource)
+	if !ok {
+		log.Error("invalid resource", zap.String("client ID", cliCtx.GetClientID().String()), zap.Error(oauth2.ErrInvalidTargetInfo))
+		return nil
+	}
 	options := &oauth2.AccessTokenOptions{
+		Audience:        audience,
 		ClientID:        authCode.ClientID,
 		AddRefreshToken: true,
+		Nonce:           authCode.Nonce,
 		Scope:           authCode.Scope,
 		OwnerID:         authCode.OwnerID,
 	}
 	return options
 }
 
+//getTokenAudience returns the resource indicator of the token request or the default resource if it is omitted
+func getTokenAudience(resource string) (string, bool) {
+	if len(resource) == 0 {
+		return config.IAM.Tokens.GetResource(), true
+	}
+	return resource, oauth2.IsValidResource(resource)
+}
+
 func DeleteOauth2AccessToken(tokenHint *oauth2.AccessTokenHint) {
 	tokenManager.deleteAccessToken(tokenHint)
 }
 
 func getAccessTokens(opt *oauth2.AccessTokenOptions) (accessToken, refreshToken *oauth2.TokenUnit) {
 	accessToken, refreshToken = oauth2.NewTokenSet(opt, config.IAM.Tokens.GetAccessDuration(), config.IAM.Tokens.GetRefreshDuration())
+	if accessToken == nil {
+		log.Error("could not create access token", zap.String("client ID", opt.ClientID.String()), zap.String("owner ID", opt.OwnerID.String()), zap.String("format", opt.TokenFormat.String()))
+		return nil, nil
+	}
 	err := tokenManager.setTokenUnit(accessToken)
 	if err != nil {
 		log.Error("could not get access token", zap.String("client ID", opt.ClientID.String()), zap.String("owner ID", opt.OwnerID.String()))
@@ -98,14 +121,11 @@ func IntrospectAccessToken(hint *oauth2.AccessTokenHint) (response *oauth2.Intro
 		return
 	}
 	response = token.GetIntrospectionResponse()
-	client, ok := GetClient(uuid.FromStringOrNil(response.ClientID))
-	//todo audience is the protected resource
-	if ok {
-		log.Debug("client URI found", zap.String("id", response.ClientID), zap.String("URI", client.URI))
-		response.Audience = client.URI
-	} else {
-		log.Debug("client URI not found", zap.String("id", response.ClientID))
+	//tokens issued before the audience was stored are intended for the default resource
+	if len(response.Audience) == 0 {
+		response.Audience = config.IAM.Tokens.GetResource()
 	}
+	client, _ := GetClient(uuid.FromStringOrNil(response.ClientID))
 
 	if response.OwnerID == response.ClientID {
 		response.OwnerName = client.Name
@@ -119,8 +139,11 @@ func IntrospectAccessToken(hint *oauth2.AccessTokenHint) (response *oauth2.Intro
 		}
 	}
 
-	response.Issuer = "https://" + config.IAM.Server.Hostname + ":" + config.IAM.Server.Port
+	response.Issuer = config.IAM.Server.GetIssuer()
 	response.TokenID = string(token.Token)
+	if token.TokenFormat == oauth2.JWTTokenFormat {
+		response.TokenID = token.TokenID
+	}
 	return
 }
 
@@ -173,8 +196,14 @@ func ClientCredentialsGrantOptions(cliCtx *oauth2.ClientCtx, request *oauth2.Cli
 		log.Error("client did not accept the request", zap.String("client ID", cliCtx.GetClientID().String()), zap.Error(oauth2.ErrInvalidRequestInfo))
 		return nil
 	}
+	audience, ok := getTokenAudience(request.Resource)
+	if !ok {
+		log.Error("invalid resource", zap.String("client ID", cliCtx.GetClientID().String()), zap.Error(oauth2.ErrInvalidTargetInfo))
+		return nil
+	}
 	validScope := client.ValidateScope(request.Scope)
 	options := &oauth2.AccessTokenOptions{
+		Audience:        audience,
 		ClientID:        client.ID,
 		AddRefreshToken: false,
 		Scope:           []byte(validScope),
@@ -194,13 +223,24 @@ func OwnerPasswordGrantOptions(cliCtx *oauth2.ClientCtx, request *oauth2.OwnerPa
 		log.Error("client did not accept the request", zap.String("client ID", cliCtx.GetClientID().String()), zap.Error(oauth2.ErrInvalidRequestInfo))
 		return nil
 	}
+	audience, ok := getTokenAudience(request.Resource)
+	if !ok {
+		log.Error("invalid resource", zap.String("client ID", cliCtx.GetClientID().String()), zap.Error(oauth2.ErrInvalidTargetInfo))
+		return nil
+	}
 	userCtx, valid := ValidateUser(request.Username, request.Password)
 	if !valid {
 		log.Error("invalid user credentials", zap.String("client ID", cliCtx.GetClientID().String()), zap.Error(oauth2.ErrAccessDeniedInfo))
 		return nil
 	}
+	//the password grant can not validate a second factor
+	if RequiresSecondFactor(userCtx.UserID) {
+		log.Error("password grant of user requiring a second factor", zap.String("client ID", cliCtx.GetClientID().String()), zap.Error(oauth2.ErrAccessDeniedInfo))
+		return nil
+	}
 	validScope := client.ValidateScope(request.Scope)
 	options := &oauth2.AccessTokenOptions{
+		Audience:        audience,
 		ClientID:        client.ID,
 		AddRefreshToken: true,
 		Scope:           []byte(validScope),
@@ -225,10 +265,25 @@ func RefreshTokenGrantOptions(cliCtx *oauth2.ClientCtx, request *oauth2.RefreshA
 		log.Error("invalid refresh token", zap.String("client ID", cliCtx.GetClientID().String()), zap.Error(oauth2.ErrAccessDeniedInfo))
 		return nil
 	}
+	//refresh tokens are bound to the client they were issued to
+	if refreshToken.GetClient() != client.ID || refreshToken.TokenHintType != oauth2.RefreshTokenHintType {
+		log.Error("refresh token was not issued to the client", zap.String("client ID", cliCtx.GetClientID().String()), zap.Error(oauth2.ErrAccessDeniedInfo))
+		return nil
+	}
+	if !refreshToken.Active || !utils.InTimeSpan(refreshToken.IssuedAt, refreshToken.ExpirationTime, time.Now()) {
+		log.Error("refresh token expired", zap.String("client ID", cliCtx.GetClientID().String()), zap.Error(oauth2.ErrAccessDeniedInfo))
+		return nil
+	}
+	audience, ok := getTokenAudience(request.Resource)
+	if !ok {
+		log.Error("invalid resource", zap.String("client ID", cliCtx.GetClientID().String()), zap.Error(oauth2.ErrInvalidTargetInfo))
+		return nil
+	}
 	validScope := client.ValidateScope(request.Scope)
 	validScope = refreshToken.ValidateScope(validScope)
 
 	options := &oauth2.AccessTokenOptions{
+		Audience:        audience,
 		ClientID:        refreshToken.GetClient(),
 		AddRefreshToken: true,
 		Scope:           []byte(validScope),
@@ -242,10 +297,18 @@ func RequestAccessToken(opt *oauth2.AccessTokenOptions) (response *oauth2.Access
 	if opt == nil {
 		return nil, oauth2.ErrInvalidRequest
 	}
+	opt.Issuer = config.IAM.Server.GetIssuer()
 	client, found := GetClient(opt.ClientID)
 	if !found {
 		return nil, oauth2.ErrUnauthorizedClient
 	}
+	if len(opt.Audience) == 0 {
+		opt.Audience = config.IAM.Tokens.GetResource()
+	}
+	opt.TokenFormat = client.GetAccessTokenFormat()
+	if opt.TokenFormat == oauth2.JWTTokenFormat {
+		opt.SigningKey = GetSigningKey()
+	}
 	//ownerID can be the client self in client credentials grant
 	var owner AccessTokenHolder
 	if opt.ClientID == opt.OwnerID {
@@ -268,9 +331,10 @@ func RequestAccessToken(opt *oauth2.AccessTokenOptions) (response *oauth2.Access
 	if ok {
 		//review if token is valid in the repository
 		accessToken, ok = ValidateAccessToken(accessTokenHint)
-		//return if token still exists and valid or remove invalid token from user
-		if !ok {
+		//return if token still exists and valid for the requested resource or remove invalid token from user
+		if !ok || accessToken.Audience != opt.Audience {
 			owner.DeleteClientAccessToken(client.ID)
+			accessToken = nil
 		}
 	}
 
@@ -300,7 +364,11 @@ func RequestAccessToken(opt *oauth2.AccessTokenOptions) (response *oauth2.Access
 		}
 	} else {
 		//generate all token from scratch since we couldnt find an old token
-		response = owner.SetClientTokens(getAccessTokens(opt))
+		accessToken, refreshToken := getAccessTokens(opt)
+		if accessToken == nil {
+			return nil, oauth2.ErrServerError
+		}
+		response = owner.SetClientTokens(accessToken, refreshToken)
 	}
 	switch owner.(type) {
 	case *User:
@@ -317,9 +385,28 @@ func RequestAccessToken(opt *oauth2.AccessTokenOptions) (response *oauth2.Access
 		log.Error("token holder type not identifed", zap.String("resource id", opt.ClientID.String()))
 	}
 
+	//OpenID Connect authentication returns an ID Token for the end-user
+	if _, isUser := owner.(*User); isUser && oauth2.HasScope(string(opt.Scope), oauth2.OpenIDScope) {
+		response.IDToken, err = newIDToken(opt, response)
+		if err != nil {
+			log.Error("can not sign id token", zap.String("client ID", opt.ClientID.String()), zap.String("owner ID", opt.OwnerID.String()), zap.Error(err))
+			return nil, oauth2.ErrServerError
+		}
+	}
+
 	return response, nil
 }
 
+//newIDToken returns a signed ID Token for the owner of the access token response
+func newIDToken(opt *oauth2.AccessTokenOptions, response *oauth2.AccessTokenResponse) (string, error) {
+	key := GetSigningKey()
+	if key == nil {
+		return "", ErrSigningKeyNotFound
+	}
+	claims := oauth2.NewIDTokenClaims(opt, response, config.IAM.Tokens.GetIDTokenDuration())
+	return key.Sign(claims)
+}
+
 //RequestAuthorizationCode returns authorization code response or error
 func RequestAuthorizationCode(context *UserCtx, authorizationRequest *oauth2.AuthorizationRequest) (response *oauth2.AuthorizationCodeResponse, err error) {
 	rep, err := getUserRepository(context.RepositoryName)
@@ -330,7 +417,10 @@ func RequestAuthorizationCode(context *UserCtx, authorizationRequest *oauth2.Aut
 	if !ok {
 		return nil, oauth2.ErrUnauthorizedClient
 	}
-	code := oauth2.NewAuthorizationCode(user.ID, authorizationRequest)
+	code, err := oauth2.NewAuthorizationCode(user.ID, authorizationRequest)
+	if err != nil {
+		return nil, err
+	}
 	err = tokenManager.setAuthorizationCode(code)
 	if err != nil {
 		return nil, err
@@ -387,6 +477,12 @@ func validateClientAllowsImplicitRequest(cli *Client, request *oauth2.Authorizat
 
 func validateClientAllowsClientCredentialsRequest(cli *Client, request *oauth2.ClientCredentialsAccessTokenRequest) (ok bool) {
 	ok = true
+	//client credentials grant must only be used by confidential clients
+	if cli.IsPublic() {
+		log.Debug("public client can not use client credentials grant", zap.String("client ID", cli.ID.String()))
+		ok = false
+		return
+	}
 	if !cli.HasGrantType(request.GrantType) {
 		log.Debug("grant type not found for client", zap.String("client ID", cli.ID.String()), zap.String("requested", request.GetGrantType().String()))
 		ok = false
@@ -484,6 +580,22 @@ func ValidateAuthorizationRequest(authorizationRequest *oauth2.AuthorizationRequ
 		return oauth2.ErrUnauthorizedClientInfo
 	}
 
+	//Verification of the RFC7636 code challenge
+	if len(authorizationRequest.CodeChallenge) > 0 {
+		if strings.Compare(responseType, "code") != 0 {
+			log.Error("code challenge is only allowed for authorization code requests", zap.String("client ID", clientID.String()), zap.Error(oauth2.ErrInvalidRequestInfo))
+			return oauth2.ErrInvalidRequestInfo
+		}
+		_, err := oauth2.NewCodeChallengeMethod(authorizationRequest.CodeChallengeMethod)
+		if err != nil {
+			log.Error("code challenge method not supported", zap.String("client ID", clientID.String()), zap.String("method", authorizationRequest.CodeChallengeMethod), zap.Error(oauth2.ErrInvalidRequestInfo))
+			return oauth2.ErrInvalidRequestInfo
+		}
+	} else if client.RequiresPKCE() && strings.Compare(responseType, "code") == 0 {
+		log.Error("client requires a code challenge", zap.String("client ID", clientID.String()), zap.Error(oauth2.ErrInvalidRequestInfo))
+		return oauth2.ErrInvalidRequestInfo
+	}
+
 	//3. State warning
 	//Verification of state otherwise warn the console
 	if len(authorizationRequest.State) == 0 {
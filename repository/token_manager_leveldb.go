@@ -124,5 +124,9 @@ func (r *TokenManagerLeveldb) validateAuthorizationCode(request *oauth2.Authoriz
 	if err != nil {
 		log.Error("can not removed consumed code", zap.Error(err))
 	}
+	if !rCode.ValidateAccessTokenRequest(request) {
+		log.Debug("access token request invalid", zap.String("client ID", request.ClientID))
+		return nil, false
+	}
 	return &rCode, true
 }
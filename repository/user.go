@@ -2,9 +2,12 @@ package repository
 
 import (
 	"bounzr/iam/oauth2"
+	"bounzr/iam/password"
 	"bounzr/iam/scim2"
+	"crypto/subtle"
 	"github.com/gofrs/uuid"
 	"go.uber.org/zap"
+	"sort"
 	"strings"
 	"time"
 )
@@ -17,7 +20,8 @@ type User struct {
 	AuthorizationRequestsConsentTokens map[uuid.UUID]*ConsentToken
 	ID                                 uuid.UUID
 	Metadata                           *ResourceTag
-	Password                           string
+	Mfa                                *UserMfa //second factors of the browser login, nil if not enrolled
+	Password                           string   //encoded password hash
 	RefreshTokens                      map[uuid.UUID]*oauth2.AccessTokenHint
 	RepositoryName                     string
 	UserName                           string
@@ -29,6 +33,8 @@ type UserAttributes struct {
 	DisplayName       string
 	Entitlements      []string
 	Emails            []scim2.MultiValueAttribute
+	EnterpriseUser    *scim2.EnterpriseUser //enterprise user schema extension, the manager only holds the value
+	Extensions        scim2.ExtensionValues //custom schema extensions declared in the configuration
 	ExternalId        string
 	Ims               []scim2.MultiValueAttribute
 	Locale            string
@@ -52,7 +58,7 @@ type UserCtx struct {
 }
 
 //NewUser creates new user
-func NewUser(username string, password string, repository string) (*User, error) {
+func NewUser(username string, pwd string, repository string) (*User, error) {
 	atm := make(map[uuid.UUID]*oauth2.AccessTokenHint)
 	arm := make(map[uuid.UUID]*oauth2.AuthorizationRequest)
 	arctm := make(map[uuid.UUID]*ConsentToken)
@@ -72,11 +78,18 @@ func NewUser(username string, password string, repository string) (*User, error)
 		Name:           username,
 		RepositoryName: repository,
 		ResourceType:   "User",
+		Version:        1,
 	}
 
 	var attributes *UserAttributes
 	attributes = &UserAttributes{}
 
+	hash, err := password.Hash(pwd)
+	if err != nil {
+		log.Error("can not hash password for new user", zap.String("username", username), zap.Error(err))
+		return nil, err
+	}
+
 	user := &User{
 		AccessTokens:                       atm,
 		Attributes:                         attributes,
@@ -84,7 +97,7 @@ func NewUser(username string, password string, repository string) (*User, error)
 		AuthorizationRequestsConsentTokens: arctm,
 		ID:                                 id,
 		Metadata:                           metadata,
-		Password:                           password,
+		Password:                           hash,
 		RefreshTokens:                      rtm,
 		RepositoryName:                     repository,
 		UserName:                           username,
@@ -93,6 +106,30 @@ func NewUser(username string, password string, repository string) (*User, error)
 	return user, nil
 }
 
+//validatePassword returns true if the password matches the stored hash. changed is true if the stored hash was
+//replaced because it was plaintext or the hash algorithm parameters changed, the user must be saved again
+func (u *User) validatePassword(pwd string) (ok bool, changed bool) {
+	var rehash bool
+	if password.IsHashed(u.Password) {
+		ok, rehash = password.Verify(pwd, u.Password)
+	} else {
+		//plaintext password stored before passwords were hashed
+		ok = len(u.Password) > 0 && subtle.ConstantTimeCompare([]byte(u.Password), []byte(pwd)) == 1
+		rehash = ok
+	}
+	if !ok || !rehash {
+		return ok, false
+	}
+	hash, err := password.Hash(pwd)
+	if err != nil {
+		log.Error("can not rehash password", zap.String("username", u.UserName), zap.Error(err))
+		return ok, false
+	}
+	u.Password = hash
+	log.Debug("password rehashed", zap.String("username", u.UserName))
+	return ok, true
+}
+
 func (u *User) DeleteClientAccessToken(clientID uuid.UUID) {
 	delete(u.AccessTokens, clientID)
 }
@@ -124,10 +161,31 @@ func (u *User) GetClientRefreshToken(clientID uuid.UUID) (tokenReference *oauth2
 }
 
 func (u *User) GetScim() *scim2.User {
+	user := u.newScimUser(true)
+	user.Groups = u.getScimGroups()
+	return user
+}
+
+//getScimValues returns the attribute values of the SCIM representation of the user to evaluate the filter and the
+//sortBy attribute of the page. The groups and the manager are only looked up if the page uses them
+func (u *User) getScimValues(page *scim2.ListPage) map[string]interface{} {
+	user := u.newScimUser(page.IsAttributeRequired(scim2.EnterpriseUserSchema))
+	if page.IsAttributeRequired("groups") {
+		user.Groups = u.getScimGroups()
+	}
+	return user.GetValues()
+}
+
+//getScimGroups returns the groups the user is member of
+func (u *User) getScimGroups() []scim2.GroupAssignment {
 	memberGroupFilter := make(map[string]interface{})
 	memberGroupFilter["member"] = u.ID
-	groups := FindGroupAssignments(memberGroupFilter)
+	return FindGroupAssignments(memberGroupFilter)
+}
 
+//newScimUser returns the SCIM representation of the user without groups. The reference and display name of the manager
+//are only set if resolveManager is true
+func (u *User) newScimUser(resolveManager bool) *scim2.User {
 	user := &scim2.User{
 		Active:            u.Attributes.Active,
 		Addresses:         u.Attributes.Addresses,
@@ -135,7 +193,6 @@ func (u *User) GetScim() *scim2.User {
 		Entitlements:      u.Attributes.Entitlements,
 		Emails:            u.Attributes.Emails,
 		ExternalId:        u.Attributes.ExternalId,
-		Groups:            groups,
 		ID:                u.Metadata.ID.String(),
 		Ims:               u.Attributes.Ims,
 		Locale:            u.Attributes.Locale,
@@ -147,16 +204,108 @@ func (u *User) GetScim() *scim2.User {
 		PreferredLanguage: u.Attributes.PreferredLanguage,
 		ProfileURL:        u.Attributes.ProfileURL,
 		Roles:             u.Attributes.Roles,
-		Schemas:           []string{"urn:ietf:params:scim:schemas:core:2.0:User"},
+		Schemas:           []string{scim2.UserSchema},
 		Timezone:          u.Attributes.Timezone,
 		Title:             u.Attributes.Title,
 		UserName:          u.Metadata.Name,
 		UserType:          u.Attributes.UserType,
 		X509Certificates:  u.Attributes.X509Certificates,
 	}
+	if u.Attributes.EnterpriseUser != nil {
+		if resolveManager {
+			user.EnterpriseUser = getScimEnterpriseUser(u.Attributes.EnterpriseUser)
+		} else {
+			user.EnterpriseUser = u.Attributes.EnterpriseUser
+		}
+		user.Schemas = append(user.Schemas, scim2.EnterpriseUserSchema)
+	}
+	if len(u.Attributes.Extensions) > 0 {
+		user.Extensions = make(scim2.ExtensionValues)
+		schemas := make([]string, 0, len(u.Attributes.Extensions))
+		for schema, values := range u.Attributes.Extensions {
+			user.Extensions[schema] = values
+			schemas = append(schemas, schema)
+		}
+		sort.Strings(schemas)
+		user.Schemas = append(user.Schemas, schemas...)
+	}
 	return user
 }
 
+//getScimEnterpriseUser returns a copy of the enterprise extension with the reference and display name of the manager
+func getScimEnterpriseUser(enterpriseUser *scim2.EnterpriseUser) *scim2.EnterpriseUser {
+	extension := *enterpriseUser
+	if enterpriseUser.Manager == nil {
+		return &extension
+	}
+	extension.Manager = &scim2.Manager{
+		Value: enterpriseUser.Manager.Value,
+	}
+	if manager, found := GetUser(uuid.FromStringOrNil(enterpriseUser.Manager.Value)); found {
+		extension.Manager.Ref = manager.Metadata.GetScimMetadata().Location
+		extension.Manager.DisplayName = manager.Attributes.DisplayName
+		if len(extension.Manager.DisplayName) == 0 {
+			extension.Manager.DisplayName = manager.UserName
+		}
+	}
+	return &extension
+}
+
+//GetUserInfo returns the OpenID Connect claims of the user that are covered by the granted scope
+func (u *User) GetUserInfo(scope string) *oauth2.UserInfo {
+	info := &oauth2.UserInfo{
+		Subject: u.ID.String(),
+	}
+	attributes := u.Attributes
+	if attributes == nil {
+		attributes = &UserAttributes{}
+	}
+	if oauth2.HasScope(scope, oauth2.ProfileScope) {
+		info.Name = attributes.DisplayName
+		if attributes.Name != nil {
+			if len(info.Name) == 0 {
+				info.Name = attributes.Name.Formatted
+			}
+			info.GivenName = attributes.Name.GivenName
+			info.FamilyName = attributes.Name.FamilyName
+			info.MiddleName = attributes.Name.MiddleName
+		}
+		info.Nickname = attributes.NickName
+		info.PreferredUsername = u.UserName
+		info.Profile = attributes.ProfileURL
+		info.Picture = getPrimaryValue(attributes.Photos)
+		info.Zoneinfo = attributes.Timezone
+		info.Locale = attributes.Locale
+		if u.Metadata != nil {
+			info.UpdatedAt = u.Metadata.LastModified.Unix()
+		}
+	}
+	if oauth2.HasScope(scope, oauth2.EmailScope) {
+		info.Email = getPrimaryValue(attributes.Emails)
+	}
+	if oauth2.HasScope(scope, oauth2.PhoneScope) {
+		info.PhoneNumber = getPrimaryValue(attributes.PhoneNumbers)
+	}
+	if oauth2.HasScope(scope, oauth2.AddressScope) && len(attributes.Addresses) > 0 {
+		address := attributes.Addresses[0]
+		for _, a := range attributes.Addresses {
+			if a.Primary {
+				address = a
+				break
+			}
+		}
+		info.Address = &oauth2.UserInfoAddress{
+			Formatted:     address.Formatted,
+			StreetAddress: address.StreetAddress,
+			Locality:      address.Locality,
+			Region:        address.Region,
+			PostalCode:    address.PostalCode,
+			Country:       address.Country,
+		}
+	}
+	return info
+}
+
 func (u *User) GetUserCtx() *UserCtx {
 	return &UserCtx{
 		RepositoryName: u.RepositoryName,
@@ -213,6 +362,14 @@ func (u *UserCtx) GetRepositoryName() string {
 	return u.RepositoryName
 }
 
+//getUniqueValueKeys returns the keys of the user in the unique value index of the user managers
+func (u *User) getUniqueValueKeys() []string {
+	if u.Attributes == nil {
+		return nil
+	}
+	return getUniqueValueKeys(&scim2.User{Extensions: u.Attributes.Extensions})
+}
+
 func (u *User) setScim(scim *scim2.User) {
 	if scim != nil {
 		u.Attributes = &UserAttributes{
@@ -221,6 +378,8 @@ func (u *User) setScim(scim *scim2.User) {
 			DisplayName:       scim.DisplayName,
 			Entitlements:      scim.Entitlements,
 			Emails:            scim.Emails,
+			EnterpriseUser:    getStoredEnterpriseUser(scim.EnterpriseUser),
+			Extensions:        scim.Extensions,
 			ExternalId:        scim.ExternalId,
 			Ims:               scim.Ims,
 			Locale:            scim.Locale,
@@ -238,3 +397,31 @@ func (u *User) setScim(scim *scim2.User) {
 		}
 	}
 }
+
+//getStoredEnterpriseUser returns the enterprise extension without the read only attributes of the manager
+func getStoredEnterpriseUser(enterpriseUser *scim2.EnterpriseUser) *scim2.EnterpriseUser {
+	if enterpriseUser == nil {
+		return nil
+	}
+	extension := *enterpriseUser
+	if enterpriseUser.Manager != nil {
+		extension.Manager = nil
+		if len(enterpriseUser.Manager.Value) > 0 {
+			extension.Manager = &scim2.Manager{Value: enterpriseUser.Manager.Value}
+		}
+	}
+	return &extension
+}
+
+//getPrimaryValue returns the primary value of a multi valued attribute or the first one if none is primary
+func getPrimaryValue(values []scim2.MultiValueAttribute) string {
+	for _, v := range values {
+		if v.Primary {
+			return v.Value
+		}
+	}
+	if len(values) > 0 {
+		return values[0].Value
+	}
+	return ""
+}
@@ -2,18 +2,23 @@ package repository
 
 import (
 	"bounzr/iam/oauth2"
+	"bounzr/iam/password"
 	"bounzr/iam/scim2"
 	"github.com/gofrs/uuid"
 	"go.uber.org/zap"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 )
 
 //UserManager contains user and profile information
 type UserManager interface {
 	close()
 	deleteUser(userID interface{})
-	findUsers() ([]User, error)
+	findUsers(query *scim2.ListQuery) ([]User, int, error)
 	getRepositoryName() string
+	getUniqueValueUser(key string) (uuid.UUID, bool)
 	getUser(userID interface{}) (*User, bool)
 	init()
 	setRepositoryName(name string)
@@ -21,7 +26,12 @@ type UserManager interface {
 	validateUser(username string, password string) error
 }
 
-var userRepositories map[string]UserManager
+var (
+	userRepositories map[string]UserManager
+	//usersMutex serializes the SCIM writes of the users, so that the preconditions are validated against the version
+	//being replaced
+	usersMutex sync.Mutex
+)
 
 func initUsers() {
 	userRepositories = make(map[string]UserManager)
@@ -31,6 +41,7 @@ func initUsers() {
 	name := "main"
 	userRepo := NewUserManager(name)
 	addUserRepository(userRepo, name)
+	migratePasswords(userRepo)
 }
 
 func addUserRepository(ur UserManager, id string) error {
@@ -59,6 +70,8 @@ func NewUserManager(name string) UserManager {
 }
 
 func AddScimUser(scimUser *scim2.User) (uuid.UUID, error) {
+	usersMutex.Lock()
+	defer usersMutex.Unlock()
 	repository := "main" //todo config repository
 	users, err := getUserRepository(repository)
 	if err != nil {
@@ -66,11 +79,25 @@ func AddScimUser(scimUser *scim2.User) (uuid.UUID, error) {
 		return uuid.Nil, err
 	}
 	username := strings.ToLower(scimUser.UserName)
+	if _, taken := users.getUser(username); taken {
+		log.Debug("username is already in use", zap.String("username", username))
+		return uuid.Nil, ErrUsernameNotAvailable
+	}
 	password := scimUser.Password
 	if len(password) == 0 {
 		log.Error("password is empty", zap.String("username", scimUser.UserName), zap.Error(scim2.ErrBadRequestInvalidValue))
 		return uuid.Nil, scim2.ErrBadRequestInvalidValue
 	}
+	if err = validateEnterpriseManager(uuid.Nil, scimUser); err != nil {
+		return uuid.Nil, err
+	}
+	if err = scimUser.ValidateExtensions(nil); err != nil {
+		log.Debug("invalid schema extensions", zap.String("username", scimUser.UserName), zap.Error(err))
+		return uuid.Nil, err
+	}
+	if err = validateUniqueExtensions(uuid.Nil, scimUser); err != nil {
+		return uuid.Nil, err
+	}
 	user, err := NewUser(username, password, repository)
 	if err != nil {
 		log.Error("can not get user", zap.String("username", scimUser.UserName), zap.Error(err))
@@ -100,30 +127,95 @@ func AddAdminUser(repository string, username string, password string) error {
 	return nil
 }
 
-func DeleteUser(userID uuid.UUID) {
-	for _, store := range userRepositories {
-		_, ok := store.getUser(userID)
-		if ok {
-			store.deleteUser(userID)
-			return
-		}
+//DeleteUser deletes the user and its sessions if the preconditions hold for the current version of the user
+func DeleteUser(userID uuid.UUID, preconditions *scim2.Preconditions) error {
+	usersMutex.Lock()
+	defer usersMutex.Unlock()
+	user, err := getVersionedUser(userID, preconditions)
+	if err != nil {
+		return err
+	}
+	rep, err := getUserRepository(user.RepositoryName)
+	if err != nil {
+		return err
 	}
+	rep.deleteUser(userID)
+	deleteResourceGroups(user.Metadata)
+	DeleteUserSessions(userID)
+	return nil
 }
 
-//todo find users(filter, attributes)
-func FindUsers() []scim2.User {
+//FindUsers returns the SCIM representation of the page of users requested by the query and the total number of users
+//matching it. A nil query returns all users. The repositories are paged one after the other, sorted by name
+func FindUsers(query *scim2.ListQuery) ([]scim2.User, int, error) {
+	if query == nil {
+		query = &scim2.ListQuery{StartIndex: 1, Count: -1}
+	}
+	if username, ok := query.Filter.GetEqualValue("userName"); ok {
+		return findUsersByName(strings.ToLower(username), query)
+	}
+	names := make([]string, 0, len(userRepositories))
+	for name := range userRepositories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
 	var users []scim2.User
-	for repName, repo := range userRepositories {
-		repUsers, err := repo.findUsers()
+	total := 0
+	skip := query.StartIndex - 1
+	remaining := query.Count
+	for _, repName := range names {
+		repQuery := *query
+		repQuery.StartIndex = skip + 1
+		repQuery.Count = remaining
+		repUsers, repTotal, err := userRepositories[repName].findUsers(&repQuery)
 		if err != nil {
 			log.Error("can not add users from repository", zap.String("repository", repName), zap.Error(err))
-			continue
+			return nil, 0, err
+		}
+		total += repTotal
+		if skip -= repTotal; skip < 0 {
+			skip = 0
+		}
+		if remaining >= 0 {
+			remaining -= len(repUsers)
 		}
 		for _, user := range repUsers {
 			users = append(users, *user.GetScim())
 		}
 	}
-	return users
+	return users, total, nil
+}
+
+//findUsersByName returns the users of the "userName eq" filters from the username index of the repositories instead of
+//evaluating the filter on every user. Usernames are stored in lowercase like the case insensitive userName comparisons
+func findUsersByName(username string, query *scim2.ListQuery) ([]scim2.User, int, error) {
+	names := make([]string, 0, len(userRepositories))
+	for name := range userRepositories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pageQuery := *query
+	pageQuery.Filter = nil
+	page := scim2.NewListPage(&pageQuery)
+	found := make([]*User, 0, 1)
+	for _, repName := range names {
+		user, ok := userRepositories[repName].getUser(username)
+		if !ok {
+			continue
+		}
+		if page.IsResourceRequired() {
+			page.AddValues(len(found), user.getScimValues(page))
+		} else {
+			page.AddValues(len(found), nil)
+		}
+		found = append(found, user)
+	}
+	keys, total := page.Keys()
+	users := make([]scim2.User, len(keys))
+	for idx, key := range keys {
+		users[idx] = *found[key.(int)].GetScim()
+	}
+	return users, total, nil
 }
 
 //GetAuthorizationRequest returns an authorization request from a user for a client by using the corresponding consent token
@@ -147,6 +239,43 @@ func GetAuthorizationRequest(user *UserCtx, consentToken *ConsentToken) (authori
 	return nil, ErrSessionNotFound
 }
 
+//GetUserInfo returns the OpenID Connect claims of the owner of the given access token
+func GetUserInfo(token *oauth2.TokenUnit) (*oauth2.UserInfo, error) {
+	if !token.HasScope(oauth2.OpenIDScope) {
+		log.Debug("access token was not granted the openid scope", zap.String("client ID", token.ClientID.String()))
+		return nil, oauth2.ErrInvalidScope
+	}
+	user, found := GetUser(token.GetResourceOwner())
+	if !found {
+		log.Debug("access token owner is not an user", zap.String("owner ID", token.GetResourceOwner().String()))
+		return nil, ErrUsernameNotFound
+	}
+	return user.GetUserInfo(token.GetScope()), nil
+}
+
+//migratePasswords replaces the plaintext passwords stored in the repository by their hash
+func migratePasswords(users UserManager) {
+	repUsers, _, err := users.findUsers(nil)
+	if err != nil {
+		log.Error("can not migrate passwords", zap.String("repository", users.getRepositoryName()), zap.Error(err))
+		return
+	}
+	for i := range repUsers {
+		user := &repUsers[i]
+		if len(user.Password) == 0 || password.IsHashed(user.Password) {
+			continue
+		}
+		hash, err := password.Hash(user.Password)
+		if err != nil {
+			log.Error("can not hash password", zap.String("username", user.UserName), zap.Error(err))
+			continue
+		}
+		user.Password = hash
+		users.setUser(user)
+		log.Info("plaintext password migrated", zap.String("username", user.UserName))
+	}
+}
+
 //getUserRepository returns the repository with the given name
 func getUserRepository(repName string) (UserManager, error) {
 	repo, ok := userRepositories[repName]
@@ -172,17 +301,86 @@ func GetUser(userID interface{}) (user *User, found bool) {
 	return nil, false
 }
 
-func ReplaceUserByScim(userID uuid.UUID, scimUser *scim2.User) error {
+//PatchUser applies the SCIM patch operations to the user if the preconditions hold for its current version
+func PatchUser(userID uuid.UUID, patch *scim2.PatchOp, preconditions *scim2.Preconditions) error {
+	usersMutex.Lock()
+	defer usersMutex.Unlock()
+	user, err := getVersionedUser(userID, preconditions)
+	if err != nil {
+		return err
+	}
+	scimUser := user.GetScim()
+	err = patch.Apply(scimUser)
+	if err != nil {
+		log.Debug("can not patch user", zap.String("user id", userID.String()), zap.Error(err))
+		return err
+	}
+	return replaceUserByScim(user, scimUser)
+}
+
+//ReplaceUserByScim replaces the user attributes if the preconditions hold for its current version. The username and
+//the password are changed if they are given
+func ReplaceUserByScim(userID uuid.UUID, scimUser *scim2.User, preconditions *scim2.Preconditions) error {
+	usersMutex.Lock()
+	defer usersMutex.Unlock()
+	user, err := getVersionedUser(userID, preconditions)
+	if err != nil {
+		return err
+	}
+	return replaceUserByScim(user, scimUser)
+}
+
+//getVersionedUser returns the user to be modified if the preconditions hold for its current version. The caller must
+//hold usersMutex until the user is written
+func getVersionedUser(userID uuid.UUID, preconditions *scim2.Preconditions) (*User, error) {
 	user, found := GetUser(userID)
 	if !found {
-		log.Debug("can not get user", zap.String("username", scimUser.UserName))
-		return ErrUsernameNotFound
+		log.Debug("can not get user", zap.String("user id", userID.String()))
+		return nil, ErrUsernameNotFound
 	}
-	user.setScim(scimUser)
+	if err := preconditions.Validate(scim2.GetETag(user.Metadata.Version)); err != nil {
+		log.Debug("user precondition failed", zap.String("user id", userID.String()), zap.Uint64("version", user.Metadata.Version))
+		return nil, err
+	}
+	return user, nil
+}
+
+//replaceUserByScim replaces the attributes of the user. The caller must hold usersMutex
+func replaceUserByScim(user *User, scimUser *scim2.User) error {
 	rep, err := getUserRepository(user.RepositoryName)
 	if err != nil {
 		return err
 	}
+	if err = validateEnterpriseManager(user.ID, scimUser); err != nil {
+		return err
+	}
+	if err = scimUser.ValidateExtensions(user.GetScim()); err != nil {
+		log.Debug("invalid schema extensions", zap.String("username", user.UserName), zap.Error(err))
+		return err
+	}
+	if err = validateUniqueExtensions(user.ID, scimUser); err != nil {
+		return err
+	}
+	username := strings.ToLower(scimUser.UserName)
+	if len(username) > 0 && username != user.UserName {
+		if _, taken := rep.getUser(username); taken {
+			log.Debug("username is already in use", zap.String("username", username))
+			return ErrUsernameNotAvailable
+		}
+		rep.deleteUser(user.UserName)
+		user.UserName = username
+		user.Metadata.Name = username
+	}
+	if len(scimUser.Password) > 0 {
+		hash, err := password.Hash(scimUser.Password)
+		if err != nil {
+			log.Error("can not hash password", zap.String("username", user.UserName), zap.Error(err))
+			return err
+		}
+		user.Password = hash
+	}
+	user.setScim(scimUser)
+	user.Metadata.setModified(time.Now())
 	rep.setUser(user)
 	SetResourceGroups(scimUser, user.Metadata)
 	return nil
@@ -220,3 +418,46 @@ func ValidateUser(username string, password string) (userCtx *UserCtx, valid boo
 	}
 	return user.GetUserCtx(), true
 }
+
+//validateEnterpriseManager verifies the manager of the enterprise extension is an existing user other than the user
+func validateEnterpriseManager(userID uuid.UUID, scimUser *scim2.User) error {
+	if scimUser.EnterpriseUser == nil || scimUser.EnterpriseUser.Manager == nil || len(scimUser.EnterpriseUser.Manager.Value) == 0 {
+		return nil
+	}
+	managerID, err := uuid.FromString(scimUser.EnterpriseUser.Manager.Value)
+	if err != nil || managerID == userID {
+		log.Debug("invalid manager", zap.String("username", scimUser.UserName), zap.String("manager", scimUser.EnterpriseUser.Manager.Value))
+		return scim2.ErrBadRequestInvalidValue
+	}
+	if _, found := GetUser(managerID); !found {
+		log.Debug("manager not found", zap.String("username", scimUser.UserName), zap.String("manager", managerID.String()))
+		return scim2.ErrBadRequestInvalidValue
+	}
+	return nil
+}
+
+//validateUniqueExtensions verifies no other user has the values of the extension attributes with server uniqueness.
+//The caller must hold usersMutex until the user is written, as the unique values are indexed when the user is set
+func validateUniqueExtensions(userID uuid.UUID, scimUser *scim2.User) error {
+	for _, key := range getUniqueValueKeys(scimUser) {
+		for _, store := range userRepositories {
+			if owner, found := store.getUniqueValueUser(key); found && owner != userID {
+				log.Debug("extension attribute value is already in use", zap.String("attribute", strings.Fields(key)[0]))
+				return scim2.ErrBadRequestUniqueness
+			}
+		}
+	}
+	return nil
+}
+
+//getUniqueValueKeys returns the keys of the unique value index for the values of the extension attributes with
+//server uniqueness, the attribute path followed by the JSON encoded value
+func getUniqueValueKeys(scimUser *scim2.User) []string {
+	keys := make([]string, 0)
+	for path, values := range scimUser.GetUniqueExtensionValues() {
+		for _, value := range values {
+			keys = append(keys, path+" "+value)
+		}
+	}
+	return keys
+}
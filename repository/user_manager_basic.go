@@ -1,22 +1,28 @@
 package repository
 
 import (
+	"bounzr/iam/scim2"
 	"github.com/gofrs/uuid"
 	"go.uber.org/zap"
+	"sort"
 	"strings"
 )
 
 //UserManagerBasic In memory repository. Used for tests
 type UserManagerBasic struct {
-	name  string
-	users map[string]*User
-	uids  map[uuid.UUID]string
+	name         string
+	users        map[string]*User
+	uids         map[uuid.UUID]string
+	uniqueValues map[string]uuid.UUID   //index of the unique extension values
+	uniqueKeys   map[uuid.UUID][]string //keys of each user in the unique value index
 }
 
 //init the repository
 func (r *UserManagerBasic) init() {
 	r.users = make(map[string]*User)
 	r.uids = make(map[uuid.UUID]string)
+	r.uniqueValues = make(map[string]uuid.UUID)
+	r.uniqueKeys = make(map[uuid.UUID][]string)
 }
 
 func (r *UserManagerBasic) close() {
@@ -28,6 +34,12 @@ func (r *UserManagerBasic) setUser(user *User) {
 	//add the new user to the repo
 	r.users[user.UserName] = user
 	r.uids[user.ID] = user.UserName
+	r.deleteUniqueValues(user.ID)
+	keys := user.getUniqueValueKeys()
+	for _, key := range keys {
+		r.uniqueValues[key] = user.ID
+	}
+	r.uniqueKeys[user.ID] = keys
 	return
 }
 
@@ -35,45 +47,63 @@ func (r *UserManagerBasic) setUser(user *User) {
 func (r *UserManagerBasic) validateUser(username string, password string) error {
 	//all usernames handled in lowercase
 	username = strings.ToLower(username)
-	user := r.users[username]
-	stdpwd := user.Password
-	if strings.Compare(stdpwd, password) == 0 {
-		return nil
+	user, ok := r.users[username]
+	if !ok {
+		return ErrInvalidLogin
 	}
-	return ErrInvalidLogin
-
-	//TODO hash storage of password
-	/*
-		hash := s.Store[username]
-		if len(hash) == 0{
-			return ErrInvalidLogin
-		}
-		err := bcrypt.CompareHashAndPassword(hash, []byte(password))
-		if err != nil{
-			return ErrInvalidLogin
-		}
-		return nil
-	*/
-	//TODO still not hashing the password in memory.
+	if ok, _ = user.validatePassword(password); !ok {
+		return ErrInvalidLogin
+	}
+	return nil
 }
 
 func (r *UserManagerBasic) deleteUser(userID interface{}) {
 	username := r.getUsername(userID)
+	if user, ok := r.users[username]; ok {
+		r.deleteUniqueValues(user.ID)
+	}
 	delete(r.users, username)
 }
 
+//deleteUniqueValues removes the values of the user from the unique value index
+func (r *UserManagerBasic) deleteUniqueValues(userID uuid.UUID) {
+	for _, key := range r.uniqueKeys[userID] {
+		delete(r.uniqueValues, key)
+	}
+	delete(r.uniqueKeys, userID)
+}
+
 func (r *UserManagerBasic) getRepositoryName() string {
 	return r.name
 }
 
-func (r *UserManagerBasic) findUsers() ([]User, error) {
-	users := make([]User, len(r.users))
-	idx := 0
-	for _, user := range r.users {
-		users[idx] = *user
-		idx++
+//findUsers returns the page of users requested by the query, ordered by username if it is not sorted, and the total number of users matching it
+func (r *UserManagerBasic) findUsers(query *scim2.ListQuery) ([]User, int, error) {
+	usernames := make([]string, 0, len(r.users))
+	for username := range r.users {
+		usernames = append(usernames, username)
+	}
+	sort.Strings(usernames)
+	page := scim2.NewListPage(query)
+	for _, username := range usernames {
+		if page.IsResourceRequired() {
+			page.AddValues(username, r.users[username].getScimValues(page))
+		} else {
+			page.AddValues(username, nil)
+		}
 	}
-	return users, nil
+	keys, total := page.Keys()
+	users := make([]User, len(keys))
+	for idx, key := range keys {
+		users[idx] = *r.users[key.(string)]
+	}
+	return users, total, nil
+}
+
+//getUniqueValueUser returns the user with the unique extension value of the key
+func (r *UserManagerBasic) getUniqueValueUser(key string) (uuid.UUID, bool) {
+	userID, ok := r.uniqueValues[key]
+	return userID, ok
 }
 
 //getUser get user by username
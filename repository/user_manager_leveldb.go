@@ -1,21 +1,23 @@
 package repository
 
 import (
+	"bounzr/iam/scim2"
 	"bytes"
 	"encoding/gob"
 	"github.com/gofrs/uuid"
 	"github.com/syndtr/goleveldb/leveldb"
 	"go.uber.org/zap"
-	"strings"
 )
 
 type UserManagerLeveldb struct {
-	cfgDB    *leveldb.DB
-	cfgPath  string
-	nameDB   *leveldb.DB
-	namePath string
-	uuidDB   *leveldb.DB
-	uuidPath string
+	cfgDB      *leveldb.DB
+	cfgPath    string
+	nameDB     *leveldb.DB
+	namePath   string
+	uniqueDB   *leveldb.DB //index of the unique extension values
+	uniquePath string
+	uuidDB     *leveldb.DB
+	uuidPath   string
 }
 
 func (r *UserManagerLeveldb) init() {
@@ -31,12 +33,18 @@ func (r *UserManagerLeveldb) init() {
 		r.uuidPath = "./rep/user/uuid"
 	}
 	r.openUUIDDB()
+	if len(r.uniquePath) == 0 {
+		r.uniquePath = "./rep/user/unique"
+	}
+	r.openUniqueDB()
+	r.indexUniqueValues()
 }
 
 func (r *UserManagerLeveldb) close() {
 	defer r.cfgDB.Close()
 	defer r.nameDB.Close()
 	defer r.uuidDB.Close()
+	defer r.uniqueDB.Close()
 }
 
 func (r *UserManagerLeveldb) validateUser(username string, password string) error {
@@ -44,10 +52,14 @@ func (r *UserManagerLeveldb) validateUser(username string, password string) erro
 	if !ok {
 		return ErrInvalidLogin
 	}
-	if strings.Compare(password, user.Password) == 0 {
-		return nil
+	ok, changed := user.validatePassword(password)
+	if !ok {
+		return ErrInvalidLogin
 	}
-	return ErrInvalidLogin
+	if changed {
+		r.setUser(user)
+	}
+	return nil
 }
 
 func (r *UserManagerLeveldb) deleteUser(userID interface{}) {
@@ -55,6 +67,9 @@ func (r *UserManagerLeveldb) deleteUser(userID interface{}) {
 	if len(username) == 0 {
 		return
 	}
+	if user, ok := r.getUser(username); ok {
+		r.deleteUniqueValues(user)
+	}
 	err := r.nameDB.Delete([]byte(username), nil)
 	if err != nil {
 		log.Error("can not delete user", zap.String("username", username), zap.Error(err))
@@ -72,27 +87,49 @@ func (r *UserManagerLeveldb) getRepositoryName() string {
 	return string(nameByte)
 }
 
-//todo findUsers(search parameters)
-func (r *UserManagerLeveldb) findUsers() ([]User, error) {
-	var users []User
+//findUsers returns the page of users requested by the query, ordered by username if it is not sorted, and the total number of users matching it.
+//Users are only decoded if the query has a filter or a sortBy attribute, or if they are in the requested page
+func (r *UserManagerLeveldb) findUsers(query *scim2.ListQuery) ([]User, int, error) {
+	page := scim2.NewListPage(query)
 	iter := r.nameDB.NewIterator(nil, nil)
 	for iter.Next() {
-		dataBytes := iter.Value()
-		data := bytes.NewBuffer(dataBytes)
+		username := string(iter.Key())
+		if !page.IsResourceRequired() {
+			page.AddValues(username, nil)
+			continue
+		}
+		data := bytes.NewBuffer(iter.Value())
 		dec := gob.NewDecoder(data)
 		var user User
 		err := dec.Decode(&user)
 		if err != nil {
-			log.Error("can not decode user", zap.ByteString("id", iter.Key()), zap.Error(err))
+			log.Error("can not decode user", zap.String("username", username), zap.Error(err))
+			continue
 		}
-		log.Debug("user decoded", zap.ByteString("id", iter.Key()))
-		users = append(users, user)
+		page.AddValues(username, user.getScimValues(page))
 	}
 	iter.Release()
 	if err := iter.Error(); err != nil {
-		return nil, err
+		return nil, 0, err
+	}
+	keys, total := page.Keys()
+	users := make([]User, 0, len(keys))
+	for _, key := range keys {
+		user, ok := r.getUser(key.(string))
+		if ok {
+			users = append(users, *user)
+		}
+	}
+	return users, total, nil
+}
+
+//getUniqueValueUser returns the user with the unique extension value of the key
+func (r *UserManagerLeveldb) getUniqueValueUser(key string) (uuid.UUID, bool) {
+	dataBytes, err := r.uniqueDB.Get([]byte(key), nil)
+	if err != nil {
+		return uuid.Nil, false
 	}
-	return users, nil
+	return uuid.FromBytesOrNil(dataBytes), true
 }
 
 func (r *UserManagerLeveldb) getUser(userID interface{}) (*User, bool) {
@@ -150,6 +187,14 @@ func (r *UserManagerLeveldb) openNameDB() {
 	}
 }
 
+func (r *UserManagerLeveldb) openUniqueDB() {
+	var err error
+	r.uniqueDB, err = leveldb.OpenFile(r.uniquePath, nil)
+	if err != nil {
+		log.Error("can not open user unique value repository", zap.Error(err))
+	}
+}
+
 func (r *UserManagerLeveldb) openUUIDDB() {
 	var err error
 	r.uuidDB, err = leveldb.OpenFile(r.uuidPath, nil)
@@ -168,6 +213,16 @@ func (r *UserManagerLeveldb) setRepositoryName(name string) {
 }
 
 func (r *UserManagerLeveldb) setUser(user *User) {
+	if username, err := r.uuidDB.Get(user.ID.Bytes(), nil); err == nil {
+		if stored, ok := r.getUser(string(username)); ok {
+			r.deleteUniqueValues(stored)
+		}
+	}
+	for _, key := range user.getUniqueValueKeys() {
+		if err := r.uniqueDB.Put([]byte(key), user.ID.Bytes(), nil); err != nil {
+			log.Error("can not index unique value", zap.String("username", user.UserName), zap.Error(err))
+		}
+	}
 	var data bytes.Buffer
 	enc := gob.NewEncoder(&data)
 	err := enc.Encode(user)
@@ -186,3 +241,37 @@ func (r *UserManagerLeveldb) setUser(user *User) {
 		}
 	}
 }
+
+//deleteUniqueValues removes the values of the stored user from the unique value index
+func (r *UserManagerLeveldb) deleteUniqueValues(user *User) {
+	for _, key := range user.getUniqueValueKeys() {
+		if owner, found := r.getUniqueValueUser(key); found && owner == user.ID {
+			r.uniqueDB.Delete([]byte(key), nil)
+		}
+	}
+}
+
+//indexUniqueValues rebuilds the unique value index, as the attributes with server uniqueness are declared in the
+//configuration
+func (r *UserManagerLeveldb) indexUniqueValues() {
+	iter := r.uniqueDB.NewIterator(nil, nil)
+	for iter.Next() {
+		r.uniqueDB.Delete(iter.Key(), nil)
+	}
+	iter.Release()
+	users := r.nameDB.NewIterator(nil, nil)
+	defer users.Release()
+	for users.Next() {
+		var user User
+		if err := gob.NewDecoder(bytes.NewBuffer(users.Value())).Decode(&user); err != nil {
+			log.Error("can not decode user", zap.String("username", string(users.Key())), zap.Error(err))
+			continue
+		}
+		for _, key := range user.getUniqueValueKeys() {
+			r.uniqueDB.Put([]byte(key), user.ID.Bytes(), nil)
+		}
+	}
+	if err := users.Error(); err != nil {
+		log.Error("can not index unique values", zap.Error(err))
+	}
+}
@@ -1,10 +1,18 @@
 package repository
 
 import (
+	"bounzr/iam/config"
+	"bounzr/iam/otp"
+	"bounzr/iam/password"
+	"bounzr/iam/scim2"
+	"encoding/gob"
+	"encoding/json"
 	"github.com/gofrs/uuid"
 	"go.uber.org/zap"
 	"os"
+	"strings"
 	"testing"
+	"time"
 )
 
 type UserDataProvider struct {
@@ -16,21 +24,32 @@ type UserDataProvider struct {
 
 var (
 	basicUMTest      = &UserManagerBasic{name: "basic"}
-	leveldbUMTest    = &UserManagerLeveldb{cfgPath: "../test/user_cfg", namePath: "../test/user"}
+	leveldbUMTest    = &UserManagerLeveldb{cfgPath: "../test/user_cfg", namePath: "../test/user", uuidPath: "../test/user_uuid", uniquePath: "../test/user_unique"}
 	userDataProvider = []UserDataProvider{
 		{basicUMTest, "testusername", "testuserpwd", uuid.FromStringOrNil("2490c31d-3005-47b4-9bc0-45952a2e505e")},
 		{leveldbUMTest, "otherusername", "otheruserpwd", uuid.FromStringOrNil("68d0dffb-3dbf-4086-965f-33dd5d012995")},
 	}
 )
 
+//executeUserTest runs the test with the main user repository of each provider and an in memory group manager. The
+//repositories are restored afterwards
 func executeUserTest(test func(provider UserDataProvider)) {
 	os.RemoveAll("../test/")
 	log, _ = zap.NewDevelopment()
+	defer func(users map[string]UserManager, groups GroupManager) {
+		userRepositories = users
+		groupManager = groups
+	}(userRepositories, groupManager)
 	for _, provider := range userDataProvider {
+		userRepositories = map[string]UserManager{"main": provider.manager}
+		groupManager = &GroupManagerBasic{}
+		groupManager.init()
 		user := &User{
-			UserName: provider.username,
-			ID:       provider.id,
-			Password: provider.password,
+			UserName:   provider.username,
+			ID:         provider.id,
+			Password:   provider.password,
+			Attributes: &UserAttributes{},
+			Metadata:   &ResourceTag{ID: provider.id, Name: provider.username},
 		}
 		provider.manager.init()
 		provider.manager.setUser(user)
@@ -79,3 +98,285 @@ func TestDeleteUser(t *testing.T) {
 	}
 	executeUserTest(test)
 }
+
+func TestMigratePassword(t *testing.T) {
+	test := func(provider UserDataProvider) {
+		//users are stored with plaintext passwords by executeUserTest
+		migratePasswords(provider.manager)
+		user, _ := provider.manager.getUser(provider.username)
+		if user.Password == provider.password || !password.IsHashed(user.Password) {
+			t.Errorf("want hashed password got %s", user.Password)
+		}
+		err := provider.manager.validateUser(provider.username, provider.password)
+		if err != nil {
+			t.Errorf("want no error, got %s", err.Error())
+		}
+	}
+	executeUserTest(test)
+}
+
+func TestFindUsers(t *testing.T) {
+	test := func(provider UserDataProvider) {
+		for _, username := range []string{"carol", "alice", "bob"} {
+			id, _ := uuid.NewV4()
+			provider.manager.setUser(&User{UserName: username, ID: id, Attributes: &UserAttributes{}, Metadata: &ResourceTag{ID: id, Name: username}})
+		}
+		users, total, err := provider.manager.findUsers(&scim2.ListQuery{StartIndex: 2, Count: 2})
+		if err != nil {
+			t.Fatalf("want no error, got %s", err.Error())
+		}
+		if total != 4 || len(users) != 2 || users[0].UserName != "bob" || users[1].UserName != "carol" {
+			t.Errorf("want bob and carol of 4 users, got %d users of %d", len(users), total)
+		}
+		filter, _ := scim2.ParseFilter(`userName sw "b" or userName sw "c"`)
+		query := &scim2.ListQuery{Filter: filter, SortBy: "userName", SortOrder: scim2.DescendingSortOrder, StartIndex: 1, Count: 1}
+		users, total, err = provider.manager.findUsers(query)
+		if err != nil {
+			t.Fatalf("want no error, got %s", err.Error())
+		}
+		if total != 2 || len(users) != 1 || users[0].UserName != "carol" {
+			t.Errorf("want carol of 2 users, got %d users of %d", len(users), total)
+		}
+		users, total, _ = provider.manager.findUsers(nil)
+		if total != 4 || len(users) != 4 {
+			t.Errorf("want 4 users, got %d users of %d", len(users), total)
+		}
+		filter, _ = scim2.ParseFilter(`userName eq "Alice"`)
+		scimUsers, total, err := FindUsers(&scim2.ListQuery{Filter: filter, StartIndex: 1, Count: -1})
+		if err != nil || total != 1 || len(scimUsers) != 1 || scimUsers[0].UserName != "alice" {
+			t.Errorf("want alice, got %d users of %d, error %v", len(scimUsers), total, err)
+		}
+		filter, _ = scim2.ParseFilter(`userName eq "dave"`)
+		if scimUsers, total, _ = FindUsers(&scim2.ListQuery{Filter: filter, StartIndex: 1, Count: -1}); total != 0 || len(scimUsers) != 0 {
+			t.Errorf("want no users, got %d users of %d", len(scimUsers), total)
+		}
+	}
+	executeUserTest(test)
+}
+
+func TestEnterpriseUser(t *testing.T) {
+	test := func(provider UserDataProvider) {
+		scimUser := &scim2.User{
+			UserName: "bjensen",
+			Password: "t1meMa$heen",
+			EnterpriseUser: &scim2.EnterpriseUser{
+				EmployeeNumber: "701984",
+				Department:     "Sales",
+				Manager:        &scim2.Manager{Value: provider.id.String(), DisplayName: "ignored"},
+			},
+		}
+		id, err := AddScimUser(scimUser)
+		if err != nil {
+			t.Fatalf("want no error, got %s", err.Error())
+		}
+		user, _ := GetUser(id)
+		extension := user.GetScim().EnterpriseUser
+		if extension == nil || extension.EmployeeNumber != "701984" || extension.Manager == nil {
+			t.Fatalf("want enterprise user extension with manager, got %+v", extension)
+		}
+		if extension.Manager.DisplayName != provider.username || len(extension.Manager.Ref) == 0 {
+			t.Errorf("want manager %s with reference, got %+v", provider.username, extension.Manager)
+		}
+		//the manager must be an existing user other than the user
+		scimUser.UserName = "jsmith"
+		scimUser.EnterpriseUser.Manager.Value = "26118915-6090-4610-87e4-49d8ca9f808d"
+		if _, err = AddScimUser(scimUser); err != scim2.ErrBadRequestInvalidValue {
+			t.Errorf("unknown manager: want error %v, got %v", scim2.ErrBadRequestInvalidValue, err)
+		}
+		scimUser.UserName = "bjensen"
+		scimUser.EnterpriseUser.Manager.Value = id.String()
+		if err = ReplaceUserByScim(id, scimUser, nil); err != scim2.ErrBadRequestInvalidValue {
+			t.Errorf("self manager: want error %v, got %v", scim2.ErrBadRequestInvalidValue, err)
+		}
+		patch := &scim2.PatchOp{
+			Schemas: []string{scim2.PatchOpSchema},
+			Operations: []scim2.PatchOperation{
+				{Op: "replace", Path: scim2.EnterpriseUserSchema + ":department", Value: "Marketing"},
+			},
+		}
+		if err = PatchUser(id, patch, nil); err != nil {
+			t.Fatalf("want no error, got %s", err.Error())
+		}
+		filter, _ := scim2.ParseFilter(`urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:department eq "marketing"`)
+		users, total, _ := provider.manager.findUsers(&scim2.ListQuery{Filter: filter, StartIndex: 1, Count: -1})
+		if total != 1 || users[0].ID != id {
+			t.Errorf("want bjensen in marketing, got %d users", total)
+		}
+		if manager := users[0].Attributes.EnterpriseUser.Manager; manager.Value != provider.id.String() || len(manager.DisplayName) > 0 {
+			t.Errorf("want manager %s, got %+v", provider.id, users[0].Attributes.EnterpriseUser.Manager)
+		}
+	}
+	executeUserTest(test)
+}
+
+func TestUserExtensions(t *testing.T) {
+	defer func(extensions []config.ScimExtension) { config.IAM.Scim.Extensions = extensions }(config.IAM.Scim.Extensions)
+	config.IAM.Scim.Extensions = []config.ScimExtension{
+		{
+			Schema: "urn:example:params:scim:schemas:extension:acme:2.0:User",
+			Attributes: []config.ScimAttribute{
+				{Name: "badgeId", Required: true, Uniqueness: "server"},
+				{Name: "contractor", Type: "boolean"},
+				{Name: "costCodes", MultiValued: true},
+			},
+		},
+	}
+	gob.Register(map[string]interface{}{})
+	gob.Register([]interface{}{})
+	test := func(provider UserDataProvider) {
+		data := `{"userName":"bjensen","password":"t1meMa$heen","urn:example:params:scim:schemas:extension:acme:2.0:User":{"badgeId":"B-1","contractor":true,"costCodes":["CC-100"]}}`
+		scimUser := &scim2.User{}
+		json.Unmarshal([]byte(data), scimUser)
+		id, err := AddScimUser(scimUser)
+		if err != nil {
+			t.Fatalf("want no error, got %s", err.Error())
+		}
+		user, _ := GetUser(id)
+		resource := user.GetScim()
+		values := resource.Extensions["urn:example:params:scim:schemas:extension:acme:2.0:User"]
+		if values["badgeId"] != "B-1" || values["contractor"] != true || len(resource.Schemas) != 2 {
+			t.Errorf("want stored extension values, got %v with schemas %v", values, resource.Schemas)
+		}
+		//badgeId is unique
+		scimUser = &scim2.User{}
+		json.Unmarshal([]byte(strings.Replace(data, "bjensen", "jsmith", 1)), scimUser)
+		if _, err = AddScimUser(scimUser); err != scim2.ErrBadRequestUniqueness {
+			t.Errorf("want error %v, got %v", scim2.ErrBadRequestUniqueness, err)
+		}
+		//string values are compared in lower case
+		json.Unmarshal([]byte(strings.Replace(strings.Replace(data, "bjensen", "jsmith", 1), "B-1", "b-1", 1)), scimUser)
+		if _, err = AddScimUser(scimUser); err != scim2.ErrBadRequestUniqueness {
+			t.Errorf("want error %v, got %v", scim2.ErrBadRequestUniqueness, err)
+		}
+		//the user can keep its own badgeId
+		resource.Password = ""
+		if err = ReplaceUserByScim(id, resource, nil); err != nil {
+			t.Errorf("want no error, got %s", err.Error())
+		}
+		//the previous badgeId is released when it is changed
+		resource.Extensions["urn:example:params:scim:schemas:extension:acme:2.0:User"]["badgeId"] = "B-2"
+		if err = ReplaceUserByScim(id, resource, nil); err != nil {
+			t.Errorf("want no error, got %s", err.Error())
+		}
+		if _, found := provider.manager.getUniqueValueUser(`urn:example:params:scim:schemas:extension:acme:2.0:User:badgeId "b-2"`); !found {
+			t.Errorf("want badgeId B-2 indexed")
+		}
+		json.Unmarshal([]byte(strings.Replace(data, "bjensen", "jsmith", 1)), scimUser)
+		otherID, err := AddScimUser(scimUser)
+		if err != nil {
+			t.Fatalf("want badgeId B-1 released, got %s", err.Error())
+		}
+		//the badgeId of a deleted user is released
+		provider.manager.deleteUser(otherID)
+		if _, found := provider.manager.getUniqueValueUser(`urn:example:params:scim:schemas:extension:acme:2.0:User:badgeId "b-1"`); found {
+			t.Errorf("want badgeId B-1 released")
+		}
+	}
+	executeUserTest(test)
+}
+
+func TestUserVersion(t *testing.T) {
+	test := func(provider UserDataProvider) {
+		id, err := AddScimUser(&scim2.User{UserName: "bjensen", Password: "t1meMa$heen"})
+		if err != nil {
+			t.Fatalf("want no error, got %s", err.Error())
+		}
+		user, _ := GetUser(id)
+		if version := user.GetScim().Metadata.Version; version != `W/"1"` {
+			t.Errorf("want version %s, got %s", `W/"1"`, version)
+		}
+		if err = ReplaceUserByScim(id, &scim2.User{UserName: "bjensen", NickName: "Babs"}, nil); err != nil {
+			t.Fatalf("want no error, got %s", err.Error())
+		}
+		patch := &scim2.PatchOp{Schemas: []string{scim2.PatchOpSchema}, Operations: []scim2.PatchOperation{{Op: "replace", Path: "nickName", Value: "Barbara"}}}
+		if err = PatchUser(id, patch, &scim2.Preconditions{IfMatch: `W/"2"`}); err != nil {
+			t.Fatalf("want no error, got %s", err.Error())
+		}
+		user, _ = GetUser(id)
+		if version := user.GetScim().Metadata.Version; version != `W/"3"` {
+			t.Errorf("want version %s, got %s", `W/"3"`, version)
+		}
+		//writes of a stale version are rejected
+		stale := &scim2.Preconditions{IfMatch: `W/"2"`}
+		if err = PatchUser(id, patch, stale); err != scim2.ErrPreconditionFailed {
+			t.Errorf("want error %v, got %v", scim2.ErrPreconditionFailed, err)
+		}
+		if err = ReplaceUserByScim(id, &scim2.User{UserName: "bjensen"}, stale); err != scim2.ErrPreconditionFailed {
+			t.Errorf("want error %v, got %v", scim2.ErrPreconditionFailed, err)
+		}
+		if err = DeleteUser(id, stale); err != scim2.ErrPreconditionFailed {
+			t.Errorf("want error %v, got %v", scim2.ErrPreconditionFailed, err)
+		}
+		if user, _ = GetUser(id); user.Attributes.NickName != "Barbara" {
+			t.Errorf("want nickName %s kept, got %s", "Barbara", user.Attributes.NickName)
+		}
+	}
+	executeUserTest(test)
+}
+
+func TestAddScimUserTaken(t *testing.T) {
+	test := func(provider UserDataProvider) {
+		_, err := AddScimUser(&scim2.User{UserName: provider.username, Password: "t1meMa$heen"})
+		if err != ErrUsernameNotAvailable {
+			t.Errorf("want error %v, got %v", ErrUsernameNotAvailable, err)
+		}
+		if user, _ := provider.manager.getUser(provider.username); user.ID != provider.id {
+			t.Errorf("want user %v not replaced, got %v", provider.id, user.ID)
+		}
+	}
+	executeUserTest(test)
+}
+
+func TestTotpEnrollment(t *testing.T) {
+	test := func(provider UserDataProvider) {
+		user, _ := provider.manager.getUser(provider.username)
+		user.RepositoryName = "main"
+		provider.manager.setUser(user)
+		enrollment, err := GetTotpEnrollment(provider.id)
+		if err != nil {
+			t.Fatalf("want no error, got %s", err.Error())
+		}
+		if again, _ := GetTotpEnrollment(provider.id); again.Secret != enrollment.Secret {
+			t.Errorf("want pending secret %s, got %s", enrollment.Secret, again.Secret)
+		}
+		if _, err = VerifyTotpEnrollment(provider.id, "000000x"); err != ErrMfaInvalidCode {
+			t.Errorf("want error %v, got %v", ErrMfaInvalidCode, err)
+		}
+		code, _ := otp.GenerateCode(enrollment.Secret, otp.GetCounter(time.Now()))
+		codes, err := VerifyTotpEnrollment(provider.id, code)
+		if err != nil {
+			t.Fatalf("want no error, got %s", err.Error())
+		}
+		if len(codes) != recoveryCodesCount {
+			t.Errorf("want %d recovery codes, got %d", recoveryCodesCount, len(codes))
+		}
+		user, _ = GetUser(provider.id)
+		if !user.HasTotp() || user.Mfa.FailedAttempts != 0 || len(user.Mfa.PendingTotpSecret) > 0 {
+			t.Errorf("want verified authenticator, got %+v", user.Mfa)
+		}
+		//the code of the enrollment can not be used again
+		if _, err = ValidateSecondFactor(provider.id, code); err != ErrMfaInvalidCode {
+			t.Errorf("want error %v, got %v", ErrMfaInvalidCode, err)
+		}
+		next, _ := otp.GenerateCode(enrollment.Secret, otp.GetCounter(time.Now())+1)
+		if method, err := ValidateSecondFactor(provider.id, next); err != nil || method != OtpAuthentication {
+			t.Errorf("want method %s, got %s %v", OtpAuthentication, method, err)
+		}
+		//recovery codes are case insensitive and can be used once
+		recovery := strings.ToLower(codes[0])
+		if method, err := ValidateSecondFactor(provider.id, recovery); err != nil || method != RecoveryCodeAuthentication {
+			t.Errorf("want method %s, got %s %v", RecoveryCodeAuthentication, method, err)
+		}
+		if _, err = ValidateSecondFactor(provider.id, recovery); err != ErrMfaInvalidCode {
+			t.Errorf("want error %v, got %v", ErrMfaInvalidCode, err)
+		}
+		//the codes are rejected after too many invalid codes
+		for i := 1; i < mfaMaxFailedAttempts; i++ {
+			ValidateSecondFactor(provider.id, "000000")
+		}
+		if _, err = ValidateSecondFactor(provider.id, codes[1]); err != ErrMfaLocked {
+			t.Errorf("want error %v, got %v", ErrMfaLocked, err)
+		}
+	}
+	executeUserTest(test)
+}
@@ -0,0 +1,213 @@
+package repository
+
+import (
+	"bounzr/iam/otp"
+	"bounzr/iam/token"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/hex"
+	"github.com/gofrs/uuid"
+	"go.uber.org/zap"
+	"strings"
+	"time"
+)
+
+const (
+	mfaLockDuration       = 5 * time.Minute //time the codes are rejected after too many invalid codes
+	mfaMaxFailedAttempts  = 5
+	recoveryCodesCount    = 10
+	recoveryCodeSize      = 10 //characters of the recovery codes, 50 bits
+	totpIssuer            = "Bounzr"
+	recoveryCodeSeparator = "-"
+)
+
+//UserMfa holds the second factors of the browser login of the user
+type UserMfa struct {
+	FailedAttempts    int //invalid codes since the last valid code
+	LastFailure       time.Time
+	PendingTotpSecret string   //secret of the authenticator waiting for the verification step
+	RecoveryCodes     []string //SHA-256 hashes of the unused recovery codes
+	TotpCounter       uint64   //time step of the last accepted code, a code can not be used twice
+	TotpSecret        string   //secret of the verified authenticator, empty if not enrolled
+}
+
+//TotpEnrollment is the authenticator of an user waiting for the verification step
+type TotpEnrollment struct {
+	ProvisioningURI string //otpauth URI shown as QR code
+	Secret          string //base32 secret for the manual entry in the authenticator app
+}
+
+//HasTotp returns true if the user has a verified authenticator
+func (u *User) HasTotp() bool {
+	return u.Mfa != nil && len(u.Mfa.TotpSecret) > 0
+}
+
+//IsMfaRequired returns true if a group of the user requires a second factor. The second factor is required if the
+//groups can not be read
+func IsMfaRequired(userID uuid.UUID) bool {
+	conditions := map[string]interface{}{"member": userID}
+	groups, _, err := groupManager.findGroups(conditions, nil)
+	if err != nil {
+		log.Error("can not get groups from repository, second factor required", zap.String("user id", userID.String()), zap.Error(err))
+		return true
+	}
+	for _, group := range groups {
+		if group.MfaRequired {
+			return true
+		}
+	}
+	return false
+}
+
+//RequiresSecondFactor returns true if the user has an authenticator or a group of the user requires a second factor.
+//The password alone does not authenticate these users
+func RequiresSecondFactor(userID uuid.UUID) bool {
+	if user, found := GetUser(userID); found && user.HasTotp() {
+		return true
+	}
+	return IsMfaRequired(userID)
+}
+
+//GetTotpEnrollment returns the authenticator of the user waiting for the verification step, a new secret is generated
+//if there is none. The current authenticator is used until the new one is verified with VerifyTotpEnrollment
+func GetTotpEnrollment(userID uuid.UUID) (*TotpEnrollment, error) {
+	user, rep, err := getMfaUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	secret := user.Mfa.PendingTotpSecret
+	if len(secret) == 0 {
+		secret = otp.NewSecret()
+		user.Mfa.PendingTotpSecret = secret
+		rep.setUser(user)
+		log.Debug("authenticator enrollment started", zap.String("user id", userID.String()))
+	}
+	return &TotpEnrollment{
+		ProvisioningURI: otp.GetProvisioningURI(totpIssuer, user.UserName, secret),
+		Secret:          secret,
+	}, nil
+}
+
+//VerifyTotpEnrollment enables the pending authenticator of the user if the code is valid and returns new recovery
+//codes. The previous authenticator and recovery codes are replaced
+func VerifyTotpEnrollment(userID uuid.UUID, code string) ([]string, error) {
+	user, rep, err := getMfaUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	mfa := user.Mfa
+	if len(mfa.PendingTotpSecret) == 0 {
+		return nil, ErrMfaNotEnrolled
+	}
+	now := time.Now()
+	if mfa.isLocked(now) {
+		return nil, ErrMfaLocked
+	}
+	counter, ok := otp.Validate(mfa.PendingTotpSecret, code, now, 0)
+	if !ok {
+		mfa.addFailure(now)
+		rep.setUser(user)
+		log.Debug("invalid authenticator enrollment code", zap.String("user id", userID.String()))
+		return nil, ErrMfaInvalidCode
+	}
+	codes, hashes := newRecoveryCodes()
+	user.Mfa = &UserMfa{
+		RecoveryCodes: hashes,
+		TotpCounter:   counter,
+		TotpSecret:    mfa.PendingTotpSecret,
+	}
+	rep.setUser(user)
+	log.Info("authenticator enrolled", zap.String("user id", userID.String()))
+	return codes, nil
+}
+
+//ValidateSecondFactor validates an authenticator code or a recovery code of the user and returns the authentication
+//method of the code. Recovery codes can be used once
+func ValidateSecondFactor(userID uuid.UUID, code string) (string, error) {
+	user, rep, err := getMfaUser(userID)
+	if err != nil {
+		return "", err
+	}
+	if !user.HasTotp() {
+		return "", ErrMfaNotEnrolled
+	}
+	mfa := user.Mfa
+	now := time.Now()
+	if mfa.isLocked(now) {
+		log.Debug("second factor locked", zap.String("user id", userID.String()))
+		return "", ErrMfaLocked
+	}
+	if counter, ok := otp.Validate(mfa.TotpSecret, code, now, mfa.TotpCounter); ok {
+		mfa.TotpCounter = counter
+		mfa.FailedAttempts = 0
+		rep.setUser(user)
+		return OtpAuthentication, nil
+	}
+	if mfa.useRecoveryCode(code) {
+		mfa.FailedAttempts = 0
+		rep.setUser(user)
+		log.Info("recovery code used", zap.String("user id", userID.String()), zap.Int("remaining", len(mfa.RecoveryCodes)))
+		return RecoveryCodeAuthentication, nil
+	}
+	mfa.addFailure(now)
+	rep.setUser(user)
+	log.Debug("invalid second factor code", zap.String("user id", userID.String()), zap.Int("attempts", mfa.FailedAttempts))
+	return "", ErrMfaInvalidCode
+}
+
+//getMfaUser returns the user with its second factors and its repository
+func getMfaUser(userID uuid.UUID) (*User, UserManager, error) {
+	user, found := GetUser(userID)
+	if !found {
+		log.Debug("can not get user", zap.String("user id", userID.String()))
+		return nil, nil, ErrUsernameNotFound
+	}
+	rep, err := getUserRepository(user.RepositoryName)
+	if err != nil {
+		return nil, nil, err
+	}
+	if user.Mfa == nil {
+		user.Mfa = &UserMfa{}
+	}
+	return user, rep, nil
+}
+
+//newRecoveryCodes returns new recovery codes and their hashes
+func newRecoveryCodes() (codes []string, hashes []string) {
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+	for i := 0; i < recoveryCodesCount; i++ {
+		code := encoding.EncodeToString(token.GetRandomBytes(recoveryCodeSize))[:recoveryCodeSize]
+		codes = append(codes, code[:recoveryCodeSize/2]+recoveryCodeSeparator+code[recoveryCodeSize/2:])
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+	return codes, hashes
+}
+
+//hashRecoveryCode returns the hash of the recovery code without separators and case
+func hashRecoveryCode(code string) string {
+	code = strings.ToUpper(strings.Replace(strings.TrimSpace(code), recoveryCodeSeparator, "", -1))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+func (m *UserMfa) addFailure(now time.Time) {
+	m.FailedAttempts++
+	m.LastFailure = now
+}
+
+func (m *UserMfa) isLocked(now time.Time) bool {
+	return m.FailedAttempts >= mfaMaxFailedAttempts && now.Before(m.LastFailure.Add(mfaLockDuration))
+}
+
+//useRecoveryCode removes the recovery code and returns true if it is an unused recovery code
+func (m *UserMfa) useRecoveryCode(code string) bool {
+	hash := hashRecoveryCode(code)
+	for i, recoveryCode := range m.RecoveryCodes {
+		if subtle.ConstantTimeCompare([]byte(recoveryCode), []byte(hash)) == 1 {
+			m.RecoveryCodes = append(m.RecoveryCodes[:i], m.RecoveryCodes[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
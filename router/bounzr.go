@@ -11,9 +11,33 @@ import (
 //newBounzrRouter returns a new router with Bounzr basic endpoints
 func newBounzrRouter(router *mux.Router) {
 	router.HandleFunc("/", chain(indexPageGetHandler, sessionCookieSecurity)).Methods(http.MethodGet)
+	router.HandleFunc("/authenticator", authenticatorPageHandler).Methods(http.MethodGet, http.MethodPost)
+	router.HandleFunc("/groups/{id:[-a-zA-Z0-9]+}/mfa", chain(
+		groupMfaHandler,
+		basicUserAuthSecurity,
+		verifyUserGroups("Admins"))).Methods(
+		http.MethodGet,
+		http.MethodPut,
+	)
 	router.HandleFunc("/login", loginPageHandler).Methods(http.MethodGet, http.MethodPost)
+	router.HandleFunc("/mfa", mfaPageHandler).Methods(http.MethodGet, http.MethodPost)
 	router.HandleFunc("/logout", logoutPageGetHandler).Methods(http.MethodGet)
+	router.HandleFunc("/logout/all", logoutAllPagePostHandler).Methods(http.MethodPost)
 	router.HandleFunc("/register", registerPageHandler).Methods(http.MethodGet, http.MethodPost)
+	router.HandleFunc("/sessions", chain(
+		sessionsHandler,
+		basicUserAuthSecurity,
+		verifyUserGroups("Admins"))).Methods(
+		http.MethodDelete,
+		http.MethodGet,
+	)
+	router.HandleFunc("/sessions/{id:[-a-zA-Z0-9]+}", chain(
+		sessionHandler,
+		basicUserAuthSecurity,
+		verifyUserGroups("Admins"))).Methods(
+		http.MethodDelete,
+		http.MethodGet,
+	)
 }
 
 func indexPageGetHandler(w http.ResponseWriter, r *http.Request) {
@@ -84,10 +108,18 @@ func loginPagePostHandler(w http.ResponseWriter, r *http.Request) {
 		pages.RenderPage(w, "login", repository.ErrInvalidLogin.Error())
 		return
 	}
-	sessionToken := repository.NewSessionToken(user)
-	session, _ := BounzrCookieStore.Get(r, SessionCookie)
-	session.Values[UserSessionToken] = sessionToken
-	session.Save(r, w)
+	//the session is created after the second factor is validated
+	if hasTotp(user) {
+		addPendingLoginToSession(w, r, user)
+		http.Redirect(w, r, "/bounzr/mfa", 302)
+		return
+	}
+	if repository.IsMfaRequired(user.GetUserID()) {
+		addPendingLoginToSession(w, r, user)
+		http.Redirect(w, r, "/bounzr/authenticator", 302)
+		return
+	}
+	newLoginSession(w, r, user, []string{repository.PasswordAuthentication})
 	landLoginRequest(w, r)
 }
 
@@ -101,6 +133,19 @@ func logoutPageGetHandler(w http.ResponseWriter, r *http.Request) {
 	landLoginRequest(w, r)
 }
 
+//logoutAllPagePostHandler signs the user out of all its sessions, in every browser and device. It only accepts POST
+//requests, which the lax or strict SameSite session cookie is not sent with from other sites
+func logoutAllPagePostHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := validateLoginSession(w, r)
+	if err == nil {
+		repository.DeleteUserSessions(user.GetUserID())
+	}
+	session, _ := BounzrCookieStore.Get(r, SessionCookie)
+	delete(session.Values, UserSessionToken)
+	session.Save(r, w)
+	landLoginRequest(w, r)
+}
+
 func registerPageHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method == http.MethodGet {
 		registerPageGetHandler(w, r)
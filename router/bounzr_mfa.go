@@ -0,0 +1,215 @@
+package router
+
+import (
+	"bounzr/iam/pages"
+	"bounzr/iam/repository"
+	"encoding/json"
+	"github.com/gofrs/uuid"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+	"net/http"
+	"time"
+)
+
+/**
+Two-factor authentication of the browser login
+
+Users with an authenticator, or members of a group requiring a second factor, are not signed in after the password
+validation. The user is kept in the cookie as pending login until the code of the authenticator or a recovery code
+is given at /bounzr/mfa. Users without authenticator enroll one at /bounzr/authenticator before the session is created.
+The basic authentication and the password grant only validate the password and are rejected for these users.
+
+Administration of the groups requiring a second factor
+
+    GET /bounzr/groups/{id}/mfa  returns {"required": true|false}
+    PUT /bounzr/groups/{id}/mfa  sets the requirement with the same body
+*/
+
+//time the password validation of a pending login is valid
+const mfaPendingDuration = 5 * time.Minute
+
+//groupMfaRequest is the json representation of the second factor requirement of a group
+type groupMfaRequest struct {
+	Required bool `json:"required"`
+}
+
+//addPendingLoginToSession keeps the user, validated by password, in the cookie until the second factor is validated
+func addPendingLoginToSession(w http.ResponseWriter, r *http.Request, user *repository.UserCtx) error {
+	session, err := BounzrCookieStore.Get(r, SessionCookie)
+	if err != nil && session == nil {
+		log.Error("session nil and can not retrieve session cookie", zap.Error(err))
+		return err
+	}
+	session.Values[MfaUserID] = user.GetUserID().String()
+	session.Values[MfaExpiresAt] = time.Now().Add(mfaPendingDuration).Unix()
+	return session.Save(r, w)
+}
+
+//getPendingLoginUser returns the user of the pending login of the cookie
+func getPendingLoginUser(r *http.Request) (*repository.UserCtx, error) {
+	session, err := BounzrCookieStore.Get(r, SessionCookie)
+	if err != nil {
+		log.Error("can not retrieve session cookie", zap.Error(err))
+		return nil, err
+	}
+	userID, ok := session.Values[MfaUserID].(string)
+	expiresAt, valid := session.Values[MfaExpiresAt].(int64)
+	if !ok || !valid || time.Now().Unix() > expiresAt {
+		log.Debug("pending login not found or expired")
+		return nil, repository.ErrSessionNotFound
+	}
+	user, found := repository.GetUser(uuid.FromStringOrNil(userID))
+	if !found {
+		log.Debug("user of the pending login not found", zap.String("user id", userID))
+		return nil, repository.ErrUsernameNotFound
+	}
+	return user.GetUserCtx(), nil
+}
+
+//hasTotp returns true if the user has a verified authenticator
+func hasTotp(user *repository.UserCtx) bool {
+	u, found := repository.GetUser(user.GetUserID())
+	return found && u.HasTotp()
+}
+
+//newLoginSession creates the session of the user with the authentication methods of the login and removes the
+//pending login from the cookie
+func newLoginSession(w http.ResponseWriter, r *http.Request, user *repository.UserCtx, authenticationMethods []string) {
+	sessionToken := repository.NewSession(user, getRemoteIP(r), r.UserAgent(), authenticationMethods)
+	session, _ := BounzrCookieStore.Get(r, SessionCookie)
+	session.Values[UserSessionToken] = sessionToken
+	delete(session.Values, MfaUserID)
+	delete(session.Values, MfaExpiresAt)
+	session.Save(r, w)
+}
+
+func mfaPageHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := getPendingLoginUser(r)
+	if err != nil {
+		http.Redirect(w, r, "/bounzr/login", 302)
+		return
+	}
+	if !hasTotp(user) {
+		//the authenticator of the pending login must be enrolled first
+		http.Redirect(w, r, "/bounzr/authenticator", 302)
+		return
+	}
+	if r.Method == http.MethodGet {
+		renderMfaPage(w, "")
+		return
+	}
+	r.ParseForm()
+	method, err := repository.ValidateSecondFactor(user.GetUserID(), r.PostForm.Get("code"))
+	if err != nil {
+		log.Debug("second factor not valid", zap.String("username", user.UserName), zap.Error(err))
+		renderMfaPage(w, err.Error())
+		return
+	}
+	authenticationMethods := []string{repository.PasswordAuthentication, method, repository.MultiFactorAuthentication}
+	newLoginSession(w, r, user, authenticationMethods)
+	landLoginRequest(w, r)
+}
+
+func renderMfaPage(w http.ResponseWriter, message string) {
+	err := pages.RenderPage(w, "mfa", message)
+	if err != nil {
+		log.Error("can not render mfa webpage", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+//authenticatorPageHandler enrolls an authenticator for the signed in user or for the pending login of an user that
+//must use a second factor. A signed in user that already has an authenticator must confirm the second factor first
+func authenticatorPageHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := getPendingLoginUser(r)
+	pending := err == nil
+	if pending && hasTotp(user) {
+		//the second factor of the pending login must be validated first
+		http.Redirect(w, r, "/bounzr/mfa", 302)
+		return
+	}
+	if !pending {
+		if user, err = validateLoginSession(w, r); err != nil {
+			addTargetURLToSession(w, r)
+			http.Redirect(w, r, "/bounzr/login", 302)
+			return
+		}
+	}
+	enrollment, err := repository.GetTotpEnrollment(user.GetUserID())
+	if err != nil {
+		log.Error("can not get authenticator enrollment", zap.String("username", user.UserName), zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	data := pages.NewMfaEnrollPage(enrollment.Secret, enrollment.ProvisioningURI)
+	data.CurrentCodeRequired = hasTotp(user)
+	if r.Method == http.MethodPost {
+		r.ParseForm()
+		if data.CurrentCodeRequired {
+			//a signed in session alone must not replace the authenticator of the user
+			if _, err := repository.ValidateSecondFactor(user.GetUserID(), r.PostForm.Get("currentCode")); err != nil {
+				log.Debug("current second factor not validated", zap.String("username", user.UserName), zap.Error(err))
+				data.Error = "invalid current authentication code"
+				renderMfaEnrollPage(w, data)
+				return
+			}
+		}
+		codes, err := repository.VerifyTotpEnrollment(user.GetUserID(), r.PostForm.Get("code"))
+		if err != nil {
+			log.Debug("authenticator enrollment not verified", zap.String("username", user.UserName), zap.Error(err))
+			data.Error = err.Error()
+		} else {
+			data.RecoveryCodes = codes
+			if pending {
+				authenticationMethods := []string{repository.PasswordAuthentication, repository.OtpAuthentication, repository.MultiFactorAuthentication}
+				newLoginSession(w, r, user, authenticationMethods)
+			}
+		}
+	}
+	renderMfaEnrollPage(w, data)
+}
+
+func renderMfaEnrollPage(w http.ResponseWriter, data *pages.MfaEnrollPage) {
+	err := pages.RenderPage(w, "mfa_enroll", data)
+	if err != nil {
+		log.Error("can not render mfa_enroll webpage", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func groupMfaHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	groupID, err := uuid.FromString(id)
+	if err != nil {
+		log.Debug("wrong group id", zap.String("id", id))
+		http.Error(w, repository.ErrGroupNotFound.Error(), http.StatusNotFound)
+		return
+	}
+	if r.Method == http.MethodPut {
+		request := &groupMfaRequest{}
+		if err = json.NewDecoder(r.Body).Decode(request); err != nil {
+			log.Debug("can not decode group mfa request", zap.Error(err))
+			http.Error(w, repository.ErrInvalidRequest.Error(), http.StatusBadRequest)
+			return
+		}
+		if err = repository.SetGroupMfaRequired(groupID, request.Required); err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+	}
+	group, err := repository.GetGroup(groupID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	response, err := json.Marshal(&groupMfaRequest{Required: group.MfaRequired})
+	if err != nil {
+		log.Error("can not marshal group mfa response", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(response)
+}
@@ -0,0 +1,135 @@
+package router
+
+import (
+	"bounzr/iam/repository"
+	"encoding/json"
+	"github.com/gofrs/uuid"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+	"net"
+	"net/http"
+	"time"
+)
+
+/**
+Session administration endpoints
+
+    GET    /bounzr/sessions?userId={id}  lists the sessions, of all the users if userId is not given
+    DELETE /bounzr/sessions?userId={id}  deletes the sessions of the user
+    GET    /bounzr/sessions/{id}         returns the session
+    DELETE /bounzr/sessions/{id}         deletes the session, the user is signed out
+*/
+
+//sessionResponse is the json representation of a login session. The session token is never returned
+type sessionResponse struct {
+	ID                    string    `json:"id"`
+	UserID                string    `json:"userId"`
+	UserName              string    `json:"userName"`
+	Created               time.Time `json:"created"`
+	LastSeen              time.Time `json:"lastSeen"`
+	IPAddress             string    `json:"ipAddress,omitempty"`
+	UserAgent             string    `json:"userAgent,omitempty"`
+	AuthenticationMethods []string  `json:"amr"`
+}
+
+func newSessionResponse(session *repository.Session) *sessionResponse {
+	return &sessionResponse{
+		ID:                    session.ID.String(),
+		UserID:                session.User.UserID.String(),
+		UserName:              session.User.UserName,
+		Created:               session.Created,
+		LastSeen:              session.LastSeen,
+		IPAddress:             session.IPAddress,
+		UserAgent:             session.UserAgent,
+		AuthenticationMethods: session.AuthenticationMethods,
+	}
+}
+
+//getRemoteIP returns the IP address of the client of the request
+func getRemoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func sessionsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		sessionsGet(w, r)
+	}
+	if r.Method == http.MethodDelete {
+		sessionsDelete(w, r)
+	}
+}
+
+func sessionsGet(w http.ResponseWriter, r *http.Request) {
+	var sessions []*repository.Session
+	if userID := r.URL.Query().Get("userId"); len(userID) > 0 {
+		uid, err := uuid.FromString(userID)
+		if err != nil {
+			log.Debug("wrong user id", zap.String("id", userID))
+			http.Error(w, repository.ErrInvalidRequest.Error(), http.StatusBadRequest)
+			return
+		}
+		sessions = repository.GetUserSessions(uid)
+	} else {
+		sessions = repository.GetSessions()
+	}
+	response := make([]*sessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		response = append(response, newSessionResponse(session))
+	}
+	writeSessionResponse(w, response)
+}
+
+func sessionsDelete(w http.ResponseWriter, r *http.Request) {
+	userID := r.URL.Query().Get("userId")
+	uid, err := uuid.FromString(userID)
+	if err != nil {
+		log.Debug("wrong user id", zap.String("id", userID))
+		http.Error(w, repository.ErrInvalidRequest.Error(), http.StatusBadRequest)
+		return
+	}
+	repository.DeleteUserSessions(uid)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func sessionHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	uid, err := uuid.FromString(id)
+	if err != nil {
+		log.Debug("wrong session id", zap.String("id", id))
+		http.Error(w, repository.ErrSessionNotFound.Error(), http.StatusNotFound)
+		return
+	}
+	if r.Method == http.MethodGet {
+		session, found := repository.GetSession(uid)
+		if !found {
+			http.Error(w, repository.ErrSessionNotFound.Error(), http.StatusNotFound)
+			return
+		}
+		writeSessionResponse(w, newSessionResponse(session))
+	}
+	if r.Method == http.MethodDelete {
+		if err = repository.DeleteSession(uid); err != nil {
+			log.Debug("can not delete session", zap.String("id", id), zap.Error(err))
+			http.Error(w, repository.ErrSessionNotFound.Error(), http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func writeSessionResponse(w http.ResponseWriter, response interface{}) {
+	sessionJSON, err := json.Marshal(response)
+	if err != nil {
+		log.Error("can not marshal sessions", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(sessionJSON)
+}
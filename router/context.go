@@ -17,6 +17,7 @@ type key int
 const (
 	userCtxKey   key = 0
 	clientCtxKey key = 1
+	tokenCtxKey  key = 2
 )
 
 func fromContextGetClient(ctx context.Context) (*oauth2.ClientCtx, bool) {
@@ -24,6 +25,11 @@ func fromContextGetClient(ctx context.Context) (*oauth2.ClientCtx, bool) {
 	return c, ok
 }
 
+func fromContextGetToken(ctx context.Context) (*oauth2.TokenUnit, bool) {
+	t, ok := ctx.Value(tokenCtxKey).(*oauth2.TokenUnit)
+	return t, ok
+}
+
 // FromContext returns the User value stored in ctx, if any.
 func fromContextGetUser(ctx context.Context) (*repository.UserCtx, bool) {
 	u, ok := ctx.Value(userCtxKey).(*repository.UserCtx)
@@ -34,6 +40,10 @@ func newContextWithClient(ctx context.Context, c *oauth2.ClientCtx) context.Cont
 	return context.WithValue(ctx, clientCtxKey, c)
 }
 
+func newContextWithToken(ctx context.Context, t *oauth2.TokenUnit) context.Context {
+	return context.WithValue(ctx, tokenCtxKey, t)
+}
+
 // NewContext returns a new Context that carries value u.
 func newContextWithUser(ctx context.Context, u *repository.UserCtx) context.Context {
 	return context.WithValue(ctx, userCtxKey, u)
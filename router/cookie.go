@@ -0,0 +1,76 @@
+package router
+
+import (
+	"bounzr/iam/config"
+	"bounzr/iam/repository"
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+	"go.uber.org/zap"
+	"net/http"
+	"sync"
+	"time"
+)
+
+//time the generated keys are used when the keys can not be read, before reading them again
+const cookieKeysRetryPeriod = time.Minute
+
+//cookieStore is the session store of the bounzr cookies. The keys are read from the configuration or the key store
+//and the underlying cookie store is replaced when the keys are rotated
+type cookieStore struct {
+	mutex     sync.Mutex
+	store     *sessions.CookieStore
+	refreshAt time.Time //the keys are read again from this date, zero if they do not change
+}
+
+//Get returns the session cookie with the given name, decoded once per request
+func (s *cookieStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(s, name)
+}
+
+func (s *cookieStore) New(r *http.Request, name string) (*sessions.Session, error) {
+	return s.getStore().New(r, name)
+}
+
+func (s *cookieStore) Save(r *http.Request, w http.ResponseWriter, session *sessions.Session) error {
+	return s.getStore().Save(r, w, session)
+}
+
+//getStore returns the cookie store of the current keys
+func (s *cookieStore) getStore() *sessions.CookieStore {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.store != nil && (s.refreshAt.IsZero() || time.Now().Before(s.refreshAt)) {
+		return s.store
+	}
+	keys, err := repository.GetCookieKeys()
+	if err != nil {
+		log.Error("can not get cookie keys", zap.Error(err))
+		s.refreshAt = time.Now().Add(cookieKeysRetryPeriod)
+		if s.store != nil {
+			return s.store
+		}
+		//cookies can not be read after a restart or by other nodes
+		keys = &repository.CookieKeys{
+			KeyPairs:  [][]byte{securecookie.GenerateRandomKey(64), securecookie.GenerateRandomKey(32)},
+			RefreshAt: s.refreshAt,
+		}
+	}
+	s.store = newCookieStore(keys.KeyPairs)
+	s.refreshAt = keys.RefreshAt
+	return s.store
+}
+
+//newCookieStore returns a cookie store with the key pairs and the cookie attributes of the configuration
+func newCookieStore(keyPairs [][]byte) *sessions.CookieStore {
+	store := sessions.NewCookieStore(keyPairs...)
+	store.Options = &sessions.Options{
+		Path:     "/",
+		Domain:   config.IAM.Cookies.Domain,
+		Secure:   config.IAM.Cookies.GetSecure(),
+		HttpOnly: config.IAM.Cookies.GetHttpOnly(),
+		SameSite: config.IAM.Cookies.GetSameSite(),
+	}
+	//sets the Max-Age attribute and the maximum age of the cookies accepted by the codecs
+	store.MaxAge(int(config.IAM.Cookies.GetMaxAge().Seconds()))
+	return store
+}
@@ -2,9 +2,11 @@ package router
 
 import (
 	"net/http"
+	"strings"
 
 	"go.uber.org/zap"
 
+	"bounzr/iam/oauth2"
 	"bounzr/iam/repository"
 )
 
@@ -28,15 +30,22 @@ Either pass client id and secret via Basic Authentication or add client_id and c
 When providing the client_id and client_secret in the Authorization header it is expected to be:
     client_id:client_secret
     Base64 encoded
+Public clients registered with the "none" token endpoint authentication method only pass the client_id field.
 */
 var basicClientAuthSecurity = func(f http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		clientID, clientSecret, ok := r.BasicAuth()
 		if !ok {
+			err := r.ParseForm()
+			if err != nil {
+				log.Debug("can not parse client authentication form", zap.Error(err))
+				http.Error(w, repository.ErrInvalidLogin.Error(), http.StatusBadRequest)
+				return
+			}
 			clientID = r.PostForm.Get("client_id")
 			clientSecret = r.PostForm.Get("client_secret")
-			if len(clientID) == 0 || len(clientSecret) == 0 {
-				log.Debug("client id or secret are empty", zap.String("client", clientID))
+			if len(clientID) == 0 {
+				log.Debug("client id is empty", zap.String("client", clientID))
 				http.Error(w, repository.ErrInvalidLogin.Error(), http.StatusUnauthorized)
 				return
 			}
@@ -52,6 +61,46 @@ var basicClientAuthSecurity = func(f http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
+/**
+Resource servers accept the access token in the Authorization request header field (RFC6750 2.1) or in the
+form-encoded body parameter access_token (RFC6750 2.2).
+    Authorization: Bearer mF_9.B5f-4.1JqM
+The token and, when owned by an user, the user are added to the context
+*/
+var bearerTokenSecurity = func(f http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		accessToken := ""
+		authorization := r.Header.Get("Authorization")
+		if strings.HasPrefix(authorization, "Bearer ") {
+			accessToken = strings.TrimSpace(strings.TrimPrefix(authorization, "Bearer "))
+		} else if r.Method == http.MethodPost {
+			accessToken = r.PostFormValue("access_token")
+		}
+		if len(accessToken) == 0 {
+			log.Debug("bearer token is empty")
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, repository.ErrInvalidLogin.Error(), http.StatusUnauthorized)
+			return
+		}
+		hint := &oauth2.AccessTokenHint{
+			Token: accessToken,
+			Hint:  oauth2.AccessTokenHintType.String(),
+		}
+		token, ok := repository.ValidateAccessToken(hint)
+		if !ok || token.TokenHintType != oauth2.AccessTokenHintType {
+			log.Debug("invalid bearer token authentication attempt")
+			w.Header().Set("WWW-Authenticate", "Bearer error=\"invalid_token\"")
+			http.Error(w, repository.ErrInvalidLogin.Error(), http.StatusUnauthorized)
+			return
+		}
+		ctx := newContextWithToken(r.Context(), token)
+		if user, found := repository.GetUser(token.GetResourceOwner()); found {
+			ctx = newContextWithUser(ctx, user.GetUserCtx())
+		}
+		f(w, r.WithContext(ctx))
+	}
+}
+
 //verifies basic authentication and adds the user to the context
 var basicUserAuthSecurity = func(f http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -67,6 +116,12 @@ var basicUserAuthSecurity = func(f http.HandlerFunc) http.HandlerFunc {
 			http.Error(w, repository.ErrInvalidLogin.Error(), http.StatusUnauthorized)
 			return
 		}
+		//users with a second factor sign in at the browser login and use bearer tokens
+		if repository.RequiresSecondFactor(user.GetUserID()) {
+			log.Debug("basic authentication of user requiring a second factor", zap.String("user", username))
+			http.Error(w, repository.ErrInvalidLogin.Error(), http.StatusUnauthorized)
+			return
+		}
 		ctx := newContextWithUser(r.Context(), user)
 		f(w, r.WithContext(ctx))
 	}
@@ -84,9 +139,30 @@ func verifyUserGroups(groups ...string) middleware {
 			}
 			if !validateIsUserInGroup(user, groups) {
 				log.Error("user does not belong to requested group", zap.String("user", user.UserName))
+				http.Error(w, repository.ErrInvalidLogin.Error(), http.StatusForbidden)
+				return
+			}
+			f(w, r)
+		}
+	}
+}
+
+//verifyTokenScope verifies that the bearer token of the context was granted the scope (RFC6750 3.1)
+func verifyTokenScope(scope string) middleware {
+	return func(f http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			token, ok := fromContextGetToken(r.Context())
+			if !ok {
+				log.Error("bearer token must be validated in order to verify its scope")
 				http.Error(w, repository.ErrInvalidLogin.Error(), http.StatusUnauthorized)
 				return
 			}
+			if !token.HasScope(scope) {
+				log.Debug("bearer token was not granted the scope", zap.String("client ID", token.ClientID.String()), zap.String("scope", scope))
+				w.Header().Set("WWW-Authenticate", "Bearer error=\"insufficient_scope\", scope=\""+scope+"\"")
+				http.Error(w, oauth2.ErrInvalidScope.Error(), http.StatusForbidden)
+				return
+			}
 			f(w, r)
 		}
 	}
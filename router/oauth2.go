@@ -20,20 +20,22 @@ func newOauth2Router(router *mux.Router) {
 	router.HandleFunc("/authorize", chain(
 		oauth2AuthorizeHandler,
 		sessionCookieSecurity),
-	).Methods(http.MethodGet, http.MethodPost)
+	).Methods(http.MethodGet, http.MethodPost).Name(authorizeRoute)
 	router.HandleFunc("/introspect", chain(
 		oauth2IntrospectHandler,
 		basicUserAuthSecurity,
 		verifyUserGroups("Admins", "ProtectedResources")),
-	).Methods("POST")
+	).Methods("POST").Name(introspectRoute)
+	router.HandleFunc("/jwks", oauth2JwksHandler).Methods(http.MethodGet).Name(jwksRoute)
 	//TODO according to rfc anonymous registration is allowed, token may be allowed.
-	router.HandleFunc("/register", chain(oauth2RegisterHandlerPost, basicUserAuthSecurity)).Methods("POST")
+	router.HandleFunc("/register", chain(oauth2RegisterHandlerPost, basicUserAuthSecurity)).Methods("POST").Name(registerRoute)
 	//TODO according to rfc authorization must be token and not basic. Replace basicUserAuthSecurity
 	router.HandleFunc("/register/{id:[-a-zA-Z0-9]+}", chain(
 		oauth2RegisterHandlerGet,
 		basicUserAuthSecurity)).Methods("GET")
-	router.HandleFunc("/revoke", chain(oauth2RevokeHandlerPost, basicClientAuthSecurity)).Methods("POST")
-	router.HandleFunc("/token", chain(oauth2TokenHandlerPost, basicClientAuthSecurity)).Methods("POST")
+	router.HandleFunc("/revoke", chain(oauth2RevokeHandlerPost, basicClientAuthSecurity)).Methods("POST").Name(revokeRoute)
+	router.HandleFunc("/token", chain(oauth2TokenHandlerPost, basicClientAuthSecurity)).Methods("POST").Name(tokenRoute)
+	router.HandleFunc("/userinfo", chain(oauth2UserInfoHandler, bearerTokenSecurity)).Methods(http.MethodGet, http.MethodPost).Name(userInfoRoute)
 }
 
 func oauth2AuthorizeHandler(w http.ResponseWriter, r *http.Request) {
@@ -321,6 +323,19 @@ func oauth2IntrospectHandler(w http.ResponseWriter, r *http.Request) {
 	return
 }
 
+//oauth2JwksHandler returns the JWK Set with the public keys used to verify the tokens signed by the server
+func oauth2JwksHandler(w http.ResponseWriter, r *http.Request) {
+	jwksJSON, err := json.Marshal(repository.GetJSONWebKeySet())
+	if err != nil {
+		log.Error("can not marshal json web key set", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(jwksJSON)
+}
+
 /**
      GET /register/s6BhdRkqt3 HTTP/1.1
      Accept: application/json
@@ -394,10 +409,12 @@ func oauth2RegisterHandlerPost(w http.ResponseWriter, r *http.Request) {
 	}
 
 	//todo process client registration
-	client, err := repository.NewClientFromOauth(clientReq)
+	client, secret, err := repository.NewClientFromOauth(clientReq)
 	if err != nil {
 		log.Error("can not create new client", zap.String("client name", clientReq.ClientName), zap.Error(err))
 		//todo client.GetClientRegistrationError
+		http.Error(w, repository.ErrInvalidRequest.Error(), http.StatusBadRequest)
+		return
 	}
 	//TODO clean context() after it was used leaving no trace
 	//TODO use better func fromContextGetUser(ctx context.Context) (*repository.User, bool)
@@ -414,6 +431,8 @@ func oauth2RegisterHandlerPost(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		log.Error("can not get client information response", zap.String("client id", client.ID.String()), zap.Error(err))
 	}
+	//the secret is only revealed when the client is registered
+	clientInfResp.ClientSecret = secret
 	//Marshal clientInfResp to json and write to response
 	cirJSON, err := json.Marshal(clientInfResp)
 	if err != nil {
@@ -477,6 +496,12 @@ func oauth2TokenHandlerPost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	//RFC8707 resource indicators must be absolute URIs
+	if resource := r.PostForm.Get("resource"); len(resource) > 0 && !oauth2.IsValidResource(resource) {
+		log.Error("can not process access token request", zap.String("resource", resource), zap.Error(oauth2.ErrInvalidTargetInfo))
+		http.Error(w, oauth2.ErrInvalidTarget.Error(), http.StatusBadRequest)
+		return
+	}
 	grant := r.FormValue("grant_type")
 	var options *oauth2.AccessTokenOptions
 	//authorization code grant
@@ -539,9 +564,49 @@ func oauth2TokenHandlerPost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	//Set Content-Type header so that clients will know how to read response
-	//todo cache and other security headers. See standard for access token response
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
 	w.WriteHeader(http.StatusOK)
 	//Write json response back to response
 	w.Write(atrJSON)
 }
+
+/**
+OpenID Connect Core 1.0 - 5.3. UserInfo Endpoint
+The Client sends the UserInfo Request using either HTTP GET or HTTP POST. The Access Token obtained from an OpenID
+Connect Authentication Request MUST be sent as a Bearer Token.
+     GET /userinfo HTTP/1.1
+     Host: server.example.com
+     Authorization: Bearer SlAV32hkKG
+*/
+func oauth2UserInfoHandler(w http.ResponseWriter, r *http.Request) {
+	token, ok := fromContextGetToken(r.Context())
+	if !ok {
+		log.Debug("can not get token from context")
+		http.Error(w, repository.ErrInvalidLogin.Error(), http.StatusUnauthorized)
+		return
+	}
+	userInfo, err := repository.GetUserInfo(token)
+	if err == oauth2.ErrInvalidScope {
+		w.Header().Set("WWW-Authenticate", "Bearer error=\"insufficient_scope\", scope=\"openid\"")
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		log.Debug("can not get user info", zap.String("owner ID", token.GetResourceOwner().String()), zap.Error(err))
+		w.Header().Set("WWW-Authenticate", "Bearer error=\"invalid_token\"")
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	uiJSON, err := json.Marshal(userInfo)
+	if err != nil {
+		log.Error("can not marshal user info", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	w.Write(uiJSON)
+}
@@ -4,30 +4,41 @@ import (
 	"bounzr/iam/logger"
 	"github.com/gorilla/mux"
 	"github.com/gorilla/schema"
-	"github.com/gorilla/securecookie"
 	"github.com/gorilla/sessions"
 	"go.uber.org/zap"
 	"net/http"
 )
 
+//names of the routes published in the server metadata
+const (
+	authorizeRoute  = "oauth2.authorize"
+	introspectRoute = "oauth2.introspect"
+	jwksRoute       = "oauth2.jwks"
+	registerRoute   = "oauth2.register"
+	revokeRoute     = "oauth2.revoke"
+	tokenRoute      = "oauth2.token"
+	userInfoRoute   = "oauth2.userinfo"
+)
+
 const (
 	ConsentsToken    = "consents_token"
+	MfaExpiresAt     = "_mfa_expires_at"
+	MfaUserID        = "_mfa_user_id"
 	SessionCookie    = "session"
 	TargetUrl        = "_target_url"
 	UserSessionToken = "bounzr_token"
 )
 
-//TODO authKey and encryptKey in the shared registry available for other nodes?
 var (
 	log               *zap.Logger
-	authKey           = securecookie.GenerateRandomKey(64)
-	encryptKey        = securecookie.GenerateRandomKey(32)
-	BounzrCookieStore = sessions.NewCookieStore([]byte(authKey), []byte(encryptKey))
-	decoder           = schema.NewDecoder()
+	BounzrCookieStore sessions.Store = &cookieStore{}
+	decoder                          = schema.NewDecoder()
 )
 
 func Init() {
 	log = logger.GetLogger()
+	//form requests may include fields handled outside of the decoded struct, e.g. client_id and client_secret
+	decoder.IgnoreUnknownKeys(true)
 }
 
 func NewRouter() *mux.Router {
@@ -47,6 +58,9 @@ func NewRouter() *mux.Router {
 	//scim2 subrouter
 	scim2SubRouter := r.PathPrefix("/scim2").Subrouter()
 	newScim2Router(scim2SubRouter)
+	//well-known subrouter
+	wellKnownSubRouter := r.PathPrefix("/.well-known").Subrouter()
+	newWellKnownRouter(wellKnownSubRouter, r)
 
 	return r
 }
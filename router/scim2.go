@@ -1,26 +1,41 @@
 package router
 
 import (
+	"bounzr/iam/config"
 	"bounzr/iam/repository"
 	"bounzr/iam/scim2"
+	"bytes"
 	"encoding/json"
 	"github.com/gofrs/uuid"
 	"github.com/gorilla/mux"
 	"go.uber.org/zap"
+	"io/ioutil"
 	"net/http"
 	"strings"
 )
 
+//header with the current password of the user required to change the password at "/Me"
+const currentPasswordHeader = "X-Current-Password"
+
 func newScim2Router(router *mux.Router) {
 	router.HandleFunc("/clients", chain(
 		clientsHandler,
+		scimErrorHandling,
 		basicUserAuthSecurity,
 		verifyUserGroups("Admins", "Clients"))).Methods(
 		http.MethodGet,
 		http.MethodPost,
 	)
-	router.HandleFunc("/clients/{id:[-a-zA-Z0-9]+", chain(
+	router.HandleFunc("/clients/.search", chain(
+		clientsGet,
+		scimErrorHandling,
+		basicUserAuthSecurity,
+		verifyUserGroups("Admins", "Clients"))).Methods(
+		http.MethodPost,
+	)
+	router.HandleFunc("/clients/{id:[-a-zA-Z0-9]+}", chain(
 		clientHandler,
+		scimErrorHandling,
 		basicUserAuthSecurity,
 		verifyUserGroups("Admins", "Clients"))).Methods(
 		http.MethodDelete,
@@ -28,32 +43,100 @@ func newScim2Router(router *mux.Router) {
 		http.MethodPatch,
 		http.MethodPut,
 	)
+	router.HandleFunc("/clients/{id:[-a-zA-Z0-9]+}/secret", chain(
+		clientSecretPost,
+		scimErrorHandling,
+		basicUserAuthSecurity,
+		verifyUserGroups("Admins", "Clients"))).Methods(
+		http.MethodPost,
+	)
 	router.HandleFunc("/users", chain(
 		usersHandler,
+		scimErrorHandling,
 		basicUserAuthSecurity,
 		verifyUserGroups("Admins"))).Methods(
 		http.MethodGet,
 		http.MethodPost,
 	)
+	router.HandleFunc("/users/.search", chain(
+		usersGet,
+		scimErrorHandling,
+		basicUserAuthSecurity,
+		verifyUserGroups("Admins"))).Methods(
+		http.MethodPost,
+	)
 	router.HandleFunc("/users/{id:[-a-zA-Z0-9]+}", chain(
 		userHandler,
+		scimErrorHandling,
 		basicUserAuthSecurity)).Methods(
 		http.MethodGet,
 		http.MethodDelete,
 		http.MethodPatch,
 		http.MethodPut,
 	)
+	router.HandleFunc("/Me", chain(
+		meHandler,
+		scimErrorHandling,
+		bearerTokenSecurity,
+		verifyTokenScope(scim2.MeScope))).Methods(
+		http.MethodGet,
+		http.MethodPatch,
+		http.MethodPut,
+	)
 	router.HandleFunc("/groups", chain(
 		groupsHandler,
+		scimErrorHandling,
+		basicUserAuthSecurity,
+		verifyUserGroups("Admins"))).Methods(
+		http.MethodGet,
+		http.MethodPost,
+	)
+	router.HandleFunc("/groups/.search", chain(
+		groupsGet,
+		scimErrorHandling,
+		basicUserAuthSecurity,
+		verifyUserGroups("Admins"))).Methods(
+		http.MethodPost,
+	)
+	router.HandleFunc("/groups/{id:[-a-zA-Z0-9]+}", chain(
+		groupHandler,
+		scimErrorHandling,
 		basicUserAuthSecurity,
 		verifyUserGroups("Admins"))).Methods(
 		http.MethodDelete,
 		http.MethodGet,
 		http.MethodPatch,
-		http.MethodPost,
 		http.MethodPut,
 	)
-	router.HandleFunc("/groups/{id:[-a-zA-Z0-9]+}", chain(groupGet, basicUserAuthSecurity)).Methods(http.MethodGet)
+	router.HandleFunc("/Bulk", chain(
+		bulkPost,
+		scimErrorHandling,
+		basicUserAuthSecurity,
+		verifyUserGroups("Admins"))).Methods(
+		http.MethodPost,
+	)
+	router.HandleFunc("/.search", chain(
+		resourcesSearch,
+		scimErrorHandling,
+		basicUserAuthSecurity,
+		verifyUserGroups("Admins"))).Methods(
+		http.MethodPost,
+	)
+	router.HandleFunc("/ServiceProviderConfig", serviceProviderConfigGet).Methods(
+		http.MethodGet,
+	)
+	router.HandleFunc("/ResourceTypes", resourceTypesGet).Methods(
+		http.MethodGet,
+	)
+	router.HandleFunc("/ResourceTypes/{name}", resourceTypeGet).Methods(
+		http.MethodGet,
+	)
+	router.HandleFunc("/Schemas", schemasGet).Methods(
+		http.MethodGet,
+	)
+	router.HandleFunc("/Schemas/{id}", schemaGet).Methods(
+		http.MethodGet,
+	)
 }
 
 func clientDelete(w http.ResponseWriter, r *http.Request) {
@@ -62,10 +145,14 @@ func clientDelete(w http.ResponseWriter, r *http.Request) {
 	uid, err := uuid.FromString(id)
 	if err != nil {
 		log.Debug("wrong user id", zap.String("id", id))
-		http.Error(w, repository.ErrInvalidRequest.Error(), http.StatusBadRequest)
+		writeScimError(w, scim2.ErrNotFound, http.StatusNotFound)
+		return
+	}
+	err = repository.DeleteClient(uid, scim2.NewPreconditions(r.Header))
+	if err != nil {
+		writeScimError(w, err, getScimErrorStatus(err))
 		return
 	}
-	repository.DeleteClient(uid)
 	w.WriteHeader(http.StatusNoContent)
 	return
 }
@@ -76,6 +163,8 @@ func clientHandler(w http.ResponseWriter, r *http.Request) {
 		clientDelete(w, r)
 	case http.MethodGet:
 		clientGet(w, r)
+	case http.MethodPatch:
+		clientPatch(w, r)
 	case http.MethodPut:
 		clientPut(w, r)
 	default:
@@ -89,22 +178,45 @@ func clientGet(w http.ResponseWriter, r *http.Request) {
 	client, found := repository.GetClient(uuid.FromStringOrNil(id))
 	if !found {
 		log.Debug("client not found", zap.String("id", id))
-		http.Error(w, repository.ErrInvalidRequest.Error(), http.StatusBadRequest)
+		writeScimError(w, scim2.ErrNotFound, http.StatusNotFound)
 		return
 	}
 	resource := client.GetScim()
-	//Marshal clientInfResp to json and write to response
-	resourceJson, err := json.Marshal(resource)
+	if !validateScimPreconditions(w, r, resource.Metadata) {
+		return
+	}
+	setScimETag(w, resource.Metadata)
+	writeScimProjection(w, r, resource, scim2.ClientSchema)
+}
+
+func clientPatch(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	uid, err := uuid.FromString(id)
 	if err != nil {
-		log.Error("can not marshal resource json", zap.String("id", resource.ID), zap.Error(scim2.ErrInternalError))
-		http.Error(w, repository.ErrResourceNotAvailable.Error(), http.StatusInternalServerError)
+		log.Debug("wrong client id", zap.String("id", id))
+		writeScimError(w, scim2.ErrNotFound, http.StatusNotFound)
 		return
 	}
-	//Set Content-Type header so that clients will know how to read response
-	w.Header().Set("Content-Type", "application/scim+json")
-	w.WriteHeader(http.StatusOK)
-	w.Write(resourceJson)
-	return
+	patch, err := getScimPatchOp(r)
+	if err != nil {
+		writeScimError(w, err, http.StatusBadRequest)
+		return
+	}
+	err = repository.PatchClient(uid, patch, scim2.NewPreconditions(r.Header))
+	if err != nil {
+		writeScimError(w, err, getScimErrorStatus(err))
+		return
+	}
+	client, found := repository.GetClient(uid)
+	if !found {
+		log.Error("can not get client", zap.String("id", id), zap.Error(repository.ErrClientNotFound))
+		writeScimError(w, scim2.ErrInternalError, http.StatusInternalServerError)
+		return
+	}
+	resource := client.GetScim()
+	setScimETag(w, resource.Metadata)
+	writeScimResource(w, resource)
 }
 
 func clientPut(w http.ResponseWriter, r *http.Request) {
@@ -113,22 +225,25 @@ func clientPut(w http.ResponseWriter, r *http.Request) {
 	uid, err := uuid.FromString(id)
 	if err != nil {
 		log.Debug("wrong user id", zap.String("id", id))
-		http.Error(w, repository.ErrInvalidRequest.Error(), http.StatusBadRequest)
+		writeScimError(w, scim2.ErrNotFound, http.StatusNotFound)
 		return
 	}
 	cscim := &scim2.Client{}
 	err = json.NewDecoder(r.Body).Decode(cscim)
 	if err != nil {
 		log.Error("can not decode client", zap.String("client id", cscim.ID), zap.Error(err))
-		http.Error(w, repository.ErrInvalidLogin.Error(), http.StatusForbidden)
+		writeScimError(w, scim2.ErrBadRequestInvalidSyntax, http.StatusBadRequest)
+		return
+	}
+	err = repository.ReplaceClientByScim(uid, cscim, scim2.NewPreconditions(r.Header))
+	if err != nil {
+		writeScimError(w, err, getScimErrorStatus(err))
 		return
 	}
-	repository.ReplaceClientByScim(uid, cscim)
-
 	client, found := repository.GetClient(uid)
 	if !found {
 		log.Debug("client not found", zap.String("id", id))
-		http.Error(w, repository.ErrInvalidRequest.Error(), http.StatusBadRequest)
+		writeScimError(w, scim2.ErrNotFound, http.StatusNotFound)
 		return
 	}
 	scim := client.GetScim()
@@ -136,9 +251,10 @@ func clientPut(w http.ResponseWriter, r *http.Request) {
 	scimJson, err := json.Marshal(scim)
 	if err != nil {
 		log.Error("can not marshal scim json", zap.String("user id", scim.ID), zap.Error(err))
-		http.Error(w, repository.ErrInvalidLogin.Error(), http.StatusInternalServerError)
+		writeScimError(w, scim2.ErrInternalError, http.StatusInternalServerError)
 		return
 	}
+	setScimETag(w, scim.Metadata)
 	//Set Content-Type header so that clients will know how to read response
 	w.Header().Set("Content-Type", "application/scim+json")
 	w.WriteHeader(http.StatusOK)
@@ -146,6 +262,33 @@ func clientPut(w http.ResponseWriter, r *http.Request) {
 	return
 }
 
+//clientSecretPost rotates the client secret. The previous secret stays valid during the configured grace period
+func clientSecretPost(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	client, secret, err := repository.RotateClientSecret(uuid.FromStringOrNil(id))
+	if err != nil {
+		log.Debug("can not rotate client secret", zap.String("id", id), zap.Error(err))
+		writeScimError(w, err, getScimErrorStatus(err))
+		return
+	}
+	scim := client.GetScim()
+	scim.Password = secret
+	//Marshal clientInfResp to json and write to response
+	scimJson, err := json.Marshal(scim)
+	if err != nil {
+		log.Error("can not marshal scim json", zap.String("client id", scim.ID), zap.Error(err))
+		writeScimError(w, scim2.ErrInternalError, http.StatusInternalServerError)
+		return
+	}
+	setScimETag(w, scim.Metadata)
+	//Set Content-Type header so that clients will know how to read response
+	w.Header().Set("Content-Type", "application/scim+json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	w.Write(scimJson)
+}
+
 func clientsHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method == http.MethodGet {
 		clientsGet(w, r)
@@ -156,35 +299,27 @@ func clientsHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func clientsGet(w http.ResponseWriter, r *http.Request) {
-	clients := repository.FindClients()
-	schema := []string{"urn:ietf:params:scim:api:messages:2.0:ListResponse"}
-	totalResults := len(clients)
-	listResponse := scim2.ResourceQueryResponse{
-		Schemas:      schema,
-		TotalResults: totalResults,
+	query, projection, err := getScimListRequest(w, r)
+	if err != nil {
+		writeScimError(w, err, getScimErrorStatus(err))
+		return
 	}
-	if totalResults > 0 {
-		listResponse.Resources = clients
+	clients, totalResults, err := repository.FindClients(query)
+	if err != nil {
+		writeScimError(w, scim2.ErrInternalError, http.StatusInternalServerError)
+		return
 	}
-	//Marshal to json and write to response
-	jsonResponse, err := json.Marshal(listResponse)
+	resources, err := projection.ApplyList(clients, scim2.ClientSchema)
 	if err != nil {
-		log.Error("can not marshal users json", zap.Error(err))
-		http.Error(w, repository.ErrInvalidLogin.Error(), http.StatusInternalServerError)
+		log.Error("can not project clients", zap.Error(err))
+		writeScimError(w, scim2.ErrInternalError, http.StatusInternalServerError)
 		return
 	}
-	//Set Content-Type header so that clients will know how to read response
-	w.Header().Set("Content-Type", "application/scim+json")
-	w.WriteHeader(http.StatusOK)
-	//Write json response back to response
-	w.Write(jsonResponse)
-	return
+	writeScimListResponse(w, query, resources, totalResults)
 }
 
 func notImplemented(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	w.Write([]byte("{\"request\":\"not implemented\"}"))
-	w.WriteHeader(http.StatusNotImplemented)
+	writeScimError(w, scim2.ErrNotImplemented, http.StatusNotImplemented)
 }
 
 func clientsPost(w http.ResponseWriter, r *http.Request) {
@@ -192,13 +327,13 @@ func clientsPost(w http.ResponseWriter, r *http.Request) {
 	err := json.NewDecoder(r.Body).Decode(clientReq)
 	if err != nil {
 		log.Error("can not decode client", zap.String("client id", clientReq.ID), zap.Error(err))
-		http.Error(w, repository.ErrInvalidLogin.Error(), http.StatusForbidden)
+		writeScimError(w, scim2.ErrBadRequestInvalidSyntax, http.StatusBadRequest)
 		return
 	}
-	client, err := repository.NewClientFromScim(clientReq)
+	client, secret, err := repository.NewClientFromScim(clientReq)
 	if err != nil {
 		log.Error("can not add user from scim", zap.String("user id", clientReq.ID), zap.Error(err))
-		http.Error(w, repository.ErrInvalidLogin.Error(), http.StatusBadRequest)
+		writeScimError(w, scim2.ErrInternalError, http.StatusInternalServerError)
 		return
 	}
 	//TODO clean context() after it was used leaving no trace
@@ -213,13 +348,16 @@ func clientsPost(w http.ResponseWriter, r *http.Request) {
 	repository.SetClient(client)
 	repository.SetResourceGroups(clientReq, client.GetResourceTag())
 	scim := client.GetScim()
+	//the secret is only revealed when the client is created or its secret rotated
+	scim.Password = secret
 	//Marshal clientInfResp to json and write to response
 	scimJson, err := json.Marshal(scim)
 	if err != nil {
 		log.Error("can not marshal scim json", zap.String("user id", scim.ID), zap.Error(err))
-		http.Error(w, repository.ErrInvalidLogin.Error(), http.StatusInternalServerError)
+		writeScimError(w, scim2.ErrInternalError, http.StatusInternalServerError)
 		return
 	}
+	setScimETag(w, scim.Metadata)
 	//Set Content-Type header so that clients will know how to read response
 	w.Header().Set("Content-Type", "application/scim+json")
 	w.WriteHeader(http.StatusOK)
@@ -227,48 +365,281 @@ func clientsPost(w http.ResponseWriter, r *http.Request) {
 	return
 }
 
-func groupGet(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	w.Write([]byte("{\"request\":\"not implemented\"}"))
-	w.WriteHeader(http.StatusNotImplemented)
+//getScimPatchOp decodes the PatchOp request of a PATCH request
+func getScimPatchOp(r *http.Request) (*scim2.PatchOp, error) {
+	patch := &scim2.PatchOp{}
+	err := json.NewDecoder(r.Body).Decode(patch)
+	if err != nil {
+		log.Debug("can not decode patch request", zap.Error(err))
+		return nil, scim2.ErrBadRequestInvalidSyntax
+	}
+	return patch, nil
 }
 
-func groupsDelete(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	w.Write([]byte("{\"request\":\"not implemented\"}"))
-	w.WriteHeader(http.StatusNotImplemented)
+//getScimRequestBody reads the body of the request up to the maximum payload size of the service provider
+func getScimRequestBody(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	maxPayloadSize := int64(config.IAM.Scim.GetBulkMaxPayloadSize())
+	if r.ContentLength > maxPayloadSize {
+		log.Debug("request too large", zap.Int64("content length", r.ContentLength))
+		return nil, scim2.ErrPayloadTooLarge
+	}
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxPayloadSize))
+	if err != nil {
+		log.Debug("can not read request", zap.Error(err))
+		return nil, scim2.ErrPayloadTooLarge
+	}
+	return body, nil
 }
 
-func groupsGet(w http.ResponseWriter, r *http.Request) {
-	filter := make(map[string]interface{})
-	groups := repository.FindGroups(filter)
-	schema := []string{"urn:ietf:params:scim:api:messages:2.0:ListResponse"}
-	totalResults := len(groups)
-	listResponse := scim2.ResourceQueryResponse{
-		Schemas:      schema,
-		TotalResults: totalResults,
-	}
-	if totalResults > 0 {
-		listResponse.Resources = groups
+//getScimErrorStatus returns the http status of the errors returned when resources are modified
+func getScimErrorStatus(err error) int {
+	switch err {
+	case repository.ErrUsernameNotFound, repository.ErrGroupNotFound, repository.ErrClientNotFound, scim2.ErrNotFound:
+		return http.StatusNotFound
+	case repository.ErrUsernameNotAvailable, scim2.ErrBadRequestUniqueness, scim2.ErrConflict:
+		return http.StatusConflict
+	case scim2.ErrUnauthorized:
+		return http.StatusUnauthorized
+	case repository.ErrGroupProtected, scim2.ErrForbidden:
+		return http.StatusForbidden
+	case scim2.ErrPreconditionFailed:
+		return http.StatusPreconditionFailed
+	case scim2.ErrPayloadTooLarge:
+		return http.StatusRequestEntityTooLarge
+	case scim2.ErrNotImplemented:
+		return http.StatusNotImplemented
+	case scim2.ErrInternalError:
+		return http.StatusInternalServerError
 	}
-	//Marshal to json and write to response
-	jsonResponse, err := json.Marshal(listResponse)
+	return http.StatusBadRequest
+}
+
+//writeScimResource writes the json representation of the resource
+func writeScimResource(w http.ResponseWriter, resource interface{}) {
+	resourceJson, err := json.Marshal(resource)
 	if err != nil {
-		log.Error("can not marshal users json", zap.Error(err))
-		http.Error(w, repository.ErrInvalidLogin.Error(), http.StatusInternalServerError)
+		log.Error("can not marshal resource json", zap.Error(err))
+		writeScimError(w, scim2.ErrInternalError, http.StatusInternalServerError)
 		return
 	}
 	//Set Content-Type header so that clients will know how to read response
 	w.Header().Set("Content-Type", "application/scim+json")
 	w.WriteHeader(http.StatusOK)
-	w.Write(jsonResponse)
+	w.Write(resourceJson)
+}
+
+//setScimETag sets the ETag header to the version of the resource
+func setScimETag(w http.ResponseWriter, metadata *scim2.Metadata) {
+	if metadata != nil && len(metadata.Version) > 0 {
+		w.Header().Set("ETag", metadata.Version)
+	}
+}
+
+//validateScimPreconditions writes the 304 or 412 response if the If-Match or If-None-Match headers of the request do
+//not hold for the current version of the resource
+func validateScimPreconditions(w http.ResponseWriter, r *http.Request, metadata *scim2.Metadata) bool {
+	err := scim2.ValidatePreconditions(r.Method, r.Header, metadata.Version)
+	switch err {
+	case nil:
+		return true
+	case scim2.ErrNotModified:
+		setScimETag(w, metadata)
+		w.WriteHeader(http.StatusNotModified)
+	default:
+		log.Debug("scim precondition failed", zap.String("location", metadata.Location), zap.String("version", metadata.Version))
+		writeScimError(w, err, http.StatusPreconditionFailed)
+	}
+	return false
+}
+
+//getScimListQuery returns the filter, sorting and pagination parameters of a list request
+func getScimListQuery(r *http.Request) (*scim2.ListQuery, error) {
+	query, err := scim2.NewListQuery(r.URL.Query(), config.IAM.Scim.GetMaxResults())
+	if err != nil {
+		log.Debug("invalid scim list query", zap.String("query", r.URL.RawQuery), zap.Error(err))
+		return nil, err
+	}
+	return query, nil
+}
+
+//getScimProjection returns the projection of the comma separated attributes and excludedAttributes parameters
+func getScimProjection(r *http.Request) (*scim2.Projection, error) {
+	values := r.URL.Query()
+	attributes := strings.Split(values.Get("attributes"), ",")
+	excludedAttributes := strings.Split(values.Get("excludedAttributes"), ",")
+	projection, err := scim2.NewProjection(attributes, excludedAttributes)
+	if err != nil {
+		log.Debug("invalid scim attributes", zap.String("query", r.URL.RawQuery), zap.Error(err))
+		return nil, err
+	}
+	return projection, nil
+}
+
+//getScimListRequest returns the list query and the projection of a list request. The parameters of POST requests to
+//the ".search" endpoints are read from the SearchRequest body and the parameters of GET requests from the URI
+func getScimListRequest(w http.ResponseWriter, r *http.Request) (*scim2.ListQuery, *scim2.Projection, error) {
+	if r.Method != http.MethodPost {
+		query, err := getScimListQuery(r)
+		if err != nil {
+			return nil, nil, err
+		}
+		projection, err := getScimProjection(r)
+		if err != nil {
+			return nil, nil, err
+		}
+		return query, projection, nil
+	}
+	body, err := getScimRequestBody(w, r)
+	if err != nil {
+		return nil, nil, err
+	}
+	searchReq := &scim2.SearchRequest{}
+	if err = json.Unmarshal(body, searchReq); err != nil {
+		log.Debug("can not decode search request", zap.Error(err))
+		return nil, nil, scim2.ErrBadRequestInvalidSyntax
+	}
+	query, err := searchReq.GetListQuery(config.IAM.Scim.GetMaxResults())
+	if err != nil {
+		log.Debug("invalid scim search request", zap.String("filter", searchReq.Filter), zap.Error(err))
+		return nil, nil, err
+	}
+	projection, err := searchReq.GetProjection()
+	if err != nil {
+		log.Debug("invalid scim search attributes", zap.Error(err))
+		return nil, nil, err
+	}
+	return query, projection, nil
+}
+
+//writeScimListResponse writes the list response of the resources of the requested page
+func writeScimListResponse(w http.ResponseWriter, query *scim2.ListQuery, resources []map[string]interface{}, totalResults int) {
+	listResponse := scim2.ResourceQueryResponse{
+		Schemas:      []string{scim2.ListResponseSchema},
+		TotalResults: totalResults,
+		StartIndex:   query.StartIndex,
+		ItemsPerPage: len(resources),
+	}
+	if len(resources) > 0 {
+		listResponse.Resources = resources
+	}
+	writeScimResource(w, listResponse)
+}
+
+//writeScimProjection writes the json representation of the attributes of the resource returned by the projection of
+//the request
+func writeScimProjection(w http.ResponseWriter, r *http.Request, resource interface{}, schema string) {
+	projection, err := getScimProjection(r)
+	if err != nil {
+		writeScimError(w, err, http.StatusBadRequest)
+		return
+	}
+	values, err := projection.Apply(resource, schema)
+	if err != nil {
+		log.Error("can not project resource", zap.Error(err))
+		writeScimError(w, scim2.ErrInternalError, http.StatusInternalServerError)
+		return
+	}
+	writeScimResource(w, values)
+}
+
+func groupDelete(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	uid, err := uuid.FromString(id)
+	if err != nil {
+		log.Debug("wrong group id", zap.String("id", id))
+		writeScimError(w, scim2.ErrNotFound, http.StatusNotFound)
+		return
+	}
+	err = repository.DeleteGroup(uid, scim2.NewPreconditions(r.Header))
+	if err != nil {
+		writeScimError(w, err, getScimErrorStatus(err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
 	return
 }
 
-func groupsPatch(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	w.Write([]byte("{\"request\":\"not implemented\"}"))
-	w.WriteHeader(http.StatusNotImplemented)
+func groupGet(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	group, err := repository.GetGroup(uuid.FromStringOrNil(id))
+	if err != nil {
+		log.Debug("group not found", zap.String("id", id))
+		writeScimError(w, err, http.StatusNotFound)
+		return
+	}
+	resource := group.GetScim()
+	if !validateScimPreconditions(w, r, resource.Metadata) {
+		return
+	}
+	setScimETag(w, resource.Metadata)
+	writeScimProjection(w, r, resource, scim2.GroupSchema)
+}
+
+func groupHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodDelete:
+		groupDelete(w, r)
+	case http.MethodGet:
+		groupGet(w, r)
+	case http.MethodPatch:
+		groupPatch(w, r)
+	case http.MethodPut:
+		groupPut(w, r)
+	default:
+		notImplemented(w, r)
+	}
+}
+
+func groupsGet(w http.ResponseWriter, r *http.Request) {
+	query, projection, err := getScimListRequest(w, r)
+	if err != nil {
+		writeScimError(w, err, getScimErrorStatus(err))
+		return
+	}
+	groups, totalResults, err := repository.FindGroups(query)
+	if err != nil {
+		writeScimError(w, scim2.ErrInternalError, http.StatusInternalServerError)
+		return
+	}
+	resources, err := projection.ApplyList(groups, scim2.GroupSchema)
+	if err != nil {
+		log.Error("can not project groups", zap.Error(err))
+		writeScimError(w, scim2.ErrInternalError, http.StatusInternalServerError)
+		return
+	}
+	writeScimListResponse(w, query, resources, totalResults)
+}
+
+func groupPatch(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	uid, err := uuid.FromString(id)
+	if err != nil {
+		log.Debug("wrong group id", zap.String("id", id))
+		writeScimError(w, scim2.ErrNotFound, http.StatusNotFound)
+		return
+	}
+	patch, err := getScimPatchOp(r)
+	if err != nil {
+		writeScimError(w, err, http.StatusBadRequest)
+		return
+	}
+	err = repository.PatchGroup(uid, patch, scim2.NewPreconditions(r.Header))
+	if err != nil {
+		writeScimError(w, err, getScimErrorStatus(err))
+		return
+	}
+	group, err := repository.GetGroup(uid)
+	if err != nil {
+		log.Error("can not get group", zap.String("group id", id), zap.Error(err))
+		writeScimError(w, scim2.ErrInternalError, http.StatusInternalServerError)
+		return
+	}
+	resource := group.GetScim()
+	setScimETag(w, resource.Metadata)
+	writeScimResource(w, resource)
 }
 
 func groupsPost(w http.ResponseWriter, r *http.Request) {
@@ -276,28 +647,29 @@ func groupsPost(w http.ResponseWriter, r *http.Request) {
 	err := json.NewDecoder(r.Body).Decode(groupReq)
 	if err != nil {
 		log.Error("can not decode group json", zap.Error(err))
-		http.Error(w, repository.ErrInvalidLogin.Error(), http.StatusForbidden)
+		writeScimError(w, scim2.ErrBadRequestInvalidSyntax, http.StatusBadRequest)
 		return
 	}
 	id, err := repository.AddScimGroup(groupReq)
 	if err != nil {
 		log.Error("can not add group from scim", zap.String("group id", groupReq.ID), zap.Error(err))
-		http.Error(w, repository.ErrInvalidLogin.Error(), http.StatusUnauthorized)
+		writeScimError(w, err, getScimErrorStatus(err))
 		return
 	}
 	group, err := repository.GetGroup(id)
 	if err != nil {
 		log.Error("can not get group", zap.String("group id", id.String()), zap.Error(err))
-		http.Error(w, repository.ErrInvalidLogin.Error(), http.StatusInternalServerError)
+		writeScimError(w, scim2.ErrInternalError, http.StatusInternalServerError)
 		return
 	}
 	scim := group.GetScim()
 	scimJson, err := json.Marshal(scim)
 	if err != nil {
 		log.Error("can not marshal group to scim json", zap.String("group id", scim.ID), zap.Error(err))
-		http.Error(w, repository.ErrInvalidLogin.Error(), http.StatusInternalServerError)
+		writeScimError(w, scim2.ErrInternalError, http.StatusInternalServerError)
 		return
 	}
+	setScimETag(w, scim.Metadata)
 	//Set Content-Type header so that clients will know how to read response
 	w.Header().Set("Content-Type", "application/scim+json")
 	w.WriteHeader(http.StatusOK)
@@ -306,28 +678,45 @@ func groupsPost(w http.ResponseWriter, r *http.Request) {
 	return
 }
 
-func groupsPut(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	w.Write([]byte("{\"request\":\"not implemented\"}"))
-	w.WriteHeader(http.StatusNotImplemented)
+func groupPut(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	uid, err := uuid.FromString(id)
+	if err != nil {
+		log.Debug("wrong group id", zap.String("id", id))
+		writeScimError(w, scim2.ErrNotFound, http.StatusNotFound)
+		return
+	}
+	groupReq := &scim2.Group{}
+	err = json.NewDecoder(r.Body).Decode(groupReq)
+	if err != nil {
+		log.Debug("can not decode group json", zap.Error(err))
+		writeScimError(w, scim2.ErrBadRequestInvalidSyntax, http.StatusBadRequest)
+		return
+	}
+	err = repository.ReplaceGroupByScim(uid, groupReq, scim2.NewPreconditions(r.Header))
+	if err != nil {
+		writeScimError(w, err, getScimErrorStatus(err))
+		return
+	}
+	group, err := repository.GetGroup(uid)
+	if err != nil {
+		log.Error("can not get group", zap.String("group id", id), zap.Error(err))
+		writeScimError(w, scim2.ErrInternalError, http.StatusInternalServerError)
+		return
+	}
+	resource := group.GetScim()
+	setScimETag(w, resource.Metadata)
+	writeScimResource(w, resource)
 }
 
 func groupsHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method == http.MethodDelete {
-		groupsDelete(w, r)
-	}
 	if r.Method == http.MethodGet {
 		groupsGet(w, r)
 	}
-	if r.Method == http.MethodPatch {
-		groupsPatch(w, r)
-	}
 	if r.Method == http.MethodPost {
 		groupsPost(w, r)
 	}
-	if r.Method == http.MethodPut {
-		groupsPut(w, r)
-	}
 }
 
 //To retrieve a known resource, clients send GET requests to the resource endpoint, e.g., "/Users/{id}", "/Groups/{id}", or
@@ -339,7 +728,7 @@ func userGet(w http.ResponseWriter, r *http.Request) {
 	usr, ok := fromContextGetUser(ctx)
 	if !ok {
 		log.Error("can not get user from context", zap.Error(scim2.ErrUnauthorized))
-		http.Error(w, repository.ErrInvalidLogin.Error(), http.StatusForbidden)
+		writeScimError(w, scim2.ErrUnauthorized, http.StatusUnauthorized)
 		return
 	}
 	userIsAdmin := repository.ValidateResourceInGroup(usr.GetUserID(), "Admins")
@@ -347,38 +736,21 @@ func userGet(w http.ResponseWriter, r *http.Request) {
 	id := vars["id"]
 	if !userIsAdmin && strings.Compare(usr.UserID.String(), strings.TrimSpace(id)) != 0 {
 		log.Debug("user not allowed to request the given resource id", zap.String("user id", usr.UserID.String()), zap.String("resource id", id))
-		http.Error(w, repository.ErrInvalidLogin.Error(), http.StatusForbidden)
+		writeScimError(w, scim2.ErrForbidden, http.StatusForbidden)
 		return
 	}
 	user, found := repository.GetUser(uuid.FromStringOrNil(id))
 	if !found {
 		log.Debug("user not found", zap.String("user id", id))
-		//HTTP/1.1 404 Not Found
-		//{
-		//"schemas": ["urn:ietf:params:scim:api:messages:2.0:Error"],
-		//"detail":"Resource 2819c223-7f76-453a-919d-413861904646 not found",
-		//"status": "404"
-		//}
-		//Set Content-Type header so that clients will know how to read response
-		w.Header().Set("Content-Type", "application/scim+json")
-		w.WriteHeader(http.StatusNotFound)
-		//todo write correct json error
-		w.Write([]byte("{\"detail\":\"Resource not found\"}"))
+		writeScimError(w, scim2.ErrNotFound, http.StatusNotFound)
 		return
 	}
 	resource := user.GetScim()
-	//Marshal clientInfResp to json and write to response
-	resourceJson, err := json.Marshal(resource)
-	if err != nil {
-		log.Error("can not marshal resource json", zap.String("id", resource.ID), zap.Error(scim2.ErrInternalError))
-		http.Error(w, repository.ErrResourceNotAvailable.Error(), http.StatusInternalServerError)
+	if !validateScimPreconditions(w, r, resource.Metadata) {
 		return
 	}
-	//Set Content-Type header so that clients will know how to read response
-	w.Header().Set("Content-Type", "application/scim+json")
-	w.WriteHeader(http.StatusOK)
-	w.Write(resourceJson)
-	return
+	setScimETag(w, resource.Metadata)
+	writeScimProjection(w, r, resource, scim2.UserSchema)
 }
 
 func userDelete(w http.ResponseWriter, r *http.Request) {
@@ -387,52 +759,54 @@ func userDelete(w http.ResponseWriter, r *http.Request) {
 	usr, ok := fromContextGetUser(ctx)
 	if !ok {
 		log.Error("can not get user from context", zap.Error(scim2.ErrUnauthorized))
-		http.Error(w, repository.ErrInvalidLogin.Error(), http.StatusForbidden)
+		writeScimError(w, scim2.ErrUnauthorized, http.StatusUnauthorized)
 		return
 	}
 	vars := mux.Vars(r)
 	id := vars["id"]
-
+	if !repository.ValidateResourceInGroup(usr.GetUserID(), "Admins") {
+		log.Debug("user not allowed to delete users", zap.String("user id", usr.UserID.String()), zap.String("resource id", id))
+		writeScimError(w, scim2.ErrForbidden, http.StatusForbidden)
+		return
+	}
 	if strings.Compare(usr.GetUserID().String(), strings.TrimSpace(id)) == 0 {
 		log.Debug("this method can not be used for self delete")
-		http.Error(w, repository.ErrInvalidLogin.Error(), http.StatusForbidden)
+		writeScimError(w, scim2.ErrForbidden, http.StatusForbidden)
 		return
 	}
 	uid, err := uuid.FromString(id)
 	if err != nil {
 		log.Debug("wrong user id", zap.String("id", id))
-		http.Error(w, repository.ErrInvalidRequest.Error(), http.StatusBadRequest)
+		writeScimError(w, scim2.ErrNotFound, http.StatusNotFound)
+		return
+	}
+	err = repository.DeleteUser(uid, scim2.NewPreconditions(r.Header))
+	if err != nil {
+		writeScimError(w, err, getScimErrorStatus(err))
 		return
 	}
-	repository.DeleteUser(uid)
 	w.WriteHeader(http.StatusNoContent)
 	return
 }
 
 func usersGet(w http.ResponseWriter, r *http.Request) {
-	users := repository.FindUsers()
-	schema := []string{"urn:ietf:params:scim:api:messages:2.0:ListResponse"}
-	totalResults := len(users)
-	listResponse := scim2.ResourceQueryResponse{
-		Schemas:      schema,
-		TotalResults: totalResults,
+	query, projection, err := getScimListRequest(w, r)
+	if err != nil {
+		writeScimError(w, err, getScimErrorStatus(err))
+		return
 	}
-	if totalResults > 0 {
-		listResponse.Resources = users
+	users, totalResults, err := repository.FindUsers(query)
+	if err != nil {
+		writeScimError(w, scim2.ErrInternalError, http.StatusInternalServerError)
+		return
 	}
-	//Marshal to json and write to response
-	jsonResponse, err := json.Marshal(listResponse)
+	resources, err := projection.ApplyList(users, scim2.UserSchema)
 	if err != nil {
-		log.Error("can not marshal users json", zap.Error(err))
-		http.Error(w, repository.ErrInvalidLogin.Error(), http.StatusInternalServerError)
+		log.Error("can not project users", zap.Error(err))
+		writeScimError(w, scim2.ErrInternalError, http.StatusInternalServerError)
 		return
 	}
-	//Set Content-Type header so that clients will know how to read response
-	w.Header().Set("Content-Type", "application/scim+json")
-	w.WriteHeader(http.StatusOK)
-	//Write json response back to response
-	w.Write(jsonResponse)
-	return
+	writeScimListResponse(w, query, resources, totalResults)
 }
 
 func userHandler(w http.ResponseWriter, r *http.Request) {
@@ -461,10 +835,120 @@ func usersHandler(w http.ResponseWriter, r *http.Request) {
 	return
 }
 
+/**
+RFC7644 3.11. "/Me" Authenticated Subject Alias
+
+The user owning the bearer token reads and modifies its own resource as if it was requested at "/users/{id}". The token
+must be granted the scim:me scope, which clients only get if they are registered with it. The password is only changed
+if the current password is given in the header
+    X-Current-Password: t1meMa$heen
+*/
+func meHandler(w http.ResponseWriter, r *http.Request) {
+	usr, ok := fromContextGetUser(r.Context())
+	if !ok {
+		log.Debug("bearer token is not owned by an user")
+		writeScimError(w, scim2.ErrNotFound, http.StatusNotFound)
+		return
+	}
+	r = mux.SetURLVars(r, map[string]string{"id": usr.GetUserID().String()})
+	if r.Method == http.MethodPatch || r.Method == http.MethodPut {
+		body, err := getScimRequestBody(w, r)
+		if err != nil {
+			writeScimError(w, err, getScimErrorStatus(err))
+			return
+		}
+		if isPasswordModified(r.Method, body) && !validateCurrentPassword(usr, r.Header.Get(currentPasswordHeader)) {
+			log.Debug("current password not valid", zap.String("user id", usr.GetUserID().String()))
+			writeScimError(w, scim2.ErrForbidden, http.StatusForbidden)
+			return
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+	switch r.Method {
+	case http.MethodGet:
+		userGet(w, r)
+	case http.MethodPatch:
+		userPatch(w, r)
+	case http.MethodPut:
+		userPut(w, r)
+	default:
+		notImplemented(w, r)
+	}
+}
+
+//isPasswordModified returns true if the PATCH or PUT request body changes the password. Malformed bodies are
+//considered to change it, they are rejected later by the handlers anyway
+func isPasswordModified(method string, body []byte) bool {
+	if method == http.MethodPatch {
+		patch := &scim2.PatchOp{}
+		if err := json.Unmarshal(body, patch); err != nil {
+			return true
+		}
+		return patch.ModifiesPassword()
+	}
+	userReq := &scim2.User{}
+	if err := json.Unmarshal(body, userReq); err != nil {
+		return true
+	}
+	return len(userReq.Password) > 0
+}
+
+//validateCurrentPassword returns true if the password is the current password of the user
+func validateCurrentPassword(usr *repository.UserCtx, password string) bool {
+	if len(password) == 0 {
+		return false
+	}
+	_, valid := repository.ValidateUser(usr.UserName, password)
+	return valid
+}
+
 func userPatch(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	w.Write([]byte("{\"request\":\"not implemented\"}"))
-	w.WriteHeader(http.StatusNotImplemented)
+	//get user from context as it has logged in by the middleware
+	ctx := r.Context()
+	usr, ok := fromContextGetUser(ctx)
+	if !ok {
+		log.Error("can not get user from context", zap.Error(scim2.ErrUnauthorized))
+		writeScimError(w, scim2.ErrUnauthorized, http.StatusUnauthorized)
+		return
+	}
+	userIsAdmin := repository.ValidateResourceInGroup(usr.GetUserID(), "Admins")
+	vars := mux.Vars(r)
+	id := vars["id"]
+	if !userIsAdmin && strings.Compare(usr.UserID.String(), strings.TrimSpace(id)) != 0 {
+		log.Debug("user not allowed to patch the given resource id", zap.String("user id", usr.UserID.String()), zap.String("resource id", id))
+		writeScimError(w, scim2.ErrForbidden, http.StatusForbidden)
+		return
+	}
+	uid, err := uuid.FromString(id)
+	if err != nil {
+		log.Debug("wrong user id", zap.String("id", id))
+		writeScimError(w, scim2.ErrNotFound, http.StatusNotFound)
+		return
+	}
+	patch, err := getScimPatchOp(r)
+	if err != nil {
+		writeScimError(w, err, http.StatusBadRequest)
+		return
+	}
+	if !userIsAdmin && !patch.IsSelfService() {
+		log.Debug("user not allowed to patch the given attributes", zap.String("user id", usr.UserID.String()))
+		writeScimError(w, scim2.ErrForbidden, http.StatusForbidden)
+		return
+	}
+	err = repository.PatchUser(uid, patch, scim2.NewPreconditions(r.Header))
+	if err != nil {
+		writeScimError(w, err, getScimErrorStatus(err))
+		return
+	}
+	user, found := repository.GetUser(uid)
+	if !found {
+		log.Error("can not get user", zap.String("user id", id), zap.Error(repository.ErrUsernameNotFound))
+		writeScimError(w, scim2.ErrInternalError, http.StatusInternalServerError)
+		return
+	}
+	resource := user.GetScim()
+	setScimETag(w, resource.Metadata)
+	writeScimResource(w, resource)
 }
 
 func usersPost(w http.ResponseWriter, r *http.Request) {
@@ -472,19 +956,19 @@ func usersPost(w http.ResponseWriter, r *http.Request) {
 	err := json.NewDecoder(r.Body).Decode(userReq)
 	if err != nil {
 		log.Error("can not decode user", zap.String("user id", userReq.ID), zap.Error(err))
-		http.Error(w, repository.ErrInvalidRequest.Error(), http.StatusBadRequest)
+		writeScimError(w, scim2.ErrBadRequestInvalidSyntax, http.StatusBadRequest)
 		return
 	}
 	userID, err := repository.AddScimUser(userReq)
 	if err != nil {
 		log.Error("can not add user from scim", zap.String("user id", userReq.ID), zap.Error(err))
-		http.Error(w, repository.ErrInvalidRequest.Error(), http.StatusBadRequest)
+		writeScimError(w, err, getScimErrorStatus(err))
 		return
 	}
 	user, found := repository.GetUser(userID)
 	if !found {
 		log.Error("can not get user", zap.String("user id", userReq.ID), zap.Error(repository.ErrUsernameNotFound))
-		http.Error(w, repository.ErrInvalidLogin.Error(), http.StatusInternalServerError)
+		writeScimError(w, scim2.ErrInternalError, http.StatusInternalServerError)
 		return
 	}
 	scim := user.GetScim()
@@ -492,9 +976,10 @@ func usersPost(w http.ResponseWriter, r *http.Request) {
 	scimJson, err := json.Marshal(scim)
 	if err != nil {
 		log.Error("can not marshal scim json", zap.String("user id", scim.ID), zap.Error(err))
-		http.Error(w, repository.ErrInvalidLogin.Error(), http.StatusInternalServerError)
+		writeScimError(w, scim2.ErrInternalError, http.StatusInternalServerError)
 		return
 	}
+	setScimETag(w, scim.Metadata)
 	//Set Content-Type header so that clients will know how to read response
 	w.Header().Set("Content-Type", "application/scim+json")
 	w.WriteHeader(http.StatusOK)
@@ -503,31 +988,59 @@ func usersPost(w http.ResponseWriter, r *http.Request) {
 }
 
 func userPut(w http.ResponseWriter, r *http.Request) {
+	//get user from context as it has logged in by the middleware
+	ctx := r.Context()
+	usr, ok := fromContextGetUser(ctx)
+	if !ok {
+		log.Error("can not get user from context", zap.Error(scim2.ErrUnauthorized))
+		writeScimError(w, scim2.ErrUnauthorized, http.StatusUnauthorized)
+		return
+	}
+	userIsAdmin := repository.ValidateResourceInGroup(usr.GetUserID(), "Admins")
 	vars := mux.Vars(r)
 	id := vars["id"]
+	if !userIsAdmin && strings.Compare(usr.UserID.String(), strings.TrimSpace(id)) != 0 {
+		log.Debug("user not allowed to replace the given resource id", zap.String("user id", usr.UserID.String()), zap.String("resource id", id))
+		writeScimError(w, scim2.ErrForbidden, http.StatusForbidden)
+		return
+	}
 	uid, err := uuid.FromString(id)
 	if err != nil {
 		log.Debug("wrong user id", zap.String("id", id))
-		http.Error(w, repository.ErrInvalidRequest.Error(), http.StatusBadRequest)
+		writeScimError(w, scim2.ErrNotFound, http.StatusNotFound)
 		return
 	}
 	userReq := &scim2.User{}
 	err = json.NewDecoder(r.Body).Decode(userReq)
 	if err != nil {
 		log.Error("can not decode user", zap.String("user id", userReq.ID), zap.Error(err))
-		http.Error(w, repository.ErrInvalidRequest.Error(), http.StatusBadRequest)
+		writeScimError(w, scim2.ErrBadRequestInvalidSyntax, http.StatusBadRequest)
 		return
 	}
-	err = repository.ReplaceUserByScim(uid, userReq)
+	if !userIsAdmin {
+		//users only replace the attributes they can modify themselves
+		current, found := repository.GetUser(uid)
+		if !found {
+			log.Error("can not get user", zap.String("user id", id), zap.Error(repository.ErrUsernameNotFound))
+			writeScimError(w, scim2.ErrNotFound, http.StatusNotFound)
+			return
+		}
+		userReq, err = scim2.GetSelfServiceUser(current.GetScim(), userReq)
+		if err != nil {
+			writeScimError(w, err, http.StatusBadRequest)
+			return
+		}
+	}
+	err = repository.ReplaceUserByScim(uid, userReq, scim2.NewPreconditions(r.Header))
 	if err != nil {
 		log.Error("can not add user from scim", zap.String("user id", userReq.ID), zap.Error(err))
-		http.Error(w, repository.ErrInvalidRequest.Error(), http.StatusBadRequest)
+		writeScimError(w, err, getScimErrorStatus(err))
 		return
 	}
 	user, found := repository.GetUser(uid)
 	if !found {
 		log.Error("can not get user", zap.String("user id", userReq.ID), zap.Error(repository.ErrUsernameNotFound))
-		http.Error(w, repository.ErrInvalidLogin.Error(), http.StatusInternalServerError)
+		writeScimError(w, scim2.ErrInternalError, http.StatusInternalServerError)
 		return
 	}
 	scim := user.GetScim()
@@ -535,12 +1048,175 @@ func userPut(w http.ResponseWriter, r *http.Request) {
 	scimJson, err := json.Marshal(scim)
 	if err != nil {
 		log.Error("can not marshal scim json", zap.String("user id", scim.ID), zap.Error(err))
-		http.Error(w, repository.ErrInvalidLogin.Error(), http.StatusInternalServerError)
+		writeScimError(w, scim2.ErrInternalError, http.StatusInternalServerError)
 		return
 	}
+	setScimETag(w, scim.Metadata)
 	//Set Content-Type header so that clients will know how to read response
 	w.Header().Set("Content-Type", "application/scim+json")
 	w.WriteHeader(http.StatusOK)
 	w.Write(scimJson)
 	return
 }
+
+//searchResourceType is a resource type searched by the root ".search" endpoint
+type searchResourceType struct {
+	schema string
+	find   func(query *scim2.ListQuery) ([]interface{}, int, error)
+}
+
+//searchResourceTypes are the resource types of the root ".search" endpoint in the order they are paged
+var searchResourceTypes = []searchResourceType{
+	{scim2.UserSchema, func(query *scim2.ListQuery) ([]interface{}, int, error) {
+		users, total, err := repository.FindUsers(query)
+		resources := make([]interface{}, 0, len(users))
+		for i := range users {
+			resources = append(resources, &users[i])
+		}
+		return resources, total, err
+	}},
+	{scim2.GroupSchema, func(query *scim2.ListQuery) ([]interface{}, int, error) {
+		groups, total, err := repository.FindGroups(query)
+		resources := make([]interface{}, 0, len(groups))
+		for i := range groups {
+			resources = append(resources, &groups[i])
+		}
+		return resources, total, err
+	}},
+	{scim2.ClientSchema, func(query *scim2.ListQuery) ([]interface{}, int, error) {
+		clients, total, err := repository.FindClients(query)
+		resources := make([]interface{}, 0, len(clients))
+		for i := range clients {
+			resources = append(resources, &clients[i])
+		}
+		return resources, total, err
+	}},
+}
+
+//resourcesSearch searches the users, groups and clients. Without sortBy the types are paged one after the other.
+//Sorted searches get from every type the resources up to the end of the requested page and merge them
+func resourcesSearch(w http.ResponseWriter, r *http.Request) {
+	query, projection, err := getScimListRequest(w, r)
+	if err != nil {
+		writeScimError(w, err, getScimErrorStatus(err))
+		return
+	}
+	type searchEntry struct {
+		resource interface{}
+		schema   string
+	}
+	var entries []searchEntry
+	sorted := len(query.SortBy) > 0
+	totalResults := 0
+	skip := query.StartIndex - 1
+	remaining := query.Count
+	for _, resourceType := range searchResourceTypes {
+		typeQuery := *query
+		if sorted {
+			typeQuery.StartIndex = 1
+			if query.Count >= 0 {
+				typeQuery.Count = query.StartIndex - 1 + query.Count
+			}
+		} else {
+			typeQuery.StartIndex = skip + 1
+			typeQuery.Count = remaining
+		}
+		resources, total, err := resourceType.find(&typeQuery)
+		if err != nil {
+			log.Error("can not search resources", zap.String("schema", resourceType.schema), zap.Error(err))
+			writeScimError(w, scim2.ErrInternalError, http.StatusInternalServerError)
+			return
+		}
+		totalResults += total
+		if skip -= total; skip < 0 {
+			skip = 0
+		}
+		if remaining >= 0 {
+			remaining -= len(resources)
+		}
+		for _, resource := range resources {
+			entries = append(entries, searchEntry{resource: resource, schema: resourceType.schema})
+		}
+	}
+	if sorted {
+		//the resources are already filtered
+		page := scim2.NewListPage(&scim2.ListQuery{
+			SortBy:     query.SortBy,
+			SortOrder:  query.SortOrder,
+			StartIndex: query.StartIndex,
+			Count:      query.Count,
+		})
+		for i, entry := range entries {
+			page.Add(i, entry.resource)
+		}
+		keys, _ := page.Keys()
+		pageEntries := make([]searchEntry, 0, len(keys))
+		for _, key := range keys {
+			pageEntries = append(pageEntries, entries[key.(int)])
+		}
+		entries = pageEntries
+	}
+	resources := make([]map[string]interface{}, 0, len(entries))
+	for _, entry := range entries {
+		values, err := projection.Apply(entry.resource, entry.schema)
+		if err != nil {
+			log.Error("can not project resource", zap.Error(err))
+			writeScimError(w, scim2.ErrInternalError, http.StatusInternalServerError)
+			return
+		}
+		resources = append(resources, values)
+	}
+	writeScimListResponse(w, query, resources, totalResults)
+}
+
+func resourceTypeGet(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	name := vars["name"]
+	resourceType, ok := scim2.GetResourceType(name)
+	if !ok {
+		log.Debug("resource type not found", zap.String("name", name))
+		writeScimError(w, scim2.ErrNotFound, http.StatusNotFound)
+		return
+	}
+	writeScimResource(w, resourceType)
+}
+
+func resourceTypesGet(w http.ResponseWriter, r *http.Request) {
+	resourceTypes := scim2.GetResourceTypes()
+	listResponse := scim2.ResourceQueryResponse{
+		Schemas:      []string{scim2.ListResponseSchema},
+		TotalResults: len(resourceTypes),
+		StartIndex:   1,
+		ItemsPerPage: len(resourceTypes),
+		Resources:    resourceTypes,
+	}
+	writeScimResource(w, listResponse)
+}
+
+func schemaGet(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	schema, ok := scim2.GetSchema(id)
+	if !ok {
+		log.Debug("schema not found", zap.String("id", id))
+		writeScimError(w, scim2.ErrNotFound, http.StatusNotFound)
+		return
+	}
+	writeScimResource(w, schema)
+}
+
+func schemasGet(w http.ResponseWriter, r *http.Request) {
+	schemas := scim2.GetSchemas()
+	listResponse := scim2.ResourceQueryResponse{
+		Schemas:      []string{scim2.ListResponseSchema},
+		TotalResults: len(schemas),
+		StartIndex:   1,
+		ItemsPerPage: len(schemas),
+		Resources:    schemas,
+	}
+	writeScimResource(w, listResponse)
+}
+
+func serviceProviderConfigGet(w http.ResponseWriter, r *http.Request) {
+	writeScimResource(w, scim2.GetServiceProviderConfig())
+}
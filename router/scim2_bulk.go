@@ -0,0 +1,233 @@
+package router
+
+import (
+	"bounzr/iam/config"
+	"bounzr/iam/scim2"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+//bulkResponseWriter records the response of an operation of a bulk request
+type bulkResponseWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newBulkResponseWriter() *bulkResponseWriter {
+	return &bulkResponseWriter{
+		header: make(http.Header),
+		status: http.StatusOK,
+	}
+}
+
+func (w *bulkResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *bulkResponseWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+func (w *bulkResponseWriter) WriteHeader(status int) {
+	w.status = status
+}
+
+//bulkEndpoint are the handlers of the resources that can be modified by a bulk request
+type bulkEndpoint struct {
+	resources http.HandlerFunc //POST to the resources endpoint
+	resource  http.HandlerFunc //PUT, PATCH and DELETE of a single resource
+}
+
+var bulkEndpoints = map[string]bulkEndpoint{
+	"users":   {resources: usersHandler, resource: userHandler},
+	"groups":  {resources: groupsHandler, resource: groupHandler},
+	"clients": {resources: clientsHandler, resource: clientHandler},
+}
+
+//bulkPost processes the operations of a bulk request with the handlers of the resource endpoints. The request is
+//authorized as a whole, so the operations run with the permissions of the logged user
+func bulkPost(w http.ResponseWriter, r *http.Request) {
+	maxPayloadSize := config.IAM.Scim.GetBulkMaxPayloadSize()
+	if r.ContentLength > int64(maxPayloadSize) {
+		log.Debug("bulk request too large", zap.Int64("content length", r.ContentLength))
+		writeScimError(w, scim2.ErrPayloadTooLarge, http.StatusRequestEntityTooLarge)
+		return
+	}
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, int64(maxPayloadSize)))
+	if err != nil {
+		log.Debug("can not read bulk request", zap.Error(err))
+		writeScimError(w, scim2.ErrPayloadTooLarge, http.StatusRequestEntityTooLarge)
+		return
+	}
+	bulkReq := &scim2.BulkRequest{}
+	err = json.Unmarshal(body, bulkReq)
+	if err != nil {
+		log.Debug("can not decode bulk request", zap.Error(err))
+		writeScimError(w, scim2.ErrBadRequestInvalidSyntax, http.StatusBadRequest)
+		return
+	}
+	err = bulkReq.Validate(config.IAM.Scim.GetBulkMaxOperations())
+	if err == scim2.ErrPayloadTooLarge {
+		log.Debug("too many bulk operations", zap.Int("operations", len(bulkReq.Operations)))
+		writeScimError(w, err, http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		log.Debug("invalid bulk request", zap.Error(err))
+		writeScimError(w, err, http.StatusBadRequest)
+		return
+	}
+	responses := processBulkOperations(r, bulkReq)
+	bulkResp := scim2.BulkResponse{
+		Schemas:    []string{scim2.BulkResponseSchema},
+		Operations: make([]scim2.BulkOperationResponse, 0, len(responses)),
+	}
+	for _, response := range responses {
+		if response != nil {
+			bulkResp.Operations = append(bulkResp.Operations, *response)
+		}
+	}
+	writeScimResource(w, bulkResp)
+}
+
+//processBulkOperations returns the responses of the operations in request order. Operations referencing the bulkId of
+//a resource not created yet are deferred. The responses of the operations not processed because the failOnErrors
+//limit was reached are nil
+func processBulkOperations(r *http.Request, bulkReq *scim2.BulkRequest) []*scim2.BulkOperationResponse {
+	responses := make([]*scim2.BulkOperationResponse, len(bulkReq.Operations))
+	ids := make(map[string]string)
+	pending := make(map[string]struct{}) //bulkIds of the POST operations not processed yet
+	for _, operation := range bulkReq.Operations {
+		if operation.Method == http.MethodPost {
+			pending[operation.BulkID] = struct{}{}
+		}
+	}
+	errors := 0
+	for processed := true; processed; {
+		processed = false
+		for i := range bulkReq.Operations {
+			if responses[i] != nil {
+				continue
+			}
+			operation := &bulkReq.Operations[i]
+			if isBulkOperationDeferred(operation, pending) {
+				continue
+			}
+			responses[i] = processBulkOperation(r, operation, ids)
+			delete(pending, operation.BulkID)
+			processed = true
+			if status, _ := strconv.Atoi(responses[i].Status); status >= http.StatusBadRequest {
+				errors++
+				if bulkReq.FailOnErrors > 0 && errors >= bulkReq.FailOnErrors {
+					return responses
+				}
+			}
+		}
+	}
+	//the remaining operations have circular references
+	for i, operation := range bulkReq.Operations {
+		if responses[i] == nil {
+			log.Debug("can not resolve bulkId references", zap.String("path", operation.Path))
+			responses[i] = newBulkErrorResponse(&operation, scim2.NewErrorResponse(http.StatusConflict, scim2.ErrBadRequestInvalidValue))
+		}
+	}
+	return responses
+}
+
+//isBulkOperationDeferred returns true if the operation references a resource created by a pending operation
+func isBulkOperationDeferred(operation *scim2.BulkOperation, pending map[string]struct{}) bool {
+	for _, bulkID := range operation.GetBulkIDReferences() {
+		if _, found := pending[bulkID]; found && bulkID != operation.BulkID {
+			return true
+		}
+	}
+	return false
+}
+
+func processBulkOperation(r *http.Request, operation *scim2.BulkOperation, ids map[string]string) *scim2.BulkOperationResponse {
+	path, data, err := operation.ResolveBulkIDs(ids)
+	if err != nil {
+		log.Debug("can not resolve bulkId references", zap.String("path", operation.Path), zap.Error(err))
+		return newBulkErrorResponse(operation, scim2.NewErrorResponse(http.StatusConflict, err))
+	}
+	handler, vars, err := getBulkHandler(operation.Method, path)
+	if err != nil {
+		log.Debug("invalid bulk operation path", zap.String("method", operation.Method), zap.String("path", path))
+		return newBulkErrorResponse(operation, scim2.NewErrorResponse(http.StatusBadRequest, err))
+	}
+	req, err := http.NewRequest(operation.Method, path, bytes.NewReader(data))
+	if err != nil {
+		return newBulkErrorResponse(operation, scim2.NewErrorResponse(http.StatusBadRequest, scim2.ErrBadRequestInvalidPath))
+	}
+	req = mux.SetURLVars(req.WithContext(r.Context()), vars)
+	req.Header.Set("Content-Type", "application/scim+json")
+	if len(operation.Version) > 0 {
+		//the version of a bulk operation is the entity tag the resource must match
+		req.Header.Set("If-Match", operation.Version)
+	}
+	writer := newBulkResponseWriter()
+	handler(writer, req)
+	if writer.status >= http.StatusBadRequest {
+		return newBulkErrorResponse(operation, getScimErrorResponse(writer.status, writer.body.Bytes()))
+	}
+	response := &scim2.BulkOperationResponse{
+		Location: fmt.Sprintf("https://%s/scim2%s", config.IAM.Server.Hostname, path),
+		Method:   operation.Method,
+		BulkID:   operation.BulkID,
+		Status:   strconv.Itoa(writer.status),
+	}
+	if operation.Method == http.MethodPost {
+		//RFC7644 3.7.3. the status of the operations creating a resource is 201
+		response.Status = strconv.Itoa(http.StatusCreated)
+	}
+	resource := &scim2.Resource{}
+	if writer.body.Len() > 0 && json.Unmarshal(writer.body.Bytes(), resource) == nil {
+		if operation.Method == http.MethodPost {
+			ids[operation.BulkID] = resource.ID
+			response.Location = fmt.Sprintf("%s/%s", response.Location, resource.ID)
+		}
+		if len(resource.Metadata.Location) > 0 {
+			response.Location = resource.Metadata.Location
+		}
+		response.Version = resource.Metadata.Version
+	}
+	return response
+}
+
+//getBulkHandler returns the handler and the route variables of the operation. POST operations target a resources
+//endpoint, e.g. "/Users", and the other operations a resource, e.g. "/Users/{id}"
+func getBulkHandler(method string, path string) (http.HandlerFunc, map[string]string, error) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	endpoint, found := bulkEndpoints[strings.ToLower(segments[0])]
+	if !found || len(segments) > 2 {
+		return nil, nil, scim2.ErrBadRequestInvalidPath
+	}
+	if method == http.MethodPost {
+		if len(segments) != 1 {
+			return nil, nil, scim2.ErrBadRequestInvalidPath
+		}
+		return endpoint.resources, map[string]string{}, nil
+	}
+	if len(segments) != 2 || len(segments[1]) == 0 {
+		return nil, nil, scim2.ErrBadRequestInvalidPath
+	}
+	return endpoint.resource, map[string]string{"id": segments[1]}, nil
+}
+
+//newBulkErrorResponse returns the response of a failed operation, with the status of the error response
+func newBulkErrorResponse(operation *scim2.BulkOperation, errorResponse *scim2.ErrorResponse) *scim2.BulkOperationResponse {
+	return &scim2.BulkOperationResponse{
+		Method:   operation.Method,
+		BulkID:   operation.BulkID,
+		Status:   errorResponse.Status,
+		Response: errorResponse,
+	}
+}
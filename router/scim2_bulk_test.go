@@ -0,0 +1,162 @@
+package router
+
+import (
+	"bounzr/iam/scim2"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+//bulkTestEndpoint fakes the users endpoints. The users are created with version 1 and replaced with version 2
+type bulkTestEndpoint struct {
+	created   int
+	processed []string //bulkIds of the POST operations and ids of the other operations in processing order
+	ifMatch   []string //If-Match headers of the operations on a single resource
+}
+
+func (e *bulkTestEndpoint) resources(w http.ResponseWriter, r *http.Request) {
+	data := make(map[string]interface{})
+	json.NewDecoder(r.Body).Decode(&data)
+	e.processed = append(e.processed, fmt.Sprint(data["userName"]))
+	if data["fail"] == true {
+		writeScimError(w, scim2.ErrBadRequestInvalidValue, http.StatusBadRequest)
+		return
+	}
+	e.created++
+	id := fmt.Sprintf("%d", e.created)
+	writeScimResource(w, map[string]interface{}{
+		"id":      id,
+		"manager": data["manager"],
+		"meta":    &scim2.Metadata{Location: "https://localhost/scim2/users/" + id, Version: scim2.GetETag(1)},
+	})
+}
+
+func (e *bulkTestEndpoint) resource(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	e.processed = append(e.processed, id)
+	e.ifMatch = append(e.ifMatch, r.Header.Get("If-Match"))
+	if err := scim2.NewPreconditions(r.Header).Validate(scim2.GetETag(1)); err != nil {
+		writeScimError(w, err, getScimErrorStatus(err))
+		return
+	}
+	writeScimResource(w, map[string]interface{}{
+		"id":   id,
+		"meta": &scim2.Metadata{Location: "https://localhost/scim2/users/" + id, Version: scim2.GetETag(2)},
+	})
+}
+
+func executeBulkTest(test func(endpoint *bulkTestEndpoint)) {
+	log, _ = zap.NewDevelopment()
+	defer func(endpoints map[string]bulkEndpoint) { bulkEndpoints = endpoints }(bulkEndpoints)
+	endpoint := &bulkTestEndpoint{}
+	bulkEndpoints = map[string]bulkEndpoint{"users": {resources: endpoint.resources, resource: endpoint.resource}}
+	test(endpoint)
+}
+
+func newBulkTestRequest(t *testing.T, data string) *scim2.BulkRequest {
+	bulkReq := &scim2.BulkRequest{}
+	if err := json.Unmarshal([]byte(data), bulkReq); err != nil {
+		t.Fatalf("can not decode bulk request: %s", err.Error())
+	}
+	return bulkReq
+}
+
+func TestBulkDeferredOperations(t *testing.T) {
+	test := func(endpoint *bulkTestEndpoint) {
+		bulkReq := newBulkTestRequest(t, `{"Operations":[
+			{"method":"POST","bulkId":"bjensen","path":"/Users","data":{"userName":"bjensen","manager":"bulkId:jsmith"}},
+			{"method":"PATCH","path":"/Users/bulkId:bjensen","data":{}},
+			{"method":"POST","bulkId":"jsmith","path":"/Users","data":{"userName":"jsmith"}}]}`)
+		responses := processBulkOperations(httptest.NewRequest(http.MethodPost, "/scim2/Bulk", nil), bulkReq)
+		if strings.Join(endpoint.processed, ",") != "jsmith,bjensen,2" {
+			t.Errorf("want operations referencing later bulkIds deferred, got order %v", endpoint.processed)
+		}
+		if len(responses) != 3 || responses[0].BulkID != "bjensen" || responses[2].BulkID != "jsmith" {
+			t.Fatalf("want responses in request order, got %+v", responses)
+		}
+		//POST operations report 201 and the location of the created resource
+		if responses[0].Status != "201" || responses[0].Location != "https://localhost/scim2/users/2" || responses[0].Version != `W/"1"` {
+			t.Errorf("want user bjensen created, got %+v", responses[0])
+		}
+		if responses[1].Status != "200" || responses[1].Version != `W/"2"` {
+			t.Errorf("want user bjensen patched, got %+v", responses[1])
+		}
+	}
+	executeBulkTest(test)
+}
+
+func TestBulkCircularReferences(t *testing.T) {
+	test := func(endpoint *bulkTestEndpoint) {
+		bulkReq := newBulkTestRequest(t, `{"Operations":[
+			{"method":"POST","bulkId":"bjensen","path":"/Users","data":{"userName":"bjensen","manager":"bulkId:jsmith"}},
+			{"method":"POST","bulkId":"jsmith","path":"/Users","data":{"userName":"jsmith","manager":"bulkId:bjensen"}},
+			{"method":"POST","bulkId":"adoe","path":"/Users","data":{"userName":"adoe"}}]}`)
+		responses := processBulkOperations(httptest.NewRequest(http.MethodPost, "/scim2/Bulk", nil), bulkReq)
+		if len(endpoint.processed) != 1 || responses[2].Status != "201" {
+			t.Errorf("want only the operation without references processed, got %v", endpoint.processed)
+		}
+		for _, response := range responses[:2] {
+			if response.Status != "409" {
+				t.Errorf("want circular reference of %s rejected, got status %s", response.BulkID, response.Status)
+			}
+		}
+	}
+	executeBulkTest(test)
+}
+
+func TestBulkFailOnErrors(t *testing.T) {
+	test := func(endpoint *bulkTestEndpoint) {
+		bulkReq := newBulkTestRequest(t, `{"failOnErrors":1,"Operations":[
+			{"method":"POST","bulkId":"bjensen","path":"/Users","data":{"userName":"bjensen"}},
+			{"method":"POST","bulkId":"jsmith","path":"/Users","data":{"userName":"jsmith","fail":true}},
+			{"method":"POST","bulkId":"adoe","path":"/Users","data":{"userName":"adoe"}}]}`)
+		responses := processBulkOperations(httptest.NewRequest(http.MethodPost, "/scim2/Bulk", nil), bulkReq)
+		if responses[0].Status != "201" || responses[1].Status != "400" || responses[2] != nil {
+			t.Errorf("want operations stopped after the first error, got %+v", responses)
+		}
+		if len(endpoint.processed) != 2 {
+			t.Errorf("want 2 operations processed, got %v", endpoint.processed)
+		}
+		//the error response of the handler is forwarded
+		if errorResponse, ok := responses[1].Response.(*scim2.ErrorResponse); !ok || errorResponse.ScimType != "invalidValue" {
+			t.Errorf("want invalidValue error response, got %+v", responses[1].Response)
+		}
+		//the remaining operations are not reported
+		w := httptest.NewRecorder()
+		body := `{"schemas":["` + scim2.BulkRequestSchema + `"],"failOnErrors":1,"Operations":[
+			{"method":"POST","bulkId":"jsmith","path":"/Users","data":{"userName":"jsmith","fail":true}},
+			{"method":"POST","bulkId":"adoe","path":"/Users","data":{"userName":"adoe"}}]}`
+		bulkPost(w, httptest.NewRequest(http.MethodPost, "/scim2/Bulk", strings.NewReader(body)))
+		bulkResp := &scim2.BulkResponse{}
+		if err := json.NewDecoder(w.Body).Decode(bulkResp); err != nil || w.Code != http.StatusOK {
+			t.Fatalf("want bulk response, got status %d", w.Code)
+		}
+		if len(bulkResp.Operations) != 1 || bulkResp.Operations[0].Status != "400" {
+			t.Errorf("want only the failed operation reported, got %+v", bulkResp.Operations)
+		}
+	}
+	executeBulkTest(test)
+}
+
+func TestBulkVersion(t *testing.T) {
+	test := func(endpoint *bulkTestEndpoint) {
+		bulkReq := newBulkTestRequest(t, `{"Operations":[
+			{"method":"PUT","path":"/Users/1","version":"W/\"1\"","data":{"userName":"bjensen"}},
+			{"method":"DELETE","path":"/Users/1","version":"W/\"3\""},
+			{"method":"PATCH","path":"/Users/1","data":{}}]}`)
+		responses := processBulkOperations(httptest.NewRequest(http.MethodPost, "/scim2/Bulk", nil), bulkReq)
+		//the version of an operation is the If-Match header of its request
+		if strings.Join(endpoint.ifMatch, ",") != `W/"1",W/"3",` {
+			t.Errorf("want versions sent as If-Match headers, got %v", endpoint.ifMatch)
+		}
+		if responses[0].Status != "200" || responses[1].Status != "412" || responses[2].Status != "200" {
+			t.Errorf("want stale version rejected, got %+v", responses)
+		}
+	}
+	executeBulkTest(test)
+}
@@ -0,0 +1,75 @@
+package router
+
+import (
+	"bounzr/iam/repository"
+	"bounzr/iam/scim2"
+	"encoding/json"
+	"errors"
+	"go.uber.org/zap"
+	"net/http"
+	"strings"
+)
+
+//scimErrors are the SCIM errors of the repository errors returned by the SCIM endpoints
+var scimErrors = map[error]error{
+	repository.ErrUsernameNotAvailable: scim2.ErrBadRequestUniqueness,
+}
+
+//writeScimError writes the RFC7644 3.12. json error response of the error
+func writeScimError(w http.ResponseWriter, err error, status int) {
+	if scimErr, found := scimErrors[err]; found {
+		err = scimErr
+	}
+	response, jsonErr := json.Marshal(scim2.NewErrorResponse(status, err))
+	if jsonErr != nil {
+		log.Error("can not marshal scim error json", zap.Error(jsonErr))
+		http.Error(w, err.Error(), status)
+		return
+	}
+	//Set Content-Type header so that clients will know how to read response
+	w.Header().Set("Content-Type", "application/scim+json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+//getScimErrorResponse returns the json error response written by a handler, or the error response of the status if
+//the response is plain text
+func getScimErrorResponse(status int, response []byte) *scim2.ErrorResponse {
+	errorResponse := &scim2.ErrorResponse{}
+	if err := json.Unmarshal(response, errorResponse); err == nil && len(errorResponse.Status) > 0 {
+		return errorResponse
+	}
+	return scim2.NewErrorResponse(status, errors.New(strings.TrimSpace(string(response))))
+}
+
+//scimErrorWriter writes the plain text errors of the shared middlewares as SCIM json error responses
+type scimErrorWriter struct {
+	http.ResponseWriter
+	status int //status of the plain text error, 0 if no error was written
+}
+
+func (w *scimErrorWriter) WriteHeader(status int) {
+	if status >= http.StatusBadRequest && strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain") {
+		w.status = status
+		return
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *scimErrorWriter) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		return w.ResponseWriter.Write(data)
+	}
+	w.Header().Del("X-Content-Type-Options")
+	writeScimError(w.ResponseWriter, errors.New(strings.TrimSpace(string(data))), w.status)
+	w.status = 0
+	return len(data), nil
+}
+
+//scimErrorHandling wraps the authentication and authorization middlewares of the SCIM endpoints so that their
+//failures are returned as SCIM errors
+var scimErrorHandling = func(f http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		f(&scimErrorWriter{ResponseWriter: w}, r)
+	}
+}
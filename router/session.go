@@ -24,6 +24,11 @@ func addTargetURLToSession(w http.ResponseWriter, r *http.Request) error {
 func authenticateClient(clientID, clientSecret string) (clientCtx *oauth2.ClientCtx, ok bool) {
 	client, found := repository.GetClient(uuid.FromStringOrNil(clientID))
 	if found {
+		//public clients are identified only by their client_id
+		if client.IsPublic() && len(clientSecret) == 0 {
+			log.Debug("public client authenticated", zap.String("client", clientID))
+			return client.GetClientCtx(), true
+		}
 		if ok = client.ValidateClientSecret(clientSecret); ok {
 			return client.GetClientCtx(), ok
 		}
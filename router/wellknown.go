@@ -0,0 +1,68 @@
+package router
+
+import (
+	"bounzr/iam/config"
+	"bounzr/iam/oauth2"
+	"bounzr/iam/repository"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+//newWellKnownRouter returns router with the server metadata documents built from the routes registered in root
+func newWellKnownRouter(router *mux.Router, root *mux.Router) {
+	metadataHandler := func(w http.ResponseWriter, r *http.Request) {
+		serverMetadataHandler(w, r, root)
+	}
+	router.HandleFunc("/oauth-authorization-server", metadataHandler).Methods(http.MethodGet)
+	router.HandleFunc("/openid-configuration", metadataHandler).Methods(http.MethodGet)
+}
+
+//getRouteURL returns the absolute URL of the named route or an empty string if it is not registered
+func getRouteURL(root *mux.Router, name string) string {
+	route := root.Get(name)
+	if route == nil {
+		return ""
+	}
+	path, err := route.URLPath()
+	if err != nil {
+		log.Error("can not get route path", zap.String("route", name), zap.Error(err))
+		return ""
+	}
+	return config.IAM.Server.GetIssuer() + path.String()
+}
+
+func getServerMetadata(root *mux.Router) *oauth2.ServerMetadata {
+	return &oauth2.ServerMetadata{
+		Issuer:                            config.IAM.Server.GetIssuer(),
+		AuthorizationEndpoint:             getRouteURL(root, authorizeRoute),
+		TokenEndpoint:                     getRouteURL(root, tokenRoute),
+		UserInfoEndpoint:                  getRouteURL(root, userInfoRoute),
+		JwksURI:                           getRouteURL(root, jwksRoute),
+		RegistrationEndpoint:              getRouteURL(root, registerRoute),
+		RevocationEndpoint:                getRouteURL(root, revokeRoute),
+		IntrospectionEndpoint:             getRouteURL(root, introspectRoute),
+		ScopesSupported:                   oauth2.GetScopesSupported(),
+		ResponseTypesSupported:            oauth2.GetResponseTypes(),
+		ResponseModesSupported:            []string{"query", "fragment"},
+		GrantTypesSupported:               oauth2.GetSupportedGrantTypes(),
+		TokenEndpointAuthMethodsSupported: oauth2.GetTokenEndpointAuthMethods(),
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  repository.GetSigningAlgorithms(),
+		ClaimsSupported:                   oauth2.GetClaimsSupported(),
+		CodeChallengeMethodsSupported:     oauth2.GetCodeChallengeMethods(),
+	}
+}
+
+func serverMetadataHandler(w http.ResponseWriter, r *http.Request, root *mux.Router) {
+	metadataJSON, err := json.Marshal(getServerMetadata(root))
+	if err != nil {
+		log.Error("can not marshal server metadata", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(metadataJSON)
+}
@@ -0,0 +1,175 @@
+package scim2
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+/**
+RFC7644 3.7. Bulk Operations
+
+Operations are processed in order, except that an operation referencing the "bulkId" of a resource created later in the
+same request is deferred until that resource exists. References are written as "bulkId:<bulkId>" and are resolved in
+the path and in any string value of the data.
+*/
+
+const (
+	BulkRequestSchema  = "urn:ietf:params:scim:api:messages:2.0:BulkRequest"
+	BulkResponseSchema = "urn:ietf:params:scim:api:messages:2.0:BulkResponse"
+	bulkIDPrefix       = "bulkId:"
+)
+
+//BulkRequest is a SCIM bulk request
+type BulkRequest struct {
+	Schemas      []string        `json:"schemas"`
+	FailOnErrors int             `json:"failOnErrors,omitempty"` //number of errors after which the remaining operations are not processed
+	Operations   []BulkOperation `json:"Operations"`
+}
+
+//BulkOperation is a POST, PUT, PATCH or DELETE request of a bulk request
+type BulkOperation struct {
+	Method  string          `json:"method"`
+	BulkID  string          `json:"bulkId,omitempty"` //transient identifier of a resource created by a POST operation
+	Version string          `json:"version,omitempty"`
+	Path    string          `json:"path"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+//BulkResponse is the response of a bulk request
+type BulkResponse struct {
+	Schemas    []string                `json:"schemas"`
+	Operations []BulkOperationResponse `json:"Operations"`
+}
+
+//BulkOperationResponse is the result of an operation of a bulk request
+type BulkOperationResponse struct {
+	Location string      `json:"location,omitempty"`
+	Method   string      `json:"method"`
+	BulkID   string      `json:"bulkId,omitempty"`
+	Version  string      `json:"version,omitempty"`
+	Status   string      `json:"status"`
+	Response interface{} `json:"response,omitempty"`
+}
+
+//Validate verifies the request has at most maxOperations valid operations
+func (b *BulkRequest) Validate(maxOperations int) error {
+	if len(b.Operations) > maxOperations {
+		return ErrPayloadTooLarge
+	}
+	if !b.hasSchema() || len(b.Operations) == 0 {
+		return ErrBadRequestInvalidSyntax
+	}
+	bulkIDs := make(map[string]struct{})
+	for _, operation := range b.Operations {
+		switch operation.Method {
+		case http.MethodPost:
+			//the bulkId is required to identify the resource created in the response
+			if len(operation.BulkID) == 0 {
+				return ErrBadRequestInvalidValue
+			}
+			if _, found := bulkIDs[operation.BulkID]; found {
+				return ErrBadRequestUniqueness
+			}
+			bulkIDs[operation.BulkID] = struct{}{}
+		case http.MethodPut, http.MethodPatch, http.MethodDelete:
+		default:
+			return ErrBadRequestInvalidValue
+		}
+		if len(strings.TrimSpace(operation.Path)) == 0 {
+			return ErrBadRequestInvalidPath
+		}
+	}
+	return nil
+}
+
+func (b *BulkRequest) hasSchema() bool {
+	for _, schema := range b.Schemas {
+		if schema == BulkRequestSchema {
+			return true
+		}
+	}
+	return false
+}
+
+//GetBulkIDReferences returns the bulkIds referenced in the path and data of the operation
+func (o *BulkOperation) GetBulkIDReferences() []string {
+	references := make([]string, 0)
+	if bulkID, ok := getBulkIDReference(o.Path); ok {
+		references = append(references, bulkID)
+	}
+	var data interface{}
+	if len(o.Data) > 0 && json.Unmarshal(o.Data, &data) == nil {
+		walkStrings(data, func(s string) string {
+			if bulkID, ok := getBulkIDReference(s); ok {
+				references = append(references, bulkID)
+			}
+			return s
+		})
+	}
+	return references
+}
+
+//ResolveBulkIDs returns the path and data of the operation with the bulkId references replaced by the ids of the
+//resources in ids. ErrBadRequestInvalidValue is returned if a reference can not be resolved
+func (o *BulkOperation) ResolveBulkIDs(ids map[string]string) (string, []byte, error) {
+	resolved := true
+	resolve := func(s string) string {
+		bulkID, ok := getBulkIDReference(s)
+		if !ok {
+			return s
+		}
+		id, found := ids[bulkID]
+		if !found {
+			resolved = false
+			return s
+		}
+		return strings.Replace(s, bulkIDPrefix+bulkID, id, 1)
+	}
+	path := resolve(o.Path)
+	data := []byte(o.Data)
+	if len(o.Data) > 0 {
+		var value interface{}
+		if err := json.Unmarshal(o.Data, &value); err != nil {
+			return "", nil, ErrBadRequestInvalidSyntax
+		}
+		value = walkStrings(value, resolve)
+		var err error
+		data, err = json.Marshal(value)
+		if err != nil {
+			return "", nil, ErrBadRequestInvalidSyntax
+		}
+	}
+	if !resolved {
+		return "", nil, ErrBadRequestInvalidValue
+	}
+	return path, data, nil
+}
+
+//getBulkIDReference returns the bulkId referenced by the value, which is either the reference or a path ending with it
+func getBulkIDReference(value string) (string, bool) {
+	if i := strings.LastIndex(value, "/"); i >= 0 {
+		value = value[i+1:]
+	}
+	if !strings.HasPrefix(value, bulkIDPrefix) || len(value) == len(bulkIDPrefix) {
+		return "", false
+	}
+	return value[len(bulkIDPrefix):], true
+}
+
+//walkStrings replaces every string of the json value by the result of f
+func walkStrings(value interface{}, f func(string) string) interface{} {
+	switch v := value.(type) {
+	case string:
+		return f(v)
+	case []interface{}:
+		for i := range v {
+			v[i] = walkStrings(v[i], f)
+		}
+	case map[string]interface{}:
+		for key := range v {
+			v[key] = walkStrings(v[key], f)
+		}
+	}
+	return value
+}
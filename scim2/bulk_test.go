@@ -0,0 +1,68 @@
+package scim2
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+type BulkValidateDataProvider struct {
+	request string
+	err     error
+}
+
+var bulkValidateDataProvider = []BulkValidateDataProvider{
+	{`{"schemas":["urn:ietf:params:scim:api:messages:2.0:BulkRequest"],"Operations":[{"method":"POST","bulkId":"q1","path":"/Users","data":{}},{"method":"DELETE","path":"/Users/b7c14771"}]}`, nil},
+	{`{"schemas":[],"Operations":[{"method":"DELETE","path":"/Users/b7c14771"}]}`, ErrBadRequestInvalidSyntax},
+	{`{"schemas":["urn:ietf:params:scim:api:messages:2.0:BulkRequest"],"Operations":[]}`, ErrBadRequestInvalidSyntax},
+	{`{"schemas":["urn:ietf:params:scim:api:messages:2.0:BulkRequest"],"Operations":[{"method":"POST","path":"/Users","data":{}}]}`, ErrBadRequestInvalidValue},
+	{`{"schemas":["urn:ietf:params:scim:api:messages:2.0:BulkRequest"],"Operations":[{"method":"POST","bulkId":"q1","path":"/Users"},{"method":"POST","bulkId":"q1","path":"/Users"}]}`, ErrBadRequestUniqueness},
+	{`{"schemas":["urn:ietf:params:scim:api:messages:2.0:BulkRequest"],"Operations":[{"method":"GET","path":"/Users/b7c14771"}]}`, ErrBadRequestInvalidValue},
+	{`{"schemas":["urn:ietf:params:scim:api:messages:2.0:BulkRequest"],"Operations":[{"method":"DELETE","path":""}]}`, ErrBadRequestInvalidPath},
+	{`{"schemas":["urn:ietf:params:scim:api:messages:2.0:BulkRequest"],"Operations":[{"method":"DELETE","path":"/Users/1"},{"method":"DELETE","path":"/Users/2"},{"method":"DELETE","path":"/Users/3"}]}`, ErrPayloadTooLarge},
+}
+
+func TestBulkRequestValidate(t *testing.T) {
+	for _, provider := range bulkValidateDataProvider {
+		request := &BulkRequest{}
+		if err := json.Unmarshal([]byte(provider.request), request); err != nil {
+			t.Fatalf("%s: can not decode request: %s", provider.request, err.Error())
+		}
+		if err := request.Validate(2); err != provider.err {
+			t.Errorf("%s: want %v, got %v", provider.request, provider.err, err)
+		}
+	}
+}
+
+func TestResolveBulkIDs(t *testing.T) {
+	operation := &BulkOperation{
+		Method: "PATCH",
+		Path:   "/Groups/bulkId:group",
+		Data:   json.RawMessage(`{"Operations":[{"op":"add","path":"members","value":[{"value":"bulkId:user1"},{"value":"bulkId:user2"},{"value":"bulkId:"}]}]}`),
+	}
+	references := operation.GetBulkIDReferences()
+	if len(references) != 3 {
+		t.Fatalf("want 3 references, got %v", references)
+	}
+	ids := map[string]string{"group": "g1", "user1": "u1"}
+	if _, _, err := operation.ResolveBulkIDs(ids); err != ErrBadRequestInvalidValue {
+		t.Errorf("unresolved reference: want %v, got %v", ErrBadRequestInvalidValue, err)
+	}
+	ids["user2"] = "u2"
+	path, data, err := operation.ResolveBulkIDs(ids)
+	if err != nil {
+		t.Fatalf("want no error, got %s", err.Error())
+	}
+	if path != "/Groups/g1" {
+		t.Errorf("path: want /Groups/g1, got %s", path)
+	}
+	patch := &PatchOp{}
+	if err := json.Unmarshal(data, patch); err != nil {
+		t.Fatalf("can not decode data: %s", err.Error())
+	}
+	members := patch.Operations[0].Value.([]interface{})
+	for i, want := range []string{"u1", "u2", "bulkId:"} {
+		if value := members[i].(map[string]interface{})["value"]; value != want {
+			t.Errorf("member %d: want %s, got %v", i, want, value)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package scim2
 
 type Client struct {
+	AccessTokenFormat       string            `json:"accessTokenFormat,omitempty"`
 	Name                    string            `json:"clientName,omitempty"`
 	PasswordExpiresAt       int64             `json:"clientSecretExpiresAt,omitempty"`
 	URI                     string            `json:"clientUri,omitempty"`
@@ -15,6 +16,7 @@ type Client struct {
 	Password                string            `json:"password,omitempty"`
 	PolicyUri               string            `json:"policyUri,omitempty"`
 	RedirectUris            []string          `json:"redirectUris"`
+	RequirePkce             bool              `json:"requirePkce,omitempty"`
 	ResponseTypes           []string          `json:"responseTypes,omitempty"`
 	Schemas                 []string          `json:"schemas,omitempty"`
 	Scope                   string            `json:"scope,omitempty"`
@@ -1,16 +1,28 @@
 package scim2
 
+/**
+RFC7643 4.3. Enterprise User Schema Extension
+
+The extension is returned as the value of the schema URI attribute of the user, e.g.
+	"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User": {"department": "Sales"}
+*/
+
+const (
+	EnterpriseUserSchema = "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"
+)
+
 type EnterpriseUser struct {
-	EmployeeNumber string
-	CostCenter     string
-	Organization   string
-	Division       string
-	Department     string
-	Manager        Manager
+	EmployeeNumber string   `json:"employeeNumber,omitempty"`
+	CostCenter     string   `json:"costCenter,omitempty"`
+	Organization   string   `json:"organization,omitempty"`
+	Division       string   `json:"division,omitempty"`
+	Department     string   `json:"department,omitempty"`
+	Manager        *Manager `json:"manager,omitempty"`
 }
 
+//Manager references the user who is the manager. Only the value is stored, the $ref and displayName are read only
 type Manager struct {
-	DisplayName string
-	Ref         string
-	Value       string
+	DisplayName string `json:"displayName,omitempty"`
+	Ref         string `json:"$ref,omitempty"`
+	Value       string `json:"value,omitempty"`
 }
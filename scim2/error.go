@@ -1,6 +1,9 @@
 package scim2
 
-import "errors"
+import (
+	"errors"
+	"strconv"
+)
 
 var (
 	ErrBadRequest = errors.New("request is unparsable or violates schema")
@@ -8,6 +11,7 @@ var (
 	ErrForbidden = errors.New("operation not permitted based on the supplied authorization")
 	ErrNotFound = errors.New("specified resource or endpoint does not exist")
 	ErrConflict = errors.New("the specified version number does match")
+	ErrNotModified = errors.New("the resource has not been modified since the specified version")
 	ErrPreconditionFailed = errors.New("failed to update as resource has changed on the server")
 	ErrPayloadTooLarge = errors.New("max operations or max payload size exceeded")
 	ErrInternalError = errors.New("internal error")
@@ -23,3 +27,40 @@ var (
 	ErrBadRequestInvalidVers = errors.New("the specified SCIM protocol version is not supported")
 	ErrBadRequestSensitive = errors.New("the specified request cannot be completed due to the passing of sensitive information in a request uri")
 	)
+
+const (
+	ErrorSchema = "urn:ietf:params:scim:api:messages:2.0:Error"
+)
+
+//ErrorResponse is the json body of an error response
+type ErrorResponse struct {
+	Schemas  []string `json:"schemas"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail,omitempty"`
+	Status   string   `json:"status"`
+}
+
+//scimTypes are the RFC7644 3.12. detail error keywords of the bad request errors
+var scimTypes = map[error]string{
+	ErrBadRequestInvalidFilter: "invalidFilter",
+	ErrBadRequestTooMany:       "tooMany",
+	ErrBadRequestUniqueness:    "uniqueness",
+	ErrBadRequestMutability:    "mutability",
+	ErrBadRequestInvalidSyntax: "invalidSyntax",
+	ErrBadRequestInvalidPath:   "invalidPath",
+	ErrBadRequestNoTarget:      "noTarget",
+	ErrBadRequestInvalidValue:  "invalidValue",
+	ErrBadRequestInvalidVers:   "invalidVers",
+	ErrBadRequestSensitive:     "sensitive",
+}
+
+//NewErrorResponse returns the error response of the http status and the error. The scimType is set for the bad
+//request errors
+func NewErrorResponse(status int, err error) *ErrorResponse {
+	return &ErrorResponse{
+		Schemas:  []string{ErrorSchema},
+		ScimType: scimTypes[err],
+		Detail:   err.Error(),
+		Status:   strconv.Itoa(status),
+	}
+}
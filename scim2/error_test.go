@@ -0,0 +1,40 @@
+package scim2
+
+import (
+	"errors"
+	"testing"
+)
+
+type ErrorResponseDataProvider struct {
+	status   int
+	err      error
+	scimType string
+}
+
+var errorResponseDataProvider = []ErrorResponseDataProvider{
+	{400, ErrBadRequestInvalidFilter, "invalidFilter"},
+	{400, ErrBadRequestTooMany, "tooMany"},
+	{409, ErrBadRequestUniqueness, "uniqueness"},
+	{400, ErrBadRequestMutability, "mutability"},
+	{400, ErrBadRequestInvalidSyntax, "invalidSyntax"},
+	{400, ErrBadRequestInvalidPath, "invalidPath"},
+	{400, ErrBadRequestNoTarget, "noTarget"},
+	{400, ErrBadRequestInvalidValue, "invalidValue"},
+	{400, ErrBadRequestInvalidVers, "invalidVers"},
+	{400, ErrBadRequestSensitive, "sensitive"},
+	{404, ErrNotFound, ""},
+	{412, ErrPreconditionFailed, ""},
+	{400, errors.New(ErrBadRequestInvalidValue.Error()), ""},
+}
+
+func TestNewErrorResponse(t *testing.T) {
+	for _, provider := range errorResponseDataProvider {
+		response := NewErrorResponse(provider.status, provider.err)
+		if response.ScimType != provider.scimType {
+			t.Errorf("%s: want scimType %q, got %q", provider.err.Error(), provider.scimType, response.ScimType)
+		}
+		if len(response.Schemas) != 1 || response.Schemas[0] != ErrorSchema || response.Detail != provider.err.Error() {
+			t.Errorf("%s: want error response, got %+v", provider.err.Error(), response)
+		}
+	}
+}
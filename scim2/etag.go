@@ -0,0 +1,78 @@
+package scim2
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+/**
+RFC7644 3.14. Versioning Resources
+
+The version of a resource is returned as the weak entity tag of the "ETag" header and of the "meta.version" attribute.
+Clients use it in the "If-Match" and "If-None-Match" headers so that concurrent modifications of a resource are
+detected instead of silently overwritten.
+*/
+
+//GetETag returns the weak entity tag of the resource version
+func GetETag(version uint64) string {
+	return fmt.Sprintf("W/\"%d\"", version)
+}
+
+//MatchETag returns true if the list of entity tags of the If-Match or If-None-Match header contains the entity tag
+//or is "*". The weak comparison function is used
+func MatchETag(header string, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || (len(tag) > 0 && strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(etag, "W/")) {
+			return true
+		}
+	}
+	return false
+}
+
+//Preconditions are the If-Match and If-None-Match headers of a request that modifies a resource. The repositories
+//validate them against the current version of the resource in the same critical section as the write
+type Preconditions struct {
+	IfMatch     string
+	IfNoneMatch string
+}
+
+//NewPreconditions returns the preconditions of the request headers
+func NewPreconditions(header http.Header) *Preconditions {
+	return &Preconditions{
+		IfMatch:     header.Get("If-Match"),
+		IfNoneMatch: header.Get("If-None-Match"),
+	}
+}
+
+//Validate returns ErrPreconditionFailed if the preconditions do not hold for the entity tag of the current version
+//of the resource. Nil preconditions always hold
+func (p *Preconditions) Validate(etag string) error {
+	if p == nil {
+		return nil
+	}
+	if len(p.IfMatch) > 0 && !MatchETag(p.IfMatch, etag) {
+		return ErrPreconditionFailed
+	}
+	if len(p.IfNoneMatch) > 0 && MatchETag(p.IfNoneMatch, etag) {
+		return ErrPreconditionFailed
+	}
+	return nil
+}
+
+//ValidatePreconditions validates the If-Match and If-None-Match headers of a request against the entity tag of the
+//current version of the resource. ErrNotModified is returned for GET requests whose If-None-Match header matches
+//and ErrPreconditionFailed for any other precondition that does not hold
+func ValidatePreconditions(method string, header http.Header, etag string) error {
+	if ifMatch := header.Get("If-Match"); len(ifMatch) > 0 && !MatchETag(ifMatch, etag) {
+		return ErrPreconditionFailed
+	}
+	if ifNoneMatch := header.Get("If-None-Match"); len(ifNoneMatch) > 0 && MatchETag(ifNoneMatch, etag) {
+		if method == http.MethodGet || method == http.MethodHead {
+			return ErrNotModified
+		}
+		return ErrPreconditionFailed
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package scim2
+
+import (
+	"net/http"
+	"testing"
+)
+
+type PreconditionDataProvider struct {
+	method      string
+	ifMatch     string
+	ifNoneMatch string
+	err         error
+}
+
+var preconditionDataProvider = []PreconditionDataProvider{
+	{http.MethodGet, "", "", nil},
+	{http.MethodGet, "", `W/"3"`, ErrNotModified},
+	{http.MethodGet, "", `W/"2", "3"`, ErrNotModified},
+	{http.MethodGet, "", `W/"2"`, nil},
+	{http.MethodPut, `W/"3"`, "", nil},
+	{http.MethodPut, `"3"`, "", nil},
+	{http.MethodPut, `W/"2"`, "", ErrPreconditionFailed},
+	{http.MethodPatch, "*", "", nil},
+	{http.MethodPatch, `W/"2", W/"3"`, "", nil},
+	{http.MethodDelete, `W/"4"`, "", ErrPreconditionFailed},
+	{http.MethodPut, "", "*", ErrPreconditionFailed},
+	{http.MethodDelete, "", `W/"2"`, nil},
+}
+
+func TestValidatePreconditions(t *testing.T) {
+	etag := GetETag(3)
+	if etag != `W/"3"` {
+		t.Errorf("want entity tag %s, got %s", `W/"3"`, etag)
+	}
+	for _, provider := range preconditionDataProvider {
+		header := http.Header{}
+		if len(provider.ifMatch) > 0 {
+			header.Set("If-Match", provider.ifMatch)
+		}
+		if len(provider.ifNoneMatch) > 0 {
+			header.Set("If-None-Match", provider.ifNoneMatch)
+		}
+		if err := ValidatePreconditions(provider.method, header, etag); err != provider.err {
+			t.Errorf("%s If-Match %s If-None-Match %s: want error %v, got %v", provider.method, provider.ifMatch, provider.ifNoneMatch, provider.err, err)
+		}
+	}
+}
+
+func TestPreconditionsValidate(t *testing.T) {
+	etag := GetETag(3)
+	var preconditions *Preconditions
+	if err := preconditions.Validate(etag); err != nil {
+		t.Errorf("want nil preconditions to hold, got %v", err)
+	}
+	for _, provider := range preconditionDataProvider {
+		if provider.method == http.MethodGet {
+			continue
+		}
+		preconditions = &Preconditions{IfMatch: provider.ifMatch, IfNoneMatch: provider.ifNoneMatch}
+		if err := preconditions.Validate(etag); err != provider.err {
+			t.Errorf("If-Match %s If-None-Match %s: want error %v, got %v", provider.ifMatch, provider.ifNoneMatch, provider.err, err)
+		}
+	}
+}
@@ -0,0 +1,622 @@
+package scim2
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+/**
+RFC7644 3.4.2.2. Filtering
+
+	FILTER    = attrExp / logExp / valuePath / *1"not" "(" FILTER ")"
+	valuePath = attrPath "[" valFilter "]"
+	valFilter = attrExp / logExp / *1"not" "(" valFilter ")"
+	attrExp   = (attrPath SP "pr") / (attrPath SP compareOp SP compValue)
+	logExp    = FILTER SP ("and" / "or") SP FILTER
+	compValue = false / null / true / number / string
+	compareOp = "eq" / "ne" / "co" / "sw" / "ew" / "gt" / "lt" / "ge" / "le"
+	attrPath  = [URI ":"] ATTRNAME *1subAttr
+
+Attribute names and operators are case insensitive. The precedence of the logical operators is "not", "and", "or".
+String comparisons are case insensitive except for the attributes with caseExact set to true.
+*/
+
+//Filter is a parsed SCIM filter expression that can be evaluated against SCIM resources
+type Filter struct {
+	attributes map[string]struct{} //lowercase names of the attributes referenced by the filter
+	root       filterExpression
+}
+
+type filterExpression interface {
+	matches(resource map[string]interface{}) bool
+}
+
+type attributePath struct {
+	uri   string   //schema URI of an extension attribute
+	names []string //attribute and optional sub attribute
+}
+
+type logicalExpression struct {
+	and   bool
+	left  filterExpression
+	right filterExpression
+}
+
+type notExpression struct {
+	filter filterExpression
+}
+
+type presentExpression struct {
+	path attributePath
+}
+
+type compareExpression struct {
+	path     attributePath
+	operator string
+	value    interface{} //string, float64, bool or nil
+}
+
+type valuePathExpression struct {
+	path   attributePath
+	filter filterExpression
+}
+
+type filterToken struct {
+	value  string
+	quoted bool
+}
+
+const (
+	coreSchemaPrefix = "urn:ietf:params:scim:schemas:core:2.0:"
+	maxFilterDepth   = 32    //nested groups, not and value paths of a filter
+	maxFilterLength  = 32768 //bytes of a filter expression, long filters are sent in the body of .search requests
+)
+
+var (
+	compareOperators = map[string]struct{}{
+		"eq": {}, "ne": {}, "co": {}, "sw": {}, "ew": {}, "gt": {}, "lt": {}, "ge": {}, "le": {},
+	}
+	//attributes compared with case sensitivity
+	caseExactAttributes = map[string]struct{}{
+		"id": {}, "externalid": {}, "version": {}, "$ref": {}, "password": {},
+	}
+)
+
+//ParseFilter returns the filter for the given expression or ErrBadRequestInvalidFilter if it is not valid
+func ParseFilter(expression string) (*Filter, error) {
+	if len(expression) > maxFilterLength {
+		return nil, ErrBadRequestInvalidFilter
+	}
+	tokens, err := tokenizeFilter(expression)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, ErrBadRequestInvalidFilter
+	}
+	p := &filterParser{attributes: make(map[string]struct{}), tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if !p.done() {
+		return nil, ErrBadRequestInvalidFilter
+	}
+	return &Filter{attributes: p.attributes, root: root}, nil
+}
+
+//GetEqualValue returns the value of filters made of a single "attribute eq value" expression with a string value
+func (f *Filter) GetEqualValue(attribute string) (string, bool) {
+	if f == nil {
+		return "", false
+	}
+	expression, ok := f.root.(*compareExpression)
+	if !ok || expression.operator != "eq" || len(expression.path.uri) > 0 || len(expression.path.names) != 1 {
+		return "", false
+	}
+	if !strings.EqualFold(expression.path.names[0], attribute) {
+		return "", false
+	}
+	value, ok := expression.value.(string)
+	return value, ok
+}
+
+//references returns true if the filter may evaluate the attribute with the given name or schema URI
+func (f *Filter) references(name string) bool {
+	if f == nil {
+		return false
+	}
+	_, found := f.attributes[strings.ToLower(name)]
+	return found
+}
+
+//Matches returns true if the SCIM representation of the resource matches the filter. A nil filter matches every resource
+func (f *Filter) Matches(resource interface{}) bool {
+	if f == nil {
+		return true
+	}
+	values, ok := getResourceValues(resource)
+	if !ok {
+		return false
+	}
+	return f.root.matches(values)
+}
+
+//getResourceValues returns the attributes of the json representation of the resource
+func getResourceValues(resource interface{}) (map[string]interface{}, bool) {
+	var data []byte
+	var err error
+	if raw, ok := resource.(json.RawMessage); ok {
+		data = raw
+	} else {
+		data, err = json.Marshal(resource)
+		if err != nil {
+			return nil, false
+		}
+	}
+	values := make(map[string]interface{})
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if err = dec.Decode(&values); err != nil {
+		return nil, false
+	}
+	return values, true
+}
+
+func tokenizeFilter(expression string) ([]filterToken, error) {
+	var tokens []filterToken
+	runes := []rune(expression)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(' || r == ')' || r == '[' || r == ']':
+			tokens = append(tokens, filterToken{value: string(r)})
+			i++
+		case r == '"':
+			//JSON string including escaped characters
+			j := i + 1
+			for ; j < len(runes) && runes[j] != '"'; j++ {
+				if runes[j] == '\\' {
+					j++
+				}
+			}
+			if j >= len(runes) {
+				return nil, ErrBadRequestInvalidFilter
+			}
+			var value string
+			if err := json.Unmarshal([]byte(string(runes[i:j+1])), &value); err != nil {
+				return nil, ErrBadRequestInvalidFilter
+			}
+			tokens = append(tokens, filterToken{value: value, quoted: true})
+			i = j + 1
+		default:
+			j := i
+			for ; j < len(runes) && !unicode.IsSpace(runes[j]) && !strings.ContainsRune("()[]\"", runes[j]); j++ {
+			}
+			tokens = append(tokens, filterToken{value: string(runes[i:j])})
+			i = j
+		}
+	}
+	return tokens, nil
+}
+
+type filterParser struct {
+	attributes map[string]struct{} //attributes referenced by the parsed expressions
+	depth      int                 //nesting level of the expression being parsed
+	tokens     []filterToken
+	pos        int
+}
+
+func (p *filterParser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *filterParser) peek() (filterToken, bool) {
+	if p.done() {
+		return filterToken{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+//peekKeyword returns true if the next token is the given unquoted keyword
+func (p *filterParser) peekKeyword(keyword string) bool {
+	token, ok := p.peek()
+	return ok && !token.quoted && strings.EqualFold(token.value, keyword)
+}
+
+func (p *filterParser) expect(value string) error {
+	if !p.peekKeyword(value) {
+		return ErrBadRequestInvalidFilter
+	}
+	p.pos++
+	return nil
+}
+
+//parseOr parses a filter. Every group, not and value path is parsed by a nested call, so the depth is limited here
+func (p *filterParser) parseOr() (filterExpression, error) {
+	if p.depth >= maxFilterDepth {
+		return nil, ErrBadRequestInvalidFilter
+	}
+	p.depth++
+	defer func() { p.depth-- }()
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekKeyword("or") {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalExpression{and: false, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (filterExpression, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekKeyword("and") {
+		p.pos++
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalExpression{and: true, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseNot() (filterExpression, error) {
+	if p.peekKeyword("not") && p.pos+1 < len(p.tokens) && p.tokens[p.pos+1].value == "(" && !p.tokens[p.pos+1].quoted {
+		p.pos++
+		filter, err := p.parseGroup()
+		if err != nil {
+			return nil, err
+		}
+		return &notExpression{filter: filter}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *filterParser) parseGroup() (filterExpression, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	filter, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if err = p.expect(")"); err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
+
+func (p *filterParser) parsePrimary() (filterExpression, error) {
+	token, ok := p.peek()
+	if !ok || token.quoted {
+		return nil, ErrBadRequestInvalidFilter
+	}
+	if token.value == "(" {
+		return p.parseGroup()
+	}
+	path, err := parseAttributePath(token.value)
+	if err != nil {
+		return nil, err
+	}
+	p.attributes[path.getRootName()] = struct{}{}
+	p.pos++
+	if p.peekKeyword("[") {
+		p.pos++
+		filter, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.expect("]"); err != nil {
+			return nil, err
+		}
+		return &valuePathExpression{path: path, filter: filter}, nil
+	}
+	operator, ok := p.peek()
+	if !ok || operator.quoted {
+		return nil, ErrBadRequestInvalidFilter
+	}
+	p.pos++
+	op := strings.ToLower(operator.value)
+	if op == "pr" {
+		return &presentExpression{path: path}, nil
+	}
+	if _, ok = compareOperators[op]; !ok {
+		return nil, ErrBadRequestInvalidFilter
+	}
+	value, err := p.parseCompareValue()
+	if err != nil {
+		return nil, err
+	}
+	switch value.(type) {
+	case bool, nil:
+		//boolean and null values can only be compared for equality
+		if op != "eq" && op != "ne" {
+			return nil, ErrBadRequestInvalidFilter
+		}
+	}
+	return &compareExpression{path: path, operator: op, value: value}, nil
+}
+
+func (p *filterParser) parseCompareValue() (interface{}, error) {
+	token, ok := p.peek()
+	if !ok {
+		return nil, ErrBadRequestInvalidFilter
+	}
+	p.pos++
+	if token.quoted {
+		return token.value, nil
+	}
+	switch strings.ToLower(token.value) {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+	number, err := strconv.ParseFloat(token.value, 64)
+	if err != nil {
+		return nil, ErrBadRequestInvalidFilter
+	}
+	return number, nil
+}
+
+//parseAttributePath returns the schema URI and the attribute names of a path like
+//urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:manager.value
+func parseAttributePath(path string) (attributePath, error) {
+	attrPath := attributePath{}
+	if strings.HasPrefix(strings.ToLower(path), "urn:") {
+		idx := strings.LastIndex(path, ":")
+		attrPath.uri = path[:idx]
+		path = path[idx+1:]
+		if strings.HasPrefix(strings.ToLower(attrPath.uri+":"), coreSchemaPrefix) {
+			attrPath.uri = ""
+		}
+	}
+	if len(path) == 0 {
+		return attrPath, ErrBadRequestInvalidFilter
+	}
+	names := strings.Split(path, ".")
+	if len(names) > 2 {
+		return attrPath, ErrBadRequestInvalidFilter
+	}
+	for _, name := range names {
+		if !isAttributeName(name) {
+			return attrPath, ErrBadRequestInvalidFilter
+		}
+	}
+	attrPath.names = names
+	return attrPath, nil
+}
+
+//getRootName returns the lowercase name of the resource attribute holding the path, the schema URI of extension attributes
+func (a attributePath) getRootName() string {
+	if len(a.uri) > 0 {
+		return strings.ToLower(a.uri)
+	}
+	return strings.ToLower(a.names[0])
+}
+
+//isAttributeName validates ATTRNAME = ALPHA *(nameChar), nameChar = "-" / "_" / DIGIT / ALPHA. $ref is also allowed
+func isAttributeName(name string) bool {
+	if name == "$ref" {
+		return true
+	}
+	for i, r := range name {
+		if r > unicode.MaxASCII {
+			return false
+		}
+		if unicode.IsLetter(r) {
+			continue
+		}
+		if i == 0 || (!unicode.IsDigit(r) && r != '-' && r != '_') {
+			return false
+		}
+	}
+	return len(name) > 0
+}
+
+//getValue returns the value of the attribute with a case insensitive name
+func getValue(resource map[string]interface{}, name string) (interface{}, bool) {
+	if value, ok := resource[name]; ok {
+		return value, true
+	}
+	for key, value := range resource {
+		if strings.EqualFold(key, name) {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+//getValues returns all the values found in the path. Multi-valued attributes are flattened
+func (a attributePath) getValues(resource map[string]interface{}) []interface{} {
+	current := []interface{}{resource}
+	if len(a.uri) > 0 {
+		extension, ok := getValue(resource, a.uri)
+		if !ok {
+			return nil
+		}
+		current = []interface{}{extension}
+	}
+	for _, name := range a.names {
+		var next []interface{}
+		for _, value := range current {
+			object, ok := value.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			child, ok := getValue(object, name)
+			if !ok || child == nil {
+				continue
+			}
+			if list, ok := child.([]interface{}); ok {
+				next = append(next, list...)
+			} else {
+				next = append(next, child)
+			}
+		}
+		current = next
+	}
+	return current
+}
+
+func (a attributePath) isCaseExact() bool {
+	_, ok := caseExactAttributes[strings.ToLower(a.names[len(a.names)-1])]
+	return ok
+}
+
+func (e *logicalExpression) matches(resource map[string]interface{}) bool {
+	if e.and {
+		return e.left.matches(resource) && e.right.matches(resource)
+	}
+	return e.left.matches(resource) || e.right.matches(resource)
+}
+
+func (e *notExpression) matches(resource map[string]interface{}) bool {
+	return !e.filter.matches(resource)
+}
+
+func (e *presentExpression) matches(resource map[string]interface{}) bool {
+	for _, value := range e.path.getValues(resource) {
+		switch v := value.(type) {
+		case string:
+			if len(v) > 0 {
+				return true
+			}
+		case map[string]interface{}:
+			if len(v) > 0 {
+				return true
+			}
+		case nil:
+		default:
+			return true
+		}
+	}
+	return false
+}
+
+func (e *valuePathExpression) matches(resource map[string]interface{}) bool {
+	for _, value := range e.path.getValues(resource) {
+		if object, ok := value.(map[string]interface{}); ok && e.filter.matches(object) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *compareExpression) matches(resource map[string]interface{}) bool {
+	values := e.path.getValues(resource)
+	if e.operator == "ne" {
+		return !(&compareExpression{path: e.path, operator: "eq", value: e.value}).matches(resource)
+	}
+	if e.value == nil {
+		//eq null matches unassigned attributes
+		return len(values) == 0
+	}
+	caseExact := e.path.isCaseExact()
+	for _, value := range values {
+		//complex multi-valued attributes are compared by their value sub attribute
+		if object, ok := value.(map[string]interface{}); ok {
+			value, _ = getValue(object, "value")
+		}
+		if compareValue(e.operator, value, e.value, caseExact) {
+			return true
+		}
+	}
+	return false
+}
+
+//compareValue compares the attribute value with the filter value using the operator
+func compareValue(operator string, value interface{}, filterValue interface{}, caseExact bool) bool {
+	switch fv := filterValue.(type) {
+	case bool:
+		v, ok := value.(bool)
+		return ok && v == fv
+	case float64:
+		v, ok := value.(float64)
+		if !ok {
+			return false
+		}
+		switch operator {
+		case "eq":
+			return v == fv
+		case "gt":
+			return v > fv
+		case "ge":
+			return v >= fv
+		case "lt":
+			return v < fv
+		case "le":
+			return v <= fv
+		}
+		return false
+	case string:
+		v, ok := value.(string)
+		if !ok {
+			return false
+		}
+		if !caseExact {
+			v = strings.ToLower(v)
+			fv = strings.ToLower(fv)
+		}
+		switch operator {
+		case "eq":
+			return v == fv
+		case "co":
+			return strings.Contains(v, fv)
+		case "sw":
+			return strings.HasPrefix(v, fv)
+		case "ew":
+			return strings.HasSuffix(v, fv)
+		case "gt", "ge", "lt", "le":
+			return compareOrder(operator, compareStrings(v, fv))
+		}
+	}
+	return false
+}
+
+//compareStrings compares dateTime values chronologically and other strings lexicographically
+func compareStrings(v string, fv string) int {
+	t1, err1 := time.Parse(time.RFC3339, v)
+	t2, err2 := time.Parse(time.RFC3339, fv)
+	if err1 == nil && err2 == nil {
+		switch {
+		case t1.Before(t2):
+			return -1
+		case t1.After(t2):
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(v, fv)
+}
+
+func compareOrder(operator string, result int) bool {
+	switch operator {
+	case "gt":
+		return result > 0
+	case "ge":
+		return result >= 0
+	case "lt":
+		return result < 0
+	case "le":
+		return result <= 0
+	}
+	return false
+}
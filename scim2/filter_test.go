@@ -0,0 +1,162 @@
+package scim2
+
+import (
+	"strings"
+	"testing"
+)
+
+type FilterDataProvider struct {
+	filter  string
+	matches bool
+}
+
+var (
+	testFilterUser = map[string]interface{}{
+		"id":       "2819c223-7f76-453a-919d-413861904646",
+		"userName": "bjensen",
+		"active":   true,
+		"name": map[string]interface{}{
+			"familyName": "Jensen",
+			"givenName":  "Barbara",
+		},
+		"emails": []interface{}{
+			map[string]interface{}{"type": "work", "value": "bjensen@example.com", "primary": true},
+			map[string]interface{}{"type": "home", "value": "babs@jensen.org"},
+		},
+		"meta": map[string]interface{}{
+			"lastModified": "2011-05-13T04:42:34Z",
+			"resourceType": "User",
+		},
+		"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User": map[string]interface{}{
+			"employeeNumber": "701984",
+		},
+		"logins": float64(42),
+	}
+	filterDataProvider = []FilterDataProvider{
+		{`userName eq "bjensen"`, true},
+		{`USERNAME EQ "BJENSEN"`, true},
+		{`userName eq "other"`, false},
+		{`userName ne "other"`, true},
+		{`name.familyName co "ens"`, true},
+		{`userName sw "bj"`, true},
+		{`userName ew "sen"`, true},
+		{`title pr`, false},
+		{`name pr`, true},
+		{`urn:ietf:params:scim:schemas:core:2.0:User:userName eq "bjensen"`, true},
+		{`urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:employeeNumber eq "701984"`, true},
+		{`meta.lastModified gt "2011-05-13T04:42:34Z"`, false},
+		{`meta.lastModified ge "2011-05-13T04:42:34Z"`, true},
+		{`meta.lastModified lt "2012-01-01T00:00:00+01:00"`, true},
+		{`logins gt 40 and logins le 42`, true},
+		{`active eq true`, true},
+		{`active eq false`, false},
+		{`title eq null`, true},
+		{`emails co "example.com"`, true},
+		{`emails[type eq "work" and value co "@example.com"]`, true},
+		{`emails[type eq "work" and value co "@jensen.org"]`, false},
+		{`emails.type eq "home"`, true},
+		{`id eq "2819C223-7F76-453A-919D-413861904646"`, false},
+		{`userName eq "other" or name.givenName eq "Barbara"`, true},
+		{`userName eq "bjensen" and not (emails.type eq "home")`, false},
+		{`(userName eq "other" or userName eq "bjensen") and meta.resourceType eq "User"`, true},
+		{`userName eq "other" or userName eq "bjensen" and active eq false`, false},
+		{`userName eq "a \"quoted\" name"`, false},
+	}
+	invalidFilters = []string{
+		``,
+		`userName`,
+		`userName eq`,
+		`userName xx "bjensen"`,
+		`userName eq bjensen`,
+		`(userName eq "bjensen"`,
+		`emails[type eq "work"`,
+		`active gt true`,
+		`userName eq "bjensen" and`,
+		`userName eq "bjensen" "x"`,
+		`name.familyName.x eq "a"`,
+		`1userName eq "a"`,
+		`userName eq "unterminated`,
+	}
+)
+
+func TestFilterMatches(t *testing.T) {
+	for _, provider := range filterDataProvider {
+		filter, err := ParseFilter(provider.filter)
+		if err != nil {
+			t.Errorf("%s: want valid filter got %s", provider.filter, err.Error())
+			continue
+		}
+		if matches := filter.Matches(testFilterUser); matches != provider.matches {
+			t.Errorf("%s: want %v got %v", provider.filter, provider.matches, matches)
+		}
+	}
+}
+
+func TestInvalidFilter(t *testing.T) {
+	for _, expression := range invalidFilters {
+		_, err := ParseFilter(expression)
+		if err != ErrBadRequestInvalidFilter {
+			t.Errorf("%s: want %v got %v", expression, ErrBadRequestInvalidFilter, err)
+		}
+	}
+}
+
+func TestFilterLimits(t *testing.T) {
+	nested := strings.Repeat("(", maxFilterDepth) + `userName eq "bjensen"` + strings.Repeat(")", maxFilterDepth)
+	if _, err := ParseFilter(nested); err != ErrBadRequestInvalidFilter {
+		t.Errorf("want error %v for too deep filter, got %v", ErrBadRequestInvalidFilter, err)
+	}
+	nested = strings.Repeat("not (", maxFilterDepth-1) + `userName eq "bjensen"` + strings.Repeat(")", maxFilterDepth-1)
+	if _, err := ParseFilter(nested); err != nil {
+		t.Errorf("want no error for filter at max depth, got %v", err)
+	}
+	long := `userName eq "` + strings.Repeat("b", maxFilterLength) + `"`
+	if _, err := ParseFilter(long); err != ErrBadRequestInvalidFilter {
+		t.Errorf("want error %v for too long filter, got %v", ErrBadRequestInvalidFilter, err)
+	}
+}
+
+func TestFilterScimUser(t *testing.T) {
+	user := &User{
+		UserName: "bjensen",
+		Emails:   []MultiValueAttribute{{Type: "work", Value: "bjensen@example.com"}},
+	}
+	filter, _ := ParseFilter(`userName eq "bjensen" and emails[type eq "work"]`)
+	if !filter.Matches(user) {
+		t.Errorf("want scim user matching filter")
+	}
+	var nilFilter *Filter
+	if !nilFilter.Matches(user) {
+		t.Errorf("want nil filter matching every resource")
+	}
+}
+
+func TestFilterGetEqualValue(t *testing.T) {
+	tests := []struct {
+		filter string
+		value  string
+		found  bool
+	}{
+		{`userName eq "bjensen"`, "bjensen", true},
+		{`USERNAME eq "bjensen"`, "bjensen", true},
+		{`userName ne "bjensen"`, "", false},
+		{`userName eq "bjensen" or userName eq "jsmith"`, "", false},
+		{`displayName eq "bjensen"`, "", false},
+		{`userName eq 12`, "", false},
+	}
+	for _, test := range tests {
+		filter, err := ParseFilter(test.filter)
+		if err != nil {
+			t.Errorf("%s: want no error, got %s", test.filter, err.Error())
+			continue
+		}
+		value, found := filter.GetEqualValue("userName")
+		if value != test.value || found != test.found {
+			t.Errorf("%s: want %q %v, got %q %v", test.filter, test.value, test.found, value, found)
+		}
+	}
+	var filter *Filter
+	if _, found := filter.GetEqualValue("userName"); found {
+		t.Errorf("nil filter: want not found")
+	}
+}
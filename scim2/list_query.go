@@ -0,0 +1,250 @@
+package scim2
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/**
+RFC7644 3.4.2.3. Sorting and 3.4.2.4. Pagination
+
+	sortBy     the attribute whose value is used to order the returned resources
+	sortOrder  "ascending" (default) or "descending"
+	startIndex 1-based index of the first result. Values less than 1 are interpreted as 1
+	count      non-negative maximum number of results. Negative values are interpreted as 0
+
+Resources without a value for the sortBy attribute are sorted last regardless of the sort order.
+*/
+
+const (
+	AscendingSortOrder  = "ascending"
+	DescendingSortOrder = "descending"
+)
+
+//ListQuery holds the filter, sorting and pagination parameters of a list request
+type ListQuery struct {
+	Filter     *Filter
+	SortBy     string
+	SortOrder  string
+	StartIndex int //1-based index of the first result
+	Count      int //maximum number of results. A negative count returns all the results
+}
+
+//ListPage collects the keys of the resources matching a list query and returns the keys of the requested page
+type ListPage struct {
+	query    *ListQuery
+	sortPath *attributePath
+	entries  []listEntry
+}
+
+type listEntry struct {
+	key   interface{}
+	value interface{} //sort value, nil if the resource has no value for the sortBy attribute
+}
+
+//NewListQuery returns the query of the filter, sortBy, sortOrder, startIndex and count request parameters.
+//maxResults is used when count is not given or is greater than maxResults
+func NewListQuery(values url.Values, maxResults int) (*ListQuery, error) {
+	query := &ListQuery{
+		StartIndex: 1,
+		Count:      maxResults,
+	}
+	if expression := values.Get("filter"); len(strings.TrimSpace(expression)) > 0 {
+		filter, err := ParseFilter(expression)
+		if err != nil {
+			return nil, ErrBadRequestInvalidFilter
+		}
+		query.Filter = filter
+	}
+	if sortBy := strings.TrimSpace(values.Get("sortBy")); len(sortBy) > 0 {
+		if _, err := parseAttributePath(sortBy); err != nil {
+			return nil, ErrBadRequestInvalidValue
+		}
+		query.SortBy = sortBy
+	}
+	switch sortOrder := strings.ToLower(values.Get("sortOrder")); sortOrder {
+	case "", AscendingSortOrder:
+		query.SortOrder = AscendingSortOrder
+	case DescendingSortOrder:
+		query.SortOrder = DescendingSortOrder
+	default:
+		return nil, ErrBadRequestInvalidValue
+	}
+	if startIndex := values.Get("startIndex"); len(startIndex) > 0 {
+		idx, err := strconv.Atoi(startIndex)
+		if err != nil {
+			return nil, ErrBadRequestInvalidValue
+		}
+		if idx > 1 {
+			query.StartIndex = idx
+		}
+	}
+	if count := values.Get("count"); len(count) > 0 {
+		c, err := strconv.Atoi(count)
+		if err != nil {
+			return nil, ErrBadRequestInvalidValue
+		}
+		if c < 0 {
+			c = 0
+		}
+		if maxResults < 0 || c < maxResults {
+			query.Count = c
+		}
+	}
+	return query, nil
+}
+
+//NewListPage returns an empty page for the query. A nil query returns every resource
+func NewListPage(query *ListQuery) *ListPage {
+	if query == nil {
+		query = &ListQuery{StartIndex: 1, Count: -1}
+	}
+	page := &ListPage{query: query}
+	if len(query.SortBy) > 0 {
+		if path, err := parseAttributePath(query.SortBy); err == nil {
+			page.sortPath = &path
+		}
+	}
+	return page
+}
+
+//IsResourceRequired returns false if the keys can be paged without evaluating the resources
+func (p *ListPage) IsResourceRequired() bool {
+	return p.query.Filter != nil || p.sortPath != nil
+}
+
+//Add adds the key of the resource if it matches the query filter. The resource is ignored if it is not required
+func (p *ListPage) Add(key interface{}, resource interface{}) {
+	if !p.IsResourceRequired() {
+		p.entries = append(p.entries, listEntry{key: key})
+		return
+	}
+	values, ok := getResourceValues(resource)
+	if !ok {
+		return
+	}
+	p.AddValues(key, values)
+}
+
+//AddValues adds the key of the resource if the attribute values of its json representation match the query filter.
+//The values are only needed if the resource is required
+func (p *ListPage) AddValues(key interface{}, values map[string]interface{}) {
+	if !p.IsResourceRequired() {
+		p.entries = append(p.entries, listEntry{key: key})
+		return
+	}
+	if p.query.Filter != nil && !p.query.Filter.root.matches(values) {
+		return
+	}
+	entry := listEntry{key: key}
+	if p.sortPath != nil {
+		entry.value = p.sortPath.getSortValue(values)
+	}
+	p.entries = append(p.entries, entry)
+}
+
+//IsAttributeRequired returns true if the filter or the sortBy attribute of the query may use the attribute with the
+//given name or schema URI
+func (p *ListPage) IsAttributeRequired(name string) bool {
+	if p.sortPath != nil && p.sortPath.getRootName() == strings.ToLower(name) {
+		return true
+	}
+	return p.query.Filter.references(name)
+}
+
+//Keys returns the keys of the requested page in order and the total number of resources matching the query
+func (p *ListPage) Keys() ([]interface{}, int) {
+	total := len(p.entries)
+	if p.sortPath != nil {
+		descending := p.query.SortOrder == DescendingSortOrder
+		sort.SliceStable(p.entries, func(i, j int) bool {
+			vi, vj := p.entries[i].value, p.entries[j].value
+			if vi == nil || vj == nil {
+				return vi != nil
+			}
+			if descending {
+				return compareSortValues(vj, vi) < 0
+			}
+			return compareSortValues(vi, vj) < 0
+		})
+	}
+	start := p.query.StartIndex - 1
+	if start < 0 {
+		start = 0
+	}
+	if start > total {
+		start = total
+	}
+	end := total
+	if p.query.Count >= 0 && start+p.query.Count < total {
+		end = start + p.query.Count
+	}
+	keys := make([]interface{}, 0, end-start)
+	for _, entry := range p.entries[start:end] {
+		keys = append(keys, entry.key)
+	}
+	return keys, total
+}
+
+//getSortValue returns the value used to sort the resource. Multi-valued attributes are sorted by their primary value
+//or the first one if none is primary
+func (a attributePath) getSortValue(resource map[string]interface{}) interface{} {
+	var value interface{}
+	for _, v := range a.getValues(resource) {
+		object, ok := v.(map[string]interface{})
+		if !ok {
+			if value == nil {
+				value = v
+			}
+			continue
+		}
+		primary, _ := getValue(object, "primary")
+		if value == nil || primary == true {
+			value, _ = getValue(object, "value")
+		}
+		if primary == true {
+			break
+		}
+	}
+	if s, ok := value.(string); ok && !a.isCaseExact() {
+		value = strings.ToLower(s)
+	}
+	return value
+}
+
+//compareSortValues compares two sort values. Values of different types are compared by their json representation
+func compareSortValues(v1 interface{}, v2 interface{}) int {
+	switch t1 := v1.(type) {
+	case string:
+		if t2, ok := v2.(string); ok {
+			return compareStrings(t1, t2)
+		}
+	case float64:
+		if t2, ok := v2.(float64); ok {
+			switch {
+			case t1 < t2:
+				return -1
+			case t1 > t2:
+				return 1
+			}
+			return 0
+		}
+	case bool:
+		if t2, ok := v2.(bool); ok {
+			switch {
+			case t1 == t2:
+				return 0
+			case !t1:
+				return -1
+			}
+			return 1
+		}
+	}
+	d1, _ := json.Marshal(v1)
+	d2, _ := json.Marshal(v2)
+	return bytes.Compare(d1, d2)
+}
@@ -0,0 +1,146 @@
+package scim2
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+type ListQueryDataProvider struct {
+	query      string
+	valid      bool
+	startIndex int
+	count      int
+}
+
+var listQueryDataProvider = []ListQueryDataProvider{
+	{"", true, 1, 100},
+	{"startIndex=10&count=5", true, 10, 5},
+	{"startIndex=-3&count=-1", true, 1, 0},
+	{"count=500", true, 1, 100},
+	{"startIndex=first", false, 0, 0},
+	{"count=many", false, 0, 0},
+	{"sortBy=name.familyName&sortOrder=DESCENDING", true, 1, 100},
+	{"sortBy=name.familyName.other", false, 0, 0},
+	{"sortOrder=random", false, 0, 0},
+	{"filter=userName%20xx%20%22bjensen%22", false, 0, 0},
+}
+
+func TestNewListQuery(t *testing.T) {
+	for _, provider := range listQueryDataProvider {
+		values, _ := url.ParseQuery(provider.query)
+		query, err := NewListQuery(values, 100)
+		if !provider.valid {
+			if err == nil {
+				t.Errorf("%s: want error, got no error", provider.query)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: want no error, got %s", provider.query, err.Error())
+			continue
+		}
+		if query.StartIndex != provider.startIndex || query.Count != provider.count {
+			t.Errorf("%s: want startIndex %d and count %d, got %d and %d", provider.query, provider.startIndex, provider.count, query.StartIndex, query.Count)
+		}
+	}
+}
+
+func TestListPage(t *testing.T) {
+	resources := []map[string]interface{}{
+		{"id": "1", "userName": "carol", "emails": []interface{}{
+			map[string]interface{}{"value": "z@example.com"},
+			map[string]interface{}{"value": "c@example.com", "primary": true},
+		}},
+		{"id": "2", "userName": "Alice", "emails": []interface{}{map[string]interface{}{"value": "a@example.com"}}},
+		{"id": "3", "userName": "bob"},
+		{"id": "4", "userName": "dave", "emails": []interface{}{map[string]interface{}{"value": "b@example.com"}}},
+	}
+	filter, _ := ParseFilter(`emails pr`)
+	tests := []struct {
+		query *ListQuery
+		keys  []string
+		total int
+	}{
+		{nil, []string{"1", "2", "3", "4"}, 4},
+		{&ListQuery{StartIndex: 2, Count: 2}, []string{"2", "3"}, 4},
+		{&ListQuery{StartIndex: 10, Count: 2}, []string{}, 4},
+		{&ListQuery{StartIndex: 1, Count: 0}, []string{}, 4},
+		{&ListQuery{SortBy: "userName", StartIndex: 1, Count: -1}, []string{"2", "3", "1", "4"}, 4},
+		{&ListQuery{SortBy: "userName", SortOrder: DescendingSortOrder, StartIndex: 1, Count: 2}, []string{"4", "1"}, 4},
+		{&ListQuery{SortBy: "emails", StartIndex: 1, Count: -1}, []string{"2", "4", "1", "3"}, 4},
+		{&ListQuery{SortBy: "emails", SortOrder: DescendingSortOrder, StartIndex: 1, Count: -1}, []string{"1", "4", "2", "3"}, 4},
+		{&ListQuery{Filter: filter, SortBy: "userName", StartIndex: 2, Count: 5}, []string{"1", "4"}, 3},
+	}
+	for idx, test := range tests {
+		page := NewListPage(test.query)
+		for _, resource := range resources {
+			page.Add(resource["id"], resource)
+		}
+		keys, total := page.Keys()
+		if total != test.total {
+			t.Errorf("test %d: want total %d, got %d", idx, test.total, total)
+		}
+		if len(keys) != len(test.keys) {
+			t.Errorf("test %d: want keys %v, got %v", idx, test.keys, keys)
+			continue
+		}
+		for i, key := range keys {
+			if key != test.keys[i] {
+				t.Errorf("test %d: want keys %v, got %v", idx, test.keys, keys)
+				break
+			}
+		}
+	}
+}
+
+func TestListPageIsAttributeRequired(t *testing.T) {
+	filter, _ := ParseFilter(`emails[type eq "work"] or urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:manager.value eq "1"`)
+	page := NewListPage(&ListQuery{Filter: filter, SortBy: "name.familyName", StartIndex: 1, Count: -1})
+	for _, name := range []string{"emails", "Name", EnterpriseUserSchema} {
+		if !page.IsAttributeRequired(name) {
+			t.Errorf("%s: want required, got not required", name)
+		}
+	}
+	for _, name := range []string{"groups", "userName"} {
+		if page.IsAttributeRequired(name) {
+			t.Errorf("%s: want not required, got required", name)
+		}
+	}
+	if NewListPage(nil).IsAttributeRequired("groups") {
+		t.Errorf("want groups not required without query")
+	}
+}
+
+func TestUserGetValues(t *testing.T) {
+	users := []*User{
+		{ID: "1", UserName: "bjensen"},
+		{
+			Active:         true,
+			Addresses:      []Address{{Country: "US", Locality: "Hollywood", Primary: true, Type: "work"}},
+			DisplayName:    "Babs Jensen",
+			Emails:         []MultiValueAttribute{{Type: "work", Value: "bjensen@example.com", Primary: true}, {Value: "babs@example.com"}},
+			EnterpriseUser: &EnterpriseUser{Department: "Sales", Manager: &Manager{DisplayName: "John", Value: "2"}},
+			Entitlements:   []string{"read"},
+			Extensions:     ExtensionValues{"urn:example:params:scim:schemas:extension:custom:2.0:User": {"badge": "A1"}},
+			ExternalId:     "bj",
+			Groups:         []GroupAssignment{{Display: "Admins", Ref: "/Groups/3", Value: "3"}},
+			ID:             "1",
+			Metadata:       &Metadata{Created: "2020-01-01T00:00:00Z", ResourceType: "User", Version: `W/"1"`},
+			Name:           &Name{FamilyName: "Jensen", GivenName: "Barbara"},
+			NickName:       "Babs",
+			PhoneNumbers:   []MultiValueAttribute{{Type: "mobile", Value: "555-555-5555"}},
+			Roles:          []string{"manager"},
+			Schemas:        []string{UserSchema},
+			UserName:       "bjensen",
+			UserType:       "Employee",
+		},
+	}
+	for _, user := range users {
+		want, _ := getResourceValues(user)
+		got := user.GetValues()
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("want %v, got %v", want, got)
+		}
+	}
+}
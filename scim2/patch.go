@@ -0,0 +1,456 @@
+package scim2
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+/**
+RFC7644 3.5.2. Modifying with PATCH
+
+	PATH = attrPath / valuePath [subAttr]
+
+Operations are applied in order to the SCIM representation of the resource. If an operation fails the resource is not
+modified. Read only attributes can not be the target of an operation and are ignored in the value of operations without
+path, as in a PUT request.
+*/
+
+const (
+	PatchOpSchema = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+)
+
+//PatchOp is a SCIM PATCH request
+type PatchOp struct {
+	Schemas    []string         `json:"schemas"`
+	Operations []PatchOperation `json:"Operations"`
+}
+
+//PatchOperation adds, removes or replaces the values of the attribute in path
+type PatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+type patchPath struct {
+	attribute    attributePath //attribute and optional sub attribute
+	filter       *Filter       //value filter of multi-valued attributes
+	subAttribute string        //sub attribute of the values matching the filter
+}
+
+//attributeMutability lists the attributes of a resource that can not be patched or removed
+type attributeMutability struct {
+	readOnly map[string]struct{}
+	required map[string]struct{}
+}
+
+var (
+	userMutability = attributeMutability{
+		readOnly: map[string]struct{}{"id": {}, "meta": {}, "groups": {}},
+		required: map[string]struct{}{"username": {}},
+	}
+	groupMutability = attributeMutability{
+		readOnly: map[string]struct{}{"id": {}, "meta": {}},
+		required: map[string]struct{}{"displayname": {}},
+	}
+	clientMutability = attributeMutability{
+		readOnly: map[string]struct{}{"id": {}, "meta": {}, "groups": {}, "password": {}},
+		required: map[string]struct{}{},
+	}
+)
+
+//Apply applies the operations to the resource, which must be a *User, *Group or *Client.
+//The resource is only modified if all the operations succeed
+func (p *PatchOp) Apply(resource interface{}) error {
+	var mutability attributeMutability
+	switch resource.(type) {
+	case *User:
+		mutability = userMutability
+	case *Group:
+		mutability = groupMutability
+	case *Client:
+		mutability = clientMutability
+	default:
+		return ErrNotImplemented
+	}
+	if !p.hasSchema() || len(p.Operations) == 0 {
+		return ErrBadRequestInvalidSyntax
+	}
+	values, ok := getResourceValues(resource)
+	if !ok {
+		return ErrInternalError
+	}
+	for _, operation := range p.Operations {
+		if err := operation.apply(values, mutability); err != nil {
+			return err
+		}
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		return ErrBadRequestInvalidValue
+	}
+	patched := reflect.New(reflect.TypeOf(resource).Elem())
+	if err = json.Unmarshal(data, patched.Interface()); err != nil {
+		return ErrBadRequestInvalidValue
+	}
+	reflect.ValueOf(resource).Elem().Set(patched.Elem())
+	return nil
+}
+
+func (p *PatchOp) hasSchema() bool {
+	for _, schema := range p.Schemas {
+		if schema == PatchOpSchema {
+			return true
+		}
+	}
+	return false
+}
+
+func (o *PatchOperation) apply(resource map[string]interface{}, mutability attributeMutability) error {
+	op := strings.ToLower(o.Op)
+	if op != "add" && op != "remove" && op != "replace" {
+		return ErrBadRequestInvalidSyntax
+	}
+	if len(strings.TrimSpace(o.Path)) == 0 {
+		if op == "remove" {
+			return ErrBadRequestNoTarget
+		}
+		attributes, ok := o.Value.(map[string]interface{})
+		if !ok {
+			return ErrBadRequestInvalidValue
+		}
+		for name, value := range attributes {
+			if err := applyPath(op, name, value, resource, mutability, true); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if op != "remove" && o.Value == nil {
+		return ErrBadRequestInvalidValue
+	}
+	return applyPath(op, o.Path, o.Value, resource, mutability, false)
+}
+
+//applyPath applies the operation to the attribute in path. Read only attributes are ignored if ignoreReadOnly is true
+func applyPath(op string, path string, value interface{}, resource map[string]interface{}, mutability attributeMutability, ignoreReadOnly bool) error {
+	path = strings.TrimSpace(path)
+	if isExtensionSchema(resource, path) {
+		//the whole extension is the target
+		if op == "remove" {
+			delete(resource, getKey(resource, path))
+			removeSchema(resource, path)
+			return nil
+		}
+		attributes, ok := value.(map[string]interface{})
+		if !ok {
+			return ErrBadRequestInvalidValue
+		}
+		for name, attributeValue := range attributes {
+			if err := applyPath(op, path+":"+name, attributeValue, resource, mutability, ignoreReadOnly); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	patchPath, err := parsePatchPath(path)
+	if err != nil {
+		return err
+	}
+	if mutability.isReadOnly(patchPath) {
+		if ignoreReadOnly {
+			return nil
+		}
+		return ErrBadRequestMutability
+	}
+	if op == "remove" && value == nil && mutability.isRequired(patchPath) {
+		return ErrBadRequestMutability
+	}
+	return patchPath.apply(op, resource, value)
+}
+
+//parsePatchPath returns the patch path of expressions like emails[type eq "work"].value
+func parsePatchPath(path string) (*patchPath, error) {
+	p := &patchPath{}
+	attribute := path
+	if open := strings.Index(path, "["); open >= 0 {
+		closing := strings.LastIndex(path, "]")
+		if closing < open {
+			return nil, ErrBadRequestInvalidPath
+		}
+		filter, err := ParseFilter(path[open+1 : closing])
+		if err != nil {
+			return nil, ErrBadRequestInvalidPath
+		}
+		p.filter = filter
+		attribute = path[:open]
+		if rest := path[closing+1:]; len(rest) > 0 {
+			if !strings.HasPrefix(rest, ".") || !isAttributeName(rest[1:]) {
+				return nil, ErrBadRequestInvalidPath
+			}
+			p.subAttribute = rest[1:]
+		}
+	}
+	attrPath, err := parseAttributePath(attribute)
+	if err != nil {
+		return nil, ErrBadRequestInvalidPath
+	}
+	if p.filter != nil && len(attrPath.names) > 1 {
+		return nil, ErrBadRequestInvalidPath
+	}
+	p.attribute = attrPath
+	return p, nil
+}
+
+func (p *patchPath) apply(op string, resource map[string]interface{}, value interface{}) error {
+	container := resource
+	if len(p.attribute.uri) > 0 {
+		extension, ok := resource[getKey(resource, p.attribute.uri)].(map[string]interface{})
+		if !ok {
+			if op == "remove" {
+				return nil
+			}
+			extension = make(map[string]interface{})
+			resource[p.attribute.uri] = extension
+			addSchema(resource, p.attribute.uri)
+		}
+		container = extension
+	}
+	name := getKey(container, p.attribute.names[0])
+	if p.filter != nil {
+		return p.applyFiltered(op, container, name, value)
+	}
+	if len(p.attribute.names) == 1 {
+		return applyValue(op, container, name, value)
+	}
+	subAttribute := p.attribute.names[1]
+	switch parent := container[name].(type) {
+	case map[string]interface{}:
+		return applyValue(op, parent, getKey(parent, subAttribute), value)
+	case []interface{}:
+		//the sub attribute of every value of a multi-valued attribute
+		for _, element := range parent {
+			if object, ok := element.(map[string]interface{}); ok {
+				if err := applyValue(op, object, getKey(object, subAttribute), value); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	case nil:
+		if op == "remove" {
+			return nil
+		}
+		object := make(map[string]interface{})
+		container[name] = object
+		return applyValue(op, object, subAttribute, value)
+	}
+	return ErrBadRequestInvalidPath
+}
+
+//applyFiltered applies the operation to the values of the multi-valued attribute matching the value filter
+func (p *patchPath) applyFiltered(op string, container map[string]interface{}, name string, value interface{}) error {
+	values, _ := container[name].([]interface{})
+	matched := false
+	var patched []interface{}
+	for _, element := range values {
+		object, ok := element.(map[string]interface{})
+		if !ok || !p.filter.root.matches(object) {
+			patched = append(patched, element)
+			continue
+		}
+		matched = true
+		switch {
+		case op == "remove" && len(p.subAttribute) == 0:
+			continue
+		case op == "remove":
+			delete(object, getKey(object, p.subAttribute))
+		case len(p.subAttribute) > 0:
+			if err := applyValue(op, object, getKey(object, p.subAttribute), value); err != nil {
+				return err
+			}
+		default:
+			if _, ok := value.(map[string]interface{}); !ok {
+				return ErrBadRequestInvalidValue
+			}
+			element = mergeValue(object, value, op == "add")
+		}
+		patched = append(patched, element)
+	}
+	if !matched && op != "remove" {
+		return ErrBadRequestNoTarget
+	}
+	if len(patched) == 0 {
+		delete(container, name)
+	} else {
+		container[name] = patched
+	}
+	return nil
+}
+
+func (m attributeMutability) isReadOnly(path *patchPath) bool {
+	if len(path.attribute.uri) > 0 {
+		return false
+	}
+	_, ok := m.readOnly[strings.ToLower(path.attribute.names[0])]
+	return ok
+}
+
+func (m attributeMutability) isRequired(path *patchPath) bool {
+	if len(path.attribute.uri) > 0 || len(path.attribute.names) > 1 || path.filter != nil {
+		return false
+	}
+	_, ok := m.required[strings.ToLower(path.attribute.names[0])]
+	return ok
+}
+
+//applyValue applies the operation to the attribute of the object
+func applyValue(op string, object map[string]interface{}, name string, value interface{}) error {
+	switch op {
+	case "add":
+		object[name] = mergeValue(object[name], value, true)
+	case "replace":
+		object[name] = mergeValue(object[name], value, false)
+	case "remove":
+		removed, ok := value.([]interface{})
+		if !ok {
+			delete(object, name)
+			return nil
+		}
+		//values to remove from a multi-valued attribute, e.g. the members of a group
+		values, _ := object[name].([]interface{})
+		var kept []interface{}
+		for _, v := range values {
+			if !containsValue(removed, v) {
+				kept = append(kept, v)
+			}
+		}
+		if len(kept) == 0 {
+			delete(object, name)
+		} else {
+			object[name] = kept
+		}
+	}
+	return nil
+}
+
+//mergeValue returns the value of an attribute after adding or replacing a value. Sub attributes of complex attributes
+//that are not in the value are unchanged. Values added to a multi-valued attribute are appended if they are not present
+func mergeValue(current interface{}, value interface{}, add bool) interface{} {
+	switch v := value.(type) {
+	case []interface{}:
+		currentValues, ok := current.([]interface{})
+		if !add || !ok {
+			return v
+		}
+		for _, element := range v {
+			if !containsValue(currentValues, element) {
+				currentValues = append(currentValues, element)
+			}
+		}
+		return setSinglePrimary(currentValues, v)
+	case map[string]interface{}:
+		currentObject, ok := current.(map[string]interface{})
+		if !ok {
+			return v
+		}
+		for name, subValue := range v {
+			key := getKey(currentObject, name)
+			currentObject[key] = mergeValue(currentObject[key], subValue, add)
+		}
+		return currentObject
+	}
+	return value
+}
+
+//setSinglePrimary keeps the primary flag only on the last added primary value
+func setSinglePrimary(values []interface{}, added []interface{}) []interface{} {
+	var primary interface{}
+	for _, element := range added {
+		if object, ok := element.(map[string]interface{}); ok {
+			if p, _ := getValue(object, "primary"); p == true {
+				primary = element
+			}
+		}
+	}
+	if primary == nil {
+		return values
+	}
+	for _, element := range values {
+		object, ok := element.(map[string]interface{})
+		if ok && !sameValue(object, primary) {
+			delete(object, getKey(object, "primary"))
+		}
+	}
+	return values
+}
+
+func containsValue(values []interface{}, value interface{}) bool {
+	for _, v := range values {
+		if sameValue(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+//sameValue compares the value sub attribute of complex values and the whole value of simple ones
+func sameValue(v1 interface{}, v2 interface{}) bool {
+	o1, ok1 := v1.(map[string]interface{})
+	o2, ok2 := v2.(map[string]interface{})
+	if ok1 && ok2 {
+		value1, found1 := getValue(o1, "value")
+		value2, found2 := getValue(o2, "value")
+		if found1 && found2 {
+			return reflect.DeepEqual(value1, value2)
+		}
+	}
+	return reflect.DeepEqual(v1, v2)
+}
+
+//getKey returns the key of the attribute with a case insensitive name, or the name if the attribute is not present
+func getKey(object map[string]interface{}, name string) string {
+	if _, ok := object[name]; ok {
+		return name
+	}
+	for key := range object {
+		if strings.EqualFold(key, name) {
+			return key
+		}
+	}
+	return name
+}
+
+//isExtensionSchema returns true if the name is an extension schema listed in the schemas of the resource
+func isExtensionSchema(resource map[string]interface{}, name string) bool {
+	if strings.HasPrefix(strings.ToLower(name+":"), coreSchemaPrefix) {
+		return false
+	}
+	schemas, _ := resource["schemas"].([]interface{})
+	for _, schema := range schemas {
+		if s, ok := schema.(string); ok && strings.EqualFold(s, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func addSchema(resource map[string]interface{}, schema string) {
+	if isExtensionSchema(resource, schema) {
+		return
+	}
+	schemas, _ := resource["schemas"].([]interface{})
+	resource["schemas"] = append(schemas, schema)
+}
+
+func removeSchema(resource map[string]interface{}, schema string) {
+	schemas, _ := resource["schemas"].([]interface{})
+	var kept []interface{}
+	for _, s := range schemas {
+		if name, ok := s.(string); !ok || !strings.EqualFold(name, schema) {
+			kept = append(kept, s)
+		}
+	}
+	resource["schemas"] = kept
+}
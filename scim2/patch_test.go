@@ -0,0 +1,121 @@
+package scim2
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+type PatchDataProvider struct {
+	operations string
+	err        error
+	check      func(user *User) bool
+}
+
+var patchDataProvider = []PatchDataProvider{
+	{`[{"op":"replace","path":"displayName","value":"Babs"}]`, nil, func(u *User) bool {
+		return u.DisplayName == "Babs"
+	}},
+	{`[{"op":"Replace","value":{"displayName":"Babs","name.givenName":"Barb","id":"ignored"}}]`, nil, func(u *User) bool {
+		return u.DisplayName == "Babs" && u.Name.GivenName == "Barb" && u.Name.FamilyName == "Jensen" && u.ID == "2819c223"
+	}},
+	{`[{"op":"add","path":"emails","value":[{"type":"other","value":"b@example.com","primary":true}]}]`, nil, func(u *User) bool {
+		return len(u.Emails) == 3 && !u.Emails[0].Primary && u.Emails[2].Primary
+	}},
+	{`[{"op":"add","path":"emails","value":[{"type":"home","value":"BJENSEN@example.com"}]}]`, nil, func(u *User) bool {
+		return len(u.Emails) == 3
+	}},
+	{`[{"op":"add","path":"emails","value":[{"value":"bjensen@example.com"}]}]`, nil, func(u *User) bool {
+		return len(u.Emails) == 2
+	}},
+	{`[{"op":"replace","path":"emails[type eq \"work\"].value","value":"new@example.com"}]`, nil, func(u *User) bool {
+		return u.Emails[0].Value == "new@example.com" && u.Emails[0].Type == "work" && u.Emails[1].Value == "babs@jensen.org"
+	}},
+	{`[{"op":"remove","path":"emails[type eq \"home\"]"}]`, nil, func(u *User) bool {
+		return len(u.Emails) == 1 && u.Emails[0].Type == "work"
+	}},
+	{`[{"op":"remove","path":"emails","value":[{"value":"babs@jensen.org"}]}]`, nil, func(u *User) bool {
+		return len(u.Emails) == 1 && u.Emails[0].Type == "work"
+	}},
+	{`[{"op":"remove","path":"emails[type eq \"other\"]"}]`, nil, func(u *User) bool {
+		return len(u.Emails) == 2
+	}},
+	{`[{"op":"remove","path":"name.givenName"},{"op":"remove","path":"title"}]`, nil, func(u *User) bool {
+		return u.Name.GivenName == "" && u.Name.FamilyName == "Jensen" && u.Title == ""
+	}},
+	{`[{"op":"add","path":"urn:ietf:params:scim:schemas:core:2.0:User:nickName","value":"babs"}]`, nil, func(u *User) bool {
+		return u.NickName == "babs"
+	}},
+	{`[{"op":"replace","path":"active","value":false}]`, nil, func(u *User) bool {
+		return !u.Active
+	}},
+	{`[{"op":"replace","path":"emails[type eq \"other\"].value","value":"x@example.com"}]`, ErrBadRequestNoTarget, nil},
+	{`[{"op":"replace","path":"id","value":"other"}]`, ErrBadRequestMutability, nil},
+	{`[{"op":"remove","path":"meta.created"}]`, ErrBadRequestMutability, nil},
+	{`[{"op":"remove","path":"userName"}]`, ErrBadRequestMutability, nil},
+	{`[{"op":"remove"}]`, ErrBadRequestNoTarget, nil},
+	{`[{"op":"move","path":"title","value":"x"}]`, ErrBadRequestInvalidSyntax, nil},
+	{`[{"op":"add","path":"emails[type xx \"work\"]","value":{}}]`, ErrBadRequestInvalidPath, nil},
+	{`[{"op":"replace","path":"active","value":"yes"}]`, ErrBadRequestInvalidValue, nil},
+	{`[{"op":"replace","path":"title","value":"Boss"},{"op":"replace","path":"id","value":"other"}]`, ErrBadRequestMutability, func(u *User) bool {
+		return u.Title == "Tour Guide"
+	}},
+}
+
+func newPatchTestUser() *User {
+	return &User{
+		Active:      true,
+		DisplayName: "Barbara Jensen",
+		Emails: []MultiValueAttribute{
+			{Type: "work", Value: "bjensen@example.com", Primary: true},
+			{Type: "home", Value: "babs@jensen.org"},
+		},
+		ID:       "2819c223",
+		Name:     &Name{FamilyName: "Jensen", GivenName: "Barbara"},
+		Schemas:  []string{"urn:ietf:params:scim:schemas:core:2.0:User"},
+		Title:    "Tour Guide",
+		UserName: "bjensen",
+	}
+}
+
+func TestPatchUser(t *testing.T) {
+	for _, provider := range patchDataProvider {
+		patch := &PatchOp{Schemas: []string{PatchOpSchema}}
+		if err := json.Unmarshal([]byte(provider.operations), &patch.Operations); err != nil {
+			t.Fatalf("%s: can not decode operations %s", provider.operations, err.Error())
+		}
+		user := newPatchTestUser()
+		err := patch.Apply(user)
+		if err != provider.err {
+			t.Errorf("%s: want error %v, got %v", provider.operations, provider.err, err)
+			continue
+		}
+		if provider.check != nil && !provider.check(user) {
+			t.Errorf("%s: unexpected patched user %+v", provider.operations, user)
+		}
+	}
+}
+
+func TestPatchGroupMembers(t *testing.T) {
+	group := &Group{
+		DisplayName: "Tour Guides",
+		ID:          "e9e30dba",
+		Members:     []GroupMember{{Value: "2819c223", Type: "User"}, {Value: "902c246b", Type: "User"}},
+	}
+	patch := &PatchOp{
+		Schemas: []string{PatchOpSchema},
+		Operations: []PatchOperation{
+			{Op: "add", Path: "members", Value: []interface{}{map[string]interface{}{"value": "6c5bb468"}}},
+			{Op: "remove", Path: `members[value eq "902c246b"]`},
+		},
+	}
+	if err := patch.Apply(group); err != nil {
+		t.Fatalf("want no error, got %s", err.Error())
+	}
+	if len(group.Members) != 2 || group.Members[0].Value != "2819c223" || group.Members[1].Value != "6c5bb468" {
+		t.Errorf("unexpected members %+v", group.Members)
+	}
+	patch = &PatchOp{Operations: []PatchOperation{{Op: "remove", Path: "members"}}}
+	if err := patch.Apply(group); err != ErrBadRequestInvalidSyntax {
+		t.Errorf("want error %v for missing schema, got %v", ErrBadRequestInvalidSyntax, err)
+	}
+}
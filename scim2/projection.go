@@ -0,0 +1,229 @@
+package scim2
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+/**
+RFC7644 3.4.2.5. Attributes
+
+	attributes         the attributes to return in the response, overriding the default set of attributes
+	excludedAttributes the attributes to remove from the default set of attributes
+
+Attributes are given by their names or their paths, e.g. "name.givenName" or
+"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:manager". excludedAttributes is ignored if attributes
+is given. Attributes returned "always" can not be excluded and attributes returned "never", like the password, are
+removed regardless of the parameters.
+*/
+
+//alwaysReturnedAttributes are the common attributes returned regardless of the projection
+var alwaysReturnedAttributes = map[string]struct{}{
+	"id":      {},
+	"schemas": {},
+}
+
+//Projection holds the attributes and excludedAttributes parameters of a request as lowercase paths
+type Projection struct {
+	attributes         []string
+	excludedAttributes []string
+}
+
+//NewProjection returns the projection of the attributes and excludedAttributes parameters
+func NewProjection(attributes []string, excludedAttributes []string) (*Projection, error) {
+	projection := &Projection{}
+	var err error
+	if projection.attributes, err = getProjectionPaths(attributes); err != nil {
+		return nil, err
+	}
+	if projection.excludedAttributes, err = getProjectionPaths(excludedAttributes); err != nil {
+		return nil, err
+	}
+	return projection, nil
+}
+
+//getProjectionPaths returns the lowercase paths of the attributes. Core schema URIs are removed and extension
+//attributes are prefixed by the extension schema URI
+func getProjectionPaths(attributes []string) ([]string, error) {
+	var paths []string
+	for _, attribute := range attributes {
+		attribute = strings.TrimSpace(attribute)
+		if len(attribute) == 0 {
+			continue
+		}
+		if _, found := getSchemaDefinition(attribute); found {
+			paths = append(paths, strings.ToLower(attribute))
+			continue
+		}
+		path, err := parseAttributePath(attribute)
+		if err != nil {
+			return nil, ErrBadRequestInvalidValue
+		}
+		name := strings.ToLower(strings.Join(path.names, "."))
+		if len(path.uri) > 0 {
+			name = strings.ToLower(path.uri) + ":" + name
+		}
+		paths = append(paths, name)
+	}
+	return paths, nil
+}
+
+//Apply returns the attributes of the json representation of the resource that are returned by the projection. The
+//schema is the core schema of the resource. A nil projection returns the default attributes
+func (p *Projection) Apply(resource interface{}, schema string) (map[string]interface{}, error) {
+	values, ok := getResourceValues(resource)
+	if !ok {
+		return nil, ErrInternalError
+	}
+	if p == nil {
+		p = &Projection{}
+	}
+	var attributes []Attribute
+	if definition, found := getSchemaDefinition(schema); found {
+		attributes = definition.Attributes
+	}
+	p.projectObject(values, "", attributes, false)
+	return values, nil
+}
+
+//ApplyList applies the projection to every resource of the list
+func (p *Projection) ApplyList(resources interface{}, schema string) ([]map[string]interface{}, error) {
+	data, err := json.Marshal(resources)
+	if err != nil {
+		return nil, ErrInternalError
+	}
+	var items []json.RawMessage
+	if err = json.Unmarshal(data, &items); err != nil {
+		return nil, ErrInternalError
+	}
+	projected := make([]map[string]interface{}, 0, len(items))
+	for _, item := range items {
+		values, err := p.Apply(item, schema)
+		if err != nil {
+			return nil, err
+		}
+		projected = append(projected, values)
+	}
+	return projected, nil
+}
+
+//projectObject removes the attributes of the object that are not returned. prefix is the path of the object and
+//included is true if the whole object was requested
+func (p *Projection) projectObject(object map[string]interface{}, prefix string, attributes []Attribute, included bool) {
+	for key, value := range object {
+		name := prefix + strings.ToLower(key)
+		childPrefix := name + "."
+		returned := DefaultReturned
+		var subAttributes []Attribute
+		if attribute, found := findAttributeDefinition(attributes, key); found {
+			returned = attribute.Returned
+			subAttributes = attribute.SubAttributes
+		} else if extension, found := getSchemaDefinition(key); found && len(prefix) == 0 {
+			subAttributes = extension.Attributes
+			childPrefix = name + ":"
+		}
+		if _, found := alwaysReturnedAttributes[name]; found {
+			returned = AlwaysReturned
+		}
+		requested := included || p.isRequested(name)
+		switch {
+		case returned == NeverReturned:
+			delete(object, key)
+			continue
+		case returned == AlwaysReturned:
+			requested = true
+		case len(p.attributes) > 0 || returned == RequestReturned:
+			if !requested && !p.isRequestedParent(name) {
+				delete(object, key)
+				continue
+			}
+		case p.isExcluded(name):
+			delete(object, key)
+			continue
+		}
+		p.projectValue(value, childPrefix, subAttributes, requested)
+		if !requested && len(p.attributes) > 0 && isEmptyValue(value) {
+			delete(object, key)
+		}
+	}
+}
+
+//projectValue projects the sub-attributes of complex attributes
+func (p *Projection) projectValue(value interface{}, prefix string, attributes []Attribute, included bool) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		p.projectObject(v, prefix, attributes, included)
+	case []interface{}:
+		for _, item := range v {
+			if object, ok := item.(map[string]interface{}); ok {
+				p.projectObject(object, prefix, attributes, included)
+			}
+		}
+	}
+}
+
+//isRequested returns true if the attribute or one of its parents is in the attributes parameter
+func (p *Projection) isRequested(name string) bool {
+	for _, attribute := range p.attributes {
+		if name == attribute || strings.HasPrefix(name, attribute+".") || strings.HasPrefix(name, attribute+":") {
+			return true
+		}
+	}
+	return false
+}
+
+//isRequestedParent returns true if one of the sub-attributes of the attribute is in the attributes parameter
+func (p *Projection) isRequestedParent(name string) bool {
+	for _, attribute := range p.attributes {
+		if strings.HasPrefix(attribute, name+".") || strings.HasPrefix(attribute, name+":") {
+			return true
+		}
+	}
+	return false
+}
+
+//isExcluded returns true if the attribute or one of its parents is in the excludedAttributes parameter
+func (p *Projection) isExcluded(name string) bool {
+	for _, attribute := range p.excludedAttributes {
+		if name == attribute || strings.HasPrefix(name, attribute+".") || strings.HasPrefix(name, attribute+":") {
+			return true
+		}
+	}
+	return false
+}
+
+//getSchemaDefinition returns the definition of the schema with a case insensitive id
+func getSchemaDefinition(id string) (*Schema, bool) {
+	for _, definition := range getSchemaDefinitions() {
+		if strings.EqualFold(definition.ID, id) {
+			return definition, true
+		}
+	}
+	return nil, false
+}
+
+//findAttributeDefinition returns the attribute with a case insensitive name
+func findAttributeDefinition(attributes []Attribute, name string) (Attribute, bool) {
+	for _, attribute := range attributes {
+		if strings.EqualFold(attribute.Name, name) {
+			return attribute, true
+		}
+	}
+	return Attribute{}, false
+}
+
+//isEmptyValue returns true if the projected complex or multi-valued attribute has no values left
+func isEmptyValue(value interface{}) bool {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return len(v) == 0
+	case []interface{}:
+		for _, item := range v {
+			if !isEmptyValue(item) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
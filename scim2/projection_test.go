@@ -0,0 +1,91 @@
+package scim2
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+type ProjectionDataProvider struct {
+	attributes         string
+	excludedAttributes string
+	want               []string //top level attributes of the projected user
+}
+
+var projectionDataProvider = []ProjectionDataProvider{
+	{"", "", []string{"emails", "id", "meta", "name", "schemas", "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User", "userName", "x509Certificates"}},
+	{"userName,displayName", "", []string{"id", "schemas", "userName"}},
+	{"name.givenName", "", []string{"id", "name", "schemas"}},
+	{"password,id", "", []string{"id", "schemas"}},
+	{"urn:ietf:params:scim:schemas:core:2.0:User:userName", "", []string{"id", "schemas", "userName"}},
+	{"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:department", "", []string{"id", "schemas", "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"}},
+	{"", "x509Certificates,emails,id,meta.location", []string{"id", "meta", "name", "schemas", "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User", "userName"}},
+	{"userName", "userName", []string{"id", "schemas", "userName"}},
+}
+
+func TestProjectionApply(t *testing.T) {
+	user := &User{
+		ID:       "2819c223",
+		Schemas:  []string{UserSchema, EnterpriseUserSchema},
+		UserName: "bjensen",
+		Password: "t1meMa$heen",
+		Name:     &Name{GivenName: "Barbara", FamilyName: "Jensen"},
+		Emails:   []MultiValueAttribute{{Value: "bjensen@example.com", Type: "work"}},
+		EnterpriseUser: &EnterpriseUser{
+			Department:     "Tour Operations",
+			EmployeeNumber: "701984",
+		},
+		X509Certificates: []MultiValueAttribute{{Value: "MIIDQzCCAqygAwIBAgICEAAwDQYJKoZIhvcNAQEFBQAw"}},
+		Metadata:         &Metadata{ResourceType: "User", Location: "https://example.com/scim2/users/2819c223"},
+	}
+	for _, provider := range projectionDataProvider {
+		projection, err := NewProjection(strings.Split(provider.attributes, ","), strings.Split(provider.excludedAttributes, ","))
+		if err != nil {
+			t.Fatalf("%s: want no error, got %s", provider.attributes, err.Error())
+		}
+		values, err := projection.Apply(user, UserSchema)
+		if err != nil {
+			t.Fatalf("%s: want no error, got %s", provider.attributes, err.Error())
+		}
+		var names []string
+		for name := range values {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if !reflect.DeepEqual(names, provider.want) {
+			t.Errorf("attributes %q excludedAttributes %q: want %v, got %v", provider.attributes, provider.excludedAttributes, provider.want, names)
+		}
+	}
+}
+
+func TestProjectionSubAttributes(t *testing.T) {
+	user := &User{
+		ID:       "2819c223",
+		UserName: "bjensen",
+		Name:     &Name{GivenName: "Barbara", FamilyName: "Jensen"},
+		Metadata: &Metadata{ResourceType: "User", Location: "https://example.com/scim2/users/2819c223", Version: `W/"1"`},
+		EnterpriseUser: &EnterpriseUser{
+			Department:     "Tour Operations",
+			EmployeeNumber: "701984",
+		},
+	}
+	projection, _ := NewProjection([]string{"name.givenName", "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:department"}, nil)
+	values, _ := projection.Apply(user, UserSchema)
+	data, _ := json.Marshal(values)
+	want := `{"id":"2819c223","name":{"givenName":"Barbara"},"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User":{"department":"Tour Operations"}}`
+	if string(data) != want {
+		t.Errorf("want %s, got %s", want, string(data))
+	}
+	projection, _ = NewProjection(nil, []string{"meta.location", "name.familyName"})
+	values, _ = projection.Apply(user, UserSchema)
+	data, _ = json.Marshal(values)
+	want = `{"id":"2819c223","meta":{"resourceType":"User","version":"W/\"1\""},"name":{"givenName":"Barbara"},"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User":{"department":"Tour Operations","employeeNumber":"701984"},"userName":"bjensen"}`
+	if string(data) != want {
+		t.Errorf("want %s, got %s", want, string(data))
+	}
+	if _, err := NewProjection([]string{"name..givenName"}, nil); err != ErrBadRequestInvalidValue {
+		t.Errorf("want error %v, got %v", ErrBadRequestInvalidValue, err)
+	}
+}
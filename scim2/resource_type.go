@@ -0,0 +1,82 @@
+package scim2
+
+/**
+RFC7643 6. ResourceType Schema
+*/
+
+//ResourceType describes a type of resource, its endpoint and its schemas
+type ResourceType struct {
+	Schemas          []string          `json:"schemas"`
+	ID               string            `json:"id,omitempty"`
+	Name             string            `json:"name"`
+	Endpoint         string            `json:"endpoint"`
+	Description      string            `json:"description,omitempty"`
+	Schema           string            `json:"schema"`
+	SchemaExtensions []SchemaExtension `json:"schemaExtensions,omitempty"`
+	Metadata         *Metadata         `json:"meta,omitempty"`
+}
+
+//SchemaExtension is a schema extending the core schema of a resource type
+type SchemaExtension struct {
+	Schema   string `json:"schema"`
+	Required bool   `json:"required"`
+}
+
+var resourceTypeDefinitions = []*ResourceType{
+	{
+		ID:          "User",
+		Name:        "User",
+		Endpoint:    "/users",
+		Description: "User Account",
+		Schema:      UserSchema,
+		SchemaExtensions: []SchemaExtension{
+			{Schema: EnterpriseUserSchema, Required: false},
+		},
+	},
+	{
+		ID:          "Group",
+		Name:        "Group",
+		Endpoint:    "/groups",
+		Description: "Group",
+		Schema:      GroupSchema,
+	},
+	{
+		ID:          "Client",
+		Name:        "Client",
+		Endpoint:    "/clients",
+		Description: "OAuth2 Client",
+		Schema:      ClientSchema,
+	},
+}
+
+//GetResourceTypes returns the resource types supported by the server
+func GetResourceTypes() []ResourceType {
+	resourceTypes := make([]ResourceType, 0, len(resourceTypeDefinitions))
+	for _, definition := range resourceTypeDefinitions {
+		resourceTypes = append(resourceTypes, *definition.withMetadata())
+	}
+	return resourceTypes
+}
+
+//GetResourceType returns the resource type with the given name
+func GetResourceType(name string) (*ResourceType, bool) {
+	for _, definition := range resourceTypeDefinitions {
+		if definition.Name == name {
+			return definition.withMetadata(), true
+		}
+	}
+	return nil, false
+}
+
+func (r *ResourceType) withMetadata() *ResourceType {
+	resourceType := *r
+	resourceType.Schemas = []string{ResourceTypeSchema}
+	if r.Schema == UserSchema {
+		resourceType.SchemaExtensions = append(append([]SchemaExtension{}, r.SchemaExtensions...), getExtensionSchemaExtensions()...)
+	}
+	resourceType.Metadata = &Metadata{
+		Location:     getLocation("ResourceTypes", r.Name),
+		ResourceType: "ResourceType",
+	}
+	return &resourceType
+}
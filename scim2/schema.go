@@ -0,0 +1,180 @@
+package scim2
+
+import (
+	"bounzr/iam/config"
+	"fmt"
+)
+
+/**
+RFC7643 7. Schema Definition
+
+Attributes are described by their type and characteristics. The common attributes id, externalId and meta are part of
+every resource and are not included in the schemas.
+*/
+
+const (
+	UserSchema                  = "urn:ietf:params:scim:schemas:core:2.0:User"
+	GroupSchema                 = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	ClientSchema                = "org:bounzer:iam:scim2:1.0:Client"
+	ListResponseSchema          = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	ResourceTypeSchema          = "urn:ietf:params:scim:schemas:core:2.0:ResourceType"
+	SchemaSchema                = "urn:ietf:params:scim:schemas:core:2.0:Schema"
+	ServiceProviderConfigSchema = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+)
+
+//attribute types
+const (
+	BinaryType    = "binary"
+	BooleanType   = "boolean"
+	ComplexType   = "complex"
+	DateTimeType  = "dateTime"
+	DecimalType   = "decimal"
+	IntegerType   = "integer"
+	ReferenceType = "reference"
+	StringType    = "string"
+)
+
+//attribute mutability
+const (
+	ImmutableMutability = "immutable"
+	ReadOnlyMutability  = "readOnly"
+	ReadWriteMutability = "readWrite"
+	WriteOnlyMutability = "writeOnly"
+)
+
+//attribute returned characteristic
+const (
+	AlwaysReturned  = "always"
+	DefaultReturned = "default"
+	NeverReturned   = "never"
+	RequestReturned = "request"
+)
+
+//attribute uniqueness
+const (
+	GlobalUniqueness = "global"
+	NoneUniqueness   = "none"
+	ServerUniqueness = "server"
+)
+
+//Schema describes the attributes of a resource or of a resource extension
+type Schema struct {
+	Schemas     []string    `json:"schemas"`
+	ID          string      `json:"id"`
+	Name        string      `json:"name,omitempty"`
+	Description string      `json:"description,omitempty"`
+	Attributes  []Attribute `json:"attributes"`
+	Metadata    *Metadata   `json:"meta,omitempty"`
+}
+
+//Attribute describes an attribute of a schema and its characteristics
+type Attribute struct {
+	Name            string      `json:"name"`
+	Type            string      `json:"type"`
+	SubAttributes   []Attribute `json:"subAttributes,omitempty"`
+	MultiValued     bool        `json:"multiValued"`
+	Description     string      `json:"description,omitempty"`
+	Required        bool        `json:"required"`
+	CanonicalValues []string    `json:"canonicalValues,omitempty"`
+	CaseExact       bool        `json:"caseExact"`
+	Mutability      string      `json:"mutability"`
+	Returned        string      `json:"returned"`
+	Uniqueness      string      `json:"uniqueness"`
+	ReferenceTypes  []string    `json:"referenceTypes,omitempty"`
+}
+
+//newAttribute returns a single-valued, optional, case insensitive and not unique readWrite attribute returned by default
+func newAttribute(name string, attributeType string, description string) Attribute {
+	return Attribute{
+		Name:        name,
+		Type:        attributeType,
+		Description: description,
+		Mutability:  ReadWriteMutability,
+		Returned:    DefaultReturned,
+		Uniqueness:  NoneUniqueness,
+	}
+}
+
+func (a Attribute) canonical(values ...string) Attribute {
+	a.CanonicalValues = values
+	return a
+}
+
+func (a Attribute) caseExact() Attribute {
+	a.CaseExact = true
+	return a
+}
+
+func (a Attribute) multiValued() Attribute {
+	a.MultiValued = true
+	return a
+}
+
+func (a Attribute) mutability(mutability string) Attribute {
+	a.Mutability = mutability
+	return a
+}
+
+func (a Attribute) references(types ...string) Attribute {
+	a.ReferenceTypes = types
+	return a
+}
+
+func (a Attribute) required() Attribute {
+	a.Required = true
+	return a
+}
+
+func (a Attribute) returned(returned string) Attribute {
+	a.Returned = returned
+	return a
+}
+
+func (a Attribute) subAttributes(attributes ...Attribute) Attribute {
+	a.SubAttributes = attributes
+	return a
+}
+
+func (a Attribute) unique(uniqueness string) Attribute {
+	a.Uniqueness = uniqueness
+	return a
+}
+
+//GetSchemas returns the schemas supported by the server
+func GetSchemas() []Schema {
+	definitions := getSchemaDefinitions()
+	schemas := make([]Schema, 0, len(definitions))
+	for _, definition := range definitions {
+		schemas = append(schemas, *definition.withMetadata())
+	}
+	return schemas
+}
+
+//GetSchema returns the schema with the given id
+func GetSchema(id string) (*Schema, bool) {
+	for _, definition := range getSchemaDefinitions() {
+		if definition.ID == id {
+			return definition.withMetadata(), true
+		}
+	}
+	return nil, false
+}
+
+func (s *Schema) withMetadata() *Schema {
+	schema := *s
+	schema.Schemas = []string{SchemaSchema}
+	schema.Metadata = &Metadata{
+		Location:     getLocation("Schemas", s.ID),
+		ResourceType: "Schema",
+	}
+	return &schema
+}
+
+//getLocation returns the URI of a resource of the SCIM endpoints
+func getLocation(endpoint string, id string) string {
+	hostname := config.IAM.Server.Hostname
+	if len(id) == 0 {
+		return fmt.Sprintf("https://%s/scim2/%s", hostname, endpoint)
+	}
+	return fmt.Sprintf("https://%s/scim2/%s/%s", hostname, endpoint, id)
+}
@@ -0,0 +1,173 @@
+package scim2
+
+//schemaDefinitions are the schemas of the resources served by the SCIM endpoints
+var schemaDefinitions = []*Schema{
+	userSchemaDefinition,
+	groupSchemaDefinition,
+	clientSchemaDefinition,
+	enterpriseUserSchemaDefinition,
+}
+
+//RFC7643 4.1. "User" Resource Schema
+var userSchemaDefinition = &Schema{
+	ID:          UserSchema,
+	Name:        "User",
+	Description: "User Account",
+	Attributes: []Attribute{
+		newAttribute("userName", StringType, "Unique identifier for the User, typically used by the user to directly authenticate to the service provider").
+			required().unique(ServerUniqueness),
+		newAttribute("name", ComplexType, "The components of the user's real name").subAttributes(
+			newAttribute("formatted", StringType, "The full name, including all middle names, titles, and suffixes as appropriate, formatted for display"),
+			newAttribute("familyName", StringType, "The family name of the User, or last name in most Western languages"),
+			newAttribute("givenName", StringType, "The given name of the User, or first name in most Western languages"),
+			newAttribute("middleName", StringType, "The middle name(s) of the User"),
+			newAttribute("honorificPrefix", StringType, "The honorific prefix(es) of the User, or title in most Western languages"),
+			newAttribute("honorificSuffix", StringType, "The honorific suffix(es) of the User, or suffix in most Western languages"),
+		),
+		newAttribute("displayName", StringType, "The name of the User, suitable for display to end-users"),
+		newAttribute("nickName", StringType, "The casual way to address the user in real life"),
+		newAttribute("profileUrl", ReferenceType, "A fully qualified URL pointing to a page representing the User's online profile").
+			references("external"),
+		newAttribute("title", StringType, "The user's title, such as \"Vice President\""),
+		newAttribute("userType", StringType, "Used to identify the relationship between the organization and the user"),
+		newAttribute("preferredLanguage", StringType, "Indicates the User's preferred written or spoken language"),
+		newAttribute("locale", StringType, "Used to indicate the User's default location for purposes of localizing items such as currency, date time format, or numerical representations"),
+		newAttribute("timezone", StringType, "The User's time zone in the 'Olson' time zone database format, e.g., 'America/Los_Angeles'"),
+		newAttribute("active", BooleanType, "A Boolean value indicating the User's administrative status"),
+		newAttribute("password", StringType, "The User's cleartext password. It is only stored hashed").
+			mutability(WriteOnlyMutability).returned(NeverReturned).caseExact(),
+		newAttribute("emails", ComplexType, "Email addresses for the user").multiValued().subAttributes(
+			newAttribute("value", StringType, "Email addresses for the user"),
+			newAttribute("type", StringType, "A label indicating the attribute's function, e.g., 'work' or 'home'").
+				canonical("work", "home", "other"),
+			newAttribute("primary", BooleanType, "A Boolean value indicating the 'primary' or preferred attribute value for this attribute"),
+		),
+		newAttribute("phoneNumbers", ComplexType, "Phone numbers for the User").multiValued().subAttributes(
+			newAttribute("value", StringType, "Phone number of the User"),
+			newAttribute("type", StringType, "A label indicating the attribute's function, e.g., 'work', 'home', 'mobile'").
+				canonical("work", "home", "mobile", "fax", "pager", "other"),
+			newAttribute("primary", BooleanType, "A Boolean value indicating the 'primary' or preferred attribute value for this attribute"),
+		),
+		newAttribute("ims", ComplexType, "Instant messaging addresses for the User").multiValued().subAttributes(
+			newAttribute("value", StringType, "Instant messaging address for the User"),
+			newAttribute("type", StringType, "A label indicating the attribute's function, e.g., 'aim', 'gtalk', 'xmpp'").
+				canonical("aim", "gtalk", "icq", "xmpp", "msn", "skype", "qq", "yahoo"),
+			newAttribute("primary", BooleanType, "A Boolean value indicating the 'primary' or preferred attribute value for this attribute"),
+		),
+		newAttribute("photos", ComplexType, "URLs of photos of the User").multiValued().subAttributes(
+			newAttribute("value", ReferenceType, "URL of a photo of the User").references("external"),
+			newAttribute("type", StringType, "A label indicating the attribute's function, i.e., 'photo' or 'thumbnail'").
+				canonical("photo", "thumbnail"),
+			newAttribute("primary", BooleanType, "A Boolean value indicating the 'primary' or preferred attribute value for this attribute"),
+		),
+		newAttribute("addresses", ComplexType, "A physical mailing address for this User").multiValued().subAttributes(
+			newAttribute("formatted", StringType, "The full mailing address, formatted for display or use with a mailing label"),
+			newAttribute("streetAddress", StringType, "The full street address component"),
+			newAttribute("locality", StringType, "The city or locality component"),
+			newAttribute("region", StringType, "The state or region component"),
+			newAttribute("postalCode", StringType, "The zip code or postal code component"),
+			newAttribute("country", StringType, "The country name component"),
+			newAttribute("type", StringType, "A label indicating the attribute's function, e.g., 'work' or 'home'").
+				canonical("work", "home", "other"),
+			newAttribute("primary", BooleanType, "A Boolean value indicating the 'primary' or preferred attribute value for this attribute"),
+		),
+		newAttribute("groups", ComplexType, "A list of groups to which the user belongs").multiValued().
+			mutability(ReadOnlyMutability).subAttributes(
+			newAttribute("value", StringType, "The identifier of the User's group").mutability(ReadOnlyMutability),
+			newAttribute("$ref", ReferenceType, "The URI of the corresponding 'Group' resource to which the user belongs").
+				references("Group").mutability(ReadOnlyMutability),
+			newAttribute("display", StringType, "A human-readable name, primarily used for display purposes").
+				mutability(ReadOnlyMutability),
+		),
+		newAttribute("entitlements", StringType, "A list of entitlements for the User that represent a thing the User has").
+			multiValued(),
+		newAttribute("roles", StringType, "A list of roles for the User that collectively represent who the User is").
+			multiValued(),
+		newAttribute("x509Certificates", ComplexType, "A list of certificates issued to the User").multiValued().subAttributes(
+			newAttribute("value", BinaryType, "The value of an X.509 certificate"),
+			newAttribute("type", StringType, "A label indicating the attribute's function"),
+			newAttribute("primary", BooleanType, "A Boolean value indicating the 'primary' or preferred attribute value for this attribute"),
+		),
+	},
+}
+
+//RFC7643 4.2. "Group" Resource Schema
+var groupSchemaDefinition = &Schema{
+	ID:          GroupSchema,
+	Name:        "Group",
+	Description: "Group",
+	Attributes: []Attribute{
+		newAttribute("displayName", StringType, "A human-readable name for the Group").required(),
+		newAttribute("members", ComplexType, "A list of members of the Group").multiValued().subAttributes(
+			newAttribute("value", StringType, "Identifier of the member of this Group").mutability(ImmutableMutability),
+			newAttribute("$ref", ReferenceType, "The URI corresponding to a SCIM resource that is a member of this Group").
+				references("User", "Client").mutability(ImmutableMutability),
+			newAttribute("type", StringType, "A label indicating the type of resource").
+				canonical("User", "Client").mutability(ImmutableMutability),
+		),
+	},
+}
+
+//OAuth2 clients of the server. Attribute names follow RFC7591 client metadata
+var clientSchemaDefinition = &Schema{
+	ID:          ClientSchema,
+	Name:        "Client",
+	Description: "OAuth2 Client",
+	Attributes: []Attribute{
+		newAttribute("accessTokenFormat", StringType, "Format of the access tokens issued to the client. The server default is used if it is empty").
+			canonical("opaque", "jwt"),
+		newAttribute("clientName", StringType, "Human-readable name of the client to be presented to the end-user"),
+		newAttribute("clientSecretExpiresAt", IntegerType, "Time at which the client secret will expire, in seconds since the epoch, or 0 if it will not expire"),
+		newAttribute("clientUri", ReferenceType, "URL of a web page providing information about the client").references("external"),
+		newAttribute("contacts", StringType, "Ways to contact people responsible for this client, typically email addresses").multiValued(),
+		newAttribute("grantTypes", StringType, "OAuth 2.0 grant types that the client can use at the token endpoint").multiValued().caseExact().
+			canonical("authorization_code", "implicit", "password", "client_credentials", "refresh_token"),
+		newAttribute("groups", ComplexType, "A list of groups to which the client belongs").multiValued().
+			mutability(ReadOnlyMutability).subAttributes(
+			newAttribute("value", StringType, "The identifier of the client's group").mutability(ReadOnlyMutability),
+			newAttribute("$ref", ReferenceType, "The URI of the corresponding 'Group' resource to which the client belongs").
+				references("Group").mutability(ReadOnlyMutability),
+			newAttribute("display", StringType, "A human-readable name, primarily used for display purposes").
+				mutability(ReadOnlyMutability),
+		),
+		newAttribute("jwksUri", ReferenceType, "URL referencing the client's JSON Web Key Set document").references("external"),
+		newAttribute("jwks", StringType, "Client's JSON Web Key Set document value").caseExact(),
+		newAttribute("logoUri", ReferenceType, "URL that references a logo for the client").references("external"),
+		newAttribute("password", StringType, "The client secret. It is only returned when the client is created or its secret is rotated").
+			mutability(ReadOnlyMutability).returned(NeverReturned).caseExact(),
+		newAttribute("policyUri", ReferenceType, "URL that points to a human-readable privacy policy document").references("external"),
+		newAttribute("redirectUris", ReferenceType, "Redirection URIs for use in redirect-based flows").multiValued().caseExact().
+			references("external"),
+		newAttribute("requirePkce", BooleanType, "Authorization code requests of the client must include a RFC7636 code challenge"),
+		newAttribute("responseTypes", StringType, "OAuth 2.0 response types that the client can use at the authorization endpoint").
+			multiValued().caseExact().canonical("code", "token"),
+		newAttribute("scope", StringType, "Space-separated list of scope values that the client can use when requesting access tokens").
+			caseExact(),
+		newAttribute("softwareId", StringType, "Identifier for the software that implements the client").caseExact(),
+		newAttribute("softwareVersion", StringType, "Version identifier of the software that implements the client").caseExact(),
+		newAttribute("tokenEndpointAuthMethod", StringType, "Requested authentication method for the token endpoint").caseExact().
+			canonical("none", "client_secret_post", "client_secret_basic"),
+		newAttribute("tosUri", ReferenceType, "URL that points to a human-readable terms of service document").references("external"),
+	},
+}
+
+//RFC7643 4.3. Enterprise User Schema Extension
+var enterpriseUserSchemaDefinition = &Schema{
+	ID:          EnterpriseUserSchema,
+	Name:        "EnterpriseUser",
+	Description: "Enterprise User",
+	Attributes: []Attribute{
+		newAttribute("employeeNumber", StringType, "Numeric or alphanumeric identifier assigned to a person, typically based on order of hire or association with an organization"),
+		newAttribute("costCenter", StringType, "Identifies the name of a cost center"),
+		newAttribute("organization", StringType, "Identifies the name of an organization"),
+		newAttribute("division", StringType, "Identifies the name of a division"),
+		newAttribute("department", StringType, "Identifies the name of a department"),
+		newAttribute("manager", ComplexType, "The User's manager. The manager must be an existing user").subAttributes(
+			newAttribute("value", StringType, "The id of the SCIM resource representing the User's manager"),
+			newAttribute("$ref", ReferenceType, "The URI of the SCIM resource representing the User's manager").
+				references("User").mutability(ReadOnlyMutability),
+			newAttribute("displayName", StringType, "The displayName of the User's manager").
+				mutability(ReadOnlyMutability),
+		),
+	},
+}
@@ -0,0 +1,252 @@
+package scim2
+
+import (
+	"bounzr/iam/config"
+	"encoding/json"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+)
+
+/**
+Custom schema extensions of the user resource are declared in the configuration. Their attributes are of simple type and
+are returned as the value of the schema URI attribute of the user, as the enterprise user extension:
+	"urn:example:params:scim:schemas:extension:acme:2.0:User": {"badgeId": "B-1234", "contractor": true}
+*/
+
+//getSchemaDefinitions returns the schemas of the resources and of the configured user extensions
+func getSchemaDefinitions() []*Schema {
+	return append(append([]*Schema{}, schemaDefinitions...), getExtensionDefinitions()...)
+}
+
+//getExtensionDefinitions returns the schemas of the user extensions declared in the configuration
+func getExtensionDefinitions() []*Schema {
+	extensions := config.IAM.Scim.GetExtensions()
+	schemas := make([]*Schema, 0, len(extensions))
+	for _, extension := range extensions {
+		if isReservedSchema(extension.Schema) {
+			continue
+		}
+		schema := &Schema{
+			ID:          extension.Schema,
+			Name:        extension.Name,
+			Description: extension.Description,
+			Attributes:  make([]Attribute, 0, len(extension.Attributes)),
+		}
+		for _, a := range extension.Attributes {
+			attribute := newAttribute(a.Name, a.Type, a.Description).
+				mutability(a.Mutability).
+				unique(a.Uniqueness)
+			attribute.CanonicalValues = a.CanonicalValues
+			attribute.CaseExact = a.CaseExact
+			attribute.MultiValued = a.MultiValued
+			attribute.Required = a.Required
+			schema.Attributes = append(schema.Attributes, attribute)
+		}
+		schemas = append(schemas, schema)
+	}
+	return schemas
+}
+
+//getExtensionDefinition returns the configured user extension with the schema URI, which is case insensitive
+func getExtensionDefinition(id string) (*Schema, bool) {
+	for _, definition := range getExtensionDefinitions() {
+		if strings.EqualFold(definition.ID, id) {
+			return definition, true
+		}
+	}
+	return nil, false
+}
+
+//getExtensionSchemaExtensions returns the configured user extensions and if they are required
+func getExtensionSchemaExtensions() []SchemaExtension {
+	var schemaExtensions []SchemaExtension
+	for _, extension := range config.IAM.Scim.GetExtensions() {
+		if !isReservedSchema(extension.Schema) {
+			schemaExtensions = append(schemaExtensions, SchemaExtension{Schema: extension.Schema, Required: extension.Required})
+		}
+	}
+	return schemaExtensions
+}
+
+//isReservedSchema returns true if the schema is a core schema or an extension supported by the server
+func isReservedSchema(schema string) bool {
+	if strings.HasPrefix(strings.ToLower(schema+":"), coreSchemaPrefix) {
+		return true
+	}
+	for _, definition := range schemaDefinitions {
+		if strings.EqualFold(definition.ID, schema) {
+			return true
+		}
+	}
+	return false
+}
+
+//ValidateExtensions verifies the custom extensions of the user against their definitions. current is the stored user
+//when it is replaced or nil when it is created. Schema URIs and attribute names are normalized, read only attributes
+//keep their current values and immutable attributes can only be assigned once
+func (u *User) ValidateExtensions(current *User) error {
+	for schema := range u.Extensions {
+		if _, found := getExtensionDefinition(schema); !found {
+			return ErrBadRequestInvalidValue
+		}
+	}
+	extensions := make(ExtensionValues)
+	for _, definition := range getExtensionDefinitions() {
+		values := getExtensionValues(u.Extensions, definition.ID)
+		var currentValues map[string]interface{}
+		if current != nil {
+			currentValues = getExtensionValues(current.Extensions, definition.ID)
+		}
+		extension := make(map[string]interface{})
+		for name, value := range values {
+			attribute, found := findSchemaAttribute(definition, name)
+			if !found {
+				return ErrBadRequestInvalidValue
+			}
+			if value == nil || attribute.Mutability == ReadOnlyMutability {
+				continue
+			}
+			if err := attribute.validateValue(value); err != nil {
+				return err
+			}
+			extension[attribute.Name] = value
+		}
+		for _, attribute := range definition.Attributes {
+			currentValue, assigned := getValue(currentValues, attribute.Name)
+			assigned = assigned && currentValue != nil
+			switch attribute.Mutability {
+			case ReadOnlyMutability:
+				if assigned {
+					extension[attribute.Name] = currentValue
+				}
+			case ImmutableMutability:
+				value, found := extension[attribute.Name]
+				if assigned && !found {
+					extension[attribute.Name] = currentValue
+				} else if assigned && !reflect.DeepEqual(value, currentValue) {
+					return ErrBadRequestMutability
+				}
+			}
+		}
+		if len(extension) == 0 {
+			if isRequiredExtension(definition.ID) {
+				return ErrBadRequestInvalidValue
+			}
+			continue
+		}
+		for _, attribute := range definition.Attributes {
+			if _, found := extension[attribute.Name]; attribute.Required && attribute.Mutability != ReadOnlyMutability && !found {
+				return ErrBadRequestInvalidValue
+			}
+		}
+		extensions[definition.ID] = extension
+	}
+	u.Extensions = extensions
+	return nil
+}
+
+//GetUniqueExtensionValues returns the values of the extension attributes with server uniqueness by attribute path.
+//The values are JSON encoded, strings in lower case unless the attribute is case exact, so that equal values match
+func (u *User) GetUniqueExtensionValues() map[string][]string {
+	unique := make(map[string][]string)
+	for _, definition := range getExtensionDefinitions() {
+		values := getExtensionValues(u.Extensions, definition.ID)
+		for _, attribute := range definition.Attributes {
+			value, found := getValue(values, attribute.Name)
+			if !found || value == nil || attribute.Uniqueness == NoneUniqueness {
+				continue
+			}
+			list, ok := value.([]interface{})
+			if !ok {
+				list = []interface{}{value}
+			}
+			path := definition.ID + ":" + attribute.Name
+			for _, item := range list {
+				if s, ok := item.(string); ok && !attribute.CaseExact {
+					item = strings.ToLower(s)
+				}
+				encoded, err := json.Marshal(item)
+				if err != nil {
+					continue
+				}
+				unique[path] = append(unique[path], string(encoded))
+			}
+		}
+	}
+	return unique
+}
+
+func getExtensionValues(extensions ExtensionValues, schema string) map[string]interface{} {
+	for id, values := range extensions {
+		if strings.EqualFold(id, schema) {
+			return values
+		}
+	}
+	return nil
+}
+
+func isRequiredExtension(schema string) bool {
+	for _, extension := range getExtensionSchemaExtensions() {
+		if strings.EqualFold(extension.Schema, schema) {
+			return extension.Required
+		}
+	}
+	return false
+}
+
+func findSchemaAttribute(schema *Schema, name string) (Attribute, bool) {
+	return findAttributeDefinition(schema.Attributes, name)
+}
+
+//validateValue verifies the json value matches the type, plurality and canonical values of the attribute
+func (a Attribute) validateValue(value interface{}) error {
+	if !a.MultiValued {
+		return a.validateSingleValue(value)
+	}
+	values, ok := value.([]interface{})
+	if !ok {
+		return ErrBadRequestInvalidValue
+	}
+	for _, v := range values {
+		if err := a.validateSingleValue(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a Attribute) validateSingleValue(value interface{}) error {
+	valid := false
+	switch a.Type {
+	case BooleanType:
+		_, valid = value.(bool)
+	case DecimalType:
+		_, valid = value.(float64)
+	case IntegerType:
+		number, ok := value.(float64)
+		valid = ok && number == math.Trunc(number)
+	case DateTimeType:
+		s, ok := value.(string)
+		if ok {
+			_, err := time.Parse(time.RFC3339, s)
+			valid = err == nil
+		}
+	default:
+		_, valid = value.(string)
+	}
+	if !valid {
+		return ErrBadRequestInvalidValue
+	}
+	if len(a.CanonicalValues) == 0 {
+		return nil
+	}
+	s, _ := value.(string)
+	for _, canonical := range a.CanonicalValues {
+		if s == canonical || (!a.CaseExact && strings.EqualFold(s, canonical)) {
+			return nil
+		}
+	}
+	return ErrBadRequestInvalidValue
+}
@@ -0,0 +1,96 @@
+package scim2
+
+import (
+	"bounzr/iam/config"
+	"encoding/json"
+	"testing"
+)
+
+const acmeSchema = "urn:example:params:scim:schemas:extension:acme:2.0:User"
+
+var acmeExtension = config.ScimExtension{
+	Schema: acmeSchema,
+	Attributes: []config.ScimAttribute{
+		{Name: "badgeId", Required: true, Uniqueness: "server"},
+		{Name: "contractor", Type: "boolean"},
+		{Name: "costCodes", MultiValued: true, CanonicalValues: []string{"CC-100", "CC-200"}},
+		{Name: "hireDate", Type: "dateTime", Mutability: "immutable"},
+		{Name: "level", Type: "integer", Mutability: "readOnly"},
+	},
+}
+
+type ExtensionDataProvider struct {
+	user    string
+	current string
+	err     error
+}
+
+var extensionDataProvider = []ExtensionDataProvider{
+	{`{"userName":"bjensen"}`, ``, nil},
+	{`{"userName":"bjensen","urn:example:params:scim:schemas:extension:acme:2.0:User":{"badgeId":"B-1","contractor":true,"costCodes":["cc-100"],"hireDate":"2019-05-13T04:42:34Z"}}`, ``, nil},
+	{`{"userName":"bjensen","urn:example:params:scim:schemas:extension:acme:2.0:User":{"contractor":true}}`, ``, ErrBadRequestInvalidValue},
+	{`{"userName":"bjensen","urn:example:params:scim:schemas:extension:acme:2.0:User":{"badgeId":1}}`, ``, ErrBadRequestInvalidValue},
+	{`{"userName":"bjensen","urn:example:params:scim:schemas:extension:acme:2.0:User":{"badgeId":"B-1","costCodes":["CC-300"]}}`, ``, ErrBadRequestInvalidValue},
+	{`{"userName":"bjensen","urn:example:params:scim:schemas:extension:acme:2.0:User":{"badgeId":"B-1","costCodes":"CC-100"}}`, ``, ErrBadRequestInvalidValue},
+	{`{"userName":"bjensen","urn:example:params:scim:schemas:extension:acme:2.0:User":{"badgeId":"B-1","hireDate":"yesterday"}}`, ``, ErrBadRequestInvalidValue},
+	{`{"userName":"bjensen","urn:example:params:scim:schemas:extension:acme:2.0:User":{"badgeId":"B-1","shoeSize":42}}`, ``, ErrBadRequestInvalidValue},
+	{`{"userName":"bjensen","urn:example:params:scim:schemas:extension:other:2.0:User":{"badgeId":"B-1"}}`, ``, ErrBadRequestInvalidValue},
+	{`{"userName":"bjensen","urn:example:params:scim:schemas:extension:acme:2.0:User":{"badgeId":"B-1","hireDate":"2020-01-01T00:00:00Z"}}`,
+		`{"userName":"bjensen","urn:example:params:scim:schemas:extension:acme:2.0:User":{"badgeId":"B-1","hireDate":"2019-05-13T04:42:34Z"}}`, ErrBadRequestMutability},
+	{`{"userName":"bjensen","urn:example:params:scim:schemas:extension:acme:2.0:User":{"badgeId":"B-2"}}`,
+		`{"userName":"bjensen","urn:example:params:scim:schemas:extension:acme:2.0:User":{"badgeId":"B-1","hireDate":"2019-05-13T04:42:34Z"}}`, nil},
+}
+
+func TestValidateExtensions(t *testing.T) {
+	defer func(extensions []config.ScimExtension) { config.IAM.Scim.Extensions = extensions }(config.IAM.Scim.Extensions)
+	config.IAM.Scim.Extensions = []config.ScimExtension{acmeExtension}
+	for _, provider := range extensionDataProvider {
+		user := &User{}
+		if err := json.Unmarshal([]byte(provider.user), user); err != nil {
+			t.Fatalf("%s: can not decode user: %s", provider.user, err.Error())
+		}
+		var current *User
+		if len(provider.current) > 0 {
+			current = &User{}
+			json.Unmarshal([]byte(provider.current), current)
+		}
+		if err := user.ValidateExtensions(current); err != provider.err {
+			t.Errorf("%s: want %v, got %v", provider.user, provider.err, err)
+		}
+	}
+}
+
+func TestUserExtensionValues(t *testing.T) {
+	defer func(extensions []config.ScimExtension) { config.IAM.Scim.Extensions = extensions }(config.IAM.Scim.Extensions)
+	config.IAM.Scim.Extensions = []config.ScimExtension{acmeExtension}
+	current := &User{Extensions: ExtensionValues{acmeSchema: {"badgeId": "B-1", "level": float64(3), "hireDate": "2019-05-13T04:42:34Z"}}}
+	user := &User{}
+	data := `{"userName":"bjensen","URN:EXAMPLE:params:scim:schemas:extension:acme:2.0:User":{"BADGEID":"B-2","level":9}}`
+	if err := json.Unmarshal([]byte(data), user); err != nil {
+		t.Fatalf("can not decode user: %s", err.Error())
+	}
+	if err := user.ValidateExtensions(current); err != nil {
+		t.Fatalf("want no error, got %s", err.Error())
+	}
+	//schema URI and names are normalized, read only and immutable attributes keep their values
+	values := user.Extensions[acmeSchema]
+	if values["badgeId"] != "B-2" || values["level"] != float64(3) || values["hireDate"] != "2019-05-13T04:42:34Z" {
+		t.Errorf("unexpected extension values %v", values)
+	}
+	unique := user.GetUniqueExtensionValues()
+	if len(unique) != 1 || unique[acmeSchema+":badgeId"][0] != `"b-2"` {
+		t.Errorf("want unique badgeId B-2, got %v", unique)
+	}
+	filter, _ := ParseFilter(`urn:example:params:scim:schemas:extension:acme:2.0:User:badgeId eq "b-2"`)
+	if !filter.Matches(user) {
+		t.Errorf("want extension attribute filtered")
+	}
+	schema, ok := GetSchema(acmeSchema)
+	if !ok || len(schema.Attributes) != 5 || !schema.Attributes[0].Required || schema.Attributes[4].Mutability != ReadOnlyMutability {
+		t.Errorf("want acme schema with 5 attributes, got %+v", schema)
+	}
+	resourceType, _ := GetResourceType("User")
+	if len(resourceType.SchemaExtensions) != 2 || resourceType.SchemaExtensions[1].Schema != acmeSchema {
+		t.Errorf("want enterprise and acme user extensions, got %+v", resourceType.SchemaExtensions)
+	}
+}
@@ -0,0 +1,108 @@
+package scim2
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var commonAttributes = []string{"id", "externalId", "meta", "schemas"}
+
+func TestResourceTypeSchemas(t *testing.T) {
+	resources := map[string]interface{}{
+		"User":   User{},
+		"Group":  Group{},
+		"Client": Client{},
+	}
+	for _, resourceType := range GetResourceTypes() {
+		schema, ok := GetSchema(resourceType.Schema)
+		if !ok {
+			t.Errorf("%s: schema %s not found", resourceType.Name, resourceType.Schema)
+			continue
+		}
+		resource, ok := resources[resourceType.Name]
+		if !ok {
+			t.Errorf("%s: unexpected resource type", resourceType.Name)
+			continue
+		}
+		//every attribute of the resource must be described by its schema
+		resourceStruct := reflect.TypeOf(resource)
+		for i := 0; i < resourceStruct.NumField(); i++ {
+			name := strings.Split(resourceStruct.Field(i).Tag.Get("json"), ",")[0]
+			if isCommonAttribute(name) || name == "-" {
+				continue
+			}
+			if isSchemaExtension(resourceType, name) {
+				if _, ok := GetSchema(name); !ok {
+					t.Errorf("%s: extension schema %s not found", resourceType.Name, name)
+				}
+				continue
+			}
+			if _, ok := findAttribute(schema.Attributes, name); !ok {
+				t.Errorf("%s: attribute %s not described by the schema", resourceType.Name, name)
+			}
+		}
+		verifyAttributes(t, schema.ID, schema.Attributes)
+	}
+}
+
+func TestUserSchemaCharacteristics(t *testing.T) {
+	schema, ok := GetSchema(UserSchema)
+	if !ok {
+		t.Fatalf("user schema not found")
+	}
+	userName, _ := findAttribute(schema.Attributes, "userName")
+	if !userName.Required || userName.Uniqueness != ServerUniqueness {
+		t.Errorf("userName: want required and server unique, got %v and %s", userName.Required, userName.Uniqueness)
+	}
+	password, _ := findAttribute(schema.Attributes, "password")
+	if password.Mutability != WriteOnlyMutability || password.Returned != NeverReturned {
+		t.Errorf("password: want writeOnly and never returned, got %s and %s", password.Mutability, password.Returned)
+	}
+	groups, _ := findAttribute(schema.Attributes, "groups")
+	if groups.Mutability != ReadOnlyMutability {
+		t.Errorf("groups: want readOnly, got %s", groups.Mutability)
+	}
+	if _, ok := GetSchema("urn:ietf:params:scim:schemas:core:2.0:Unknown"); ok {
+		t.Errorf("unknown schema: want not found")
+	}
+}
+
+func findAttribute(attributes []Attribute, name string) (Attribute, bool) {
+	for _, attribute := range attributes {
+		if attribute.Name == name {
+			return attribute, true
+		}
+	}
+	return Attribute{}, false
+}
+
+func isCommonAttribute(name string) bool {
+	for _, common := range commonAttributes {
+		if name == common {
+			return true
+		}
+	}
+	return false
+}
+
+func isSchemaExtension(resourceType ResourceType, name string) bool {
+	for _, extension := range resourceType.SchemaExtensions {
+		if extension.Schema == name {
+			return true
+		}
+	}
+	return false
+}
+
+func verifyAttributes(t *testing.T, schema string, attributes []Attribute) {
+	for _, attribute := range attributes {
+		if len(attribute.Type) == 0 || len(attribute.Mutability) == 0 || len(attribute.Returned) == 0 || len(attribute.Uniqueness) == 0 {
+			t.Errorf("%s: attribute %s has undefined characteristics", schema, attribute.Name)
+		}
+		if len(attribute.SubAttributes) > 0 && attribute.Type != ComplexType {
+			t.Errorf("%s: attribute %s has sub-attributes but is not complex", schema, attribute.Name)
+		}
+		verifyAttributes(t, schema, attribute.SubAttributes)
+	}
+}
@@ -0,0 +1,63 @@
+package scim2
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+/**
+RFC7644 3.4.3. Querying Resources Using HTTP POST
+
+Queries are sent in the body of a POST request to the ".search" endpoints so that long filters are not limited by the
+length of the URI. The parameters have the same meaning as the query parameters of a GET request.
+*/
+
+const (
+	SearchRequestSchema = "urn:ietf:params:scim:api:messages:2.0:SearchRequest"
+)
+
+//SearchRequest is the body of a POST request to a ".search" endpoint
+type SearchRequest struct {
+	Schemas            []string `json:"schemas"`
+	Attributes         []string `json:"attributes,omitempty"`
+	ExcludedAttributes []string `json:"excludedAttributes,omitempty"`
+	Filter             string   `json:"filter,omitempty"`
+	SortBy             string   `json:"sortBy,omitempty"`
+	SortOrder          string   `json:"sortOrder,omitempty"`
+	StartIndex         int      `json:"startIndex,omitempty"`
+	Count              *int     `json:"count,omitempty"` //nil if not given, as 0 requests no resources
+}
+
+//GetListQuery returns the list query of the search request. maxResults is used when count is not given or is greater
+//than maxResults
+func (s *SearchRequest) GetListQuery(maxResults int) (*ListQuery, error) {
+	if !s.hasSchema() {
+		return nil, ErrBadRequestInvalidSyntax
+	}
+	values := url.Values{}
+	values.Set("filter", s.Filter)
+	values.Set("sortBy", s.SortBy)
+	values.Set("sortOrder", s.SortOrder)
+	if s.StartIndex != 0 {
+		values.Set("startIndex", strconv.Itoa(s.StartIndex))
+	}
+	if s.Count != nil {
+		values.Set("count", strconv.Itoa(*s.Count))
+	}
+	return NewListQuery(values, maxResults)
+}
+
+//GetProjection returns the projection of the attributes and excludedAttributes of the search request
+func (s *SearchRequest) GetProjection() (*Projection, error) {
+	return NewProjection(s.Attributes, s.ExcludedAttributes)
+}
+
+func (s *SearchRequest) hasSchema() bool {
+	for _, schema := range s.Schemas {
+		if strings.EqualFold(schema, SearchRequestSchema) {
+			return true
+		}
+	}
+	return false
+}
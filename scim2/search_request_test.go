@@ -0,0 +1,66 @@
+package scim2
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type SearchRequestDataProvider struct {
+	body       string
+	err        error
+	startIndex int
+	count      int
+}
+
+var searchRequestDataProvider = []SearchRequestDataProvider{
+	{`{"schemas":["urn:ietf:params:scim:api:messages:2.0:SearchRequest"]}`, nil, 1, 100},
+	{`{"schemas":["urn:ietf:params:scim:api:messages:2.0:SearchRequest"],"startIndex":10,"count":5}`, nil, 10, 5},
+	{`{"schemas":["urn:ietf:params:scim:api:messages:2.0:SearchRequest"],"count":0}`, nil, 1, 0},
+	{`{"schemas":["urn:ietf:params:scim:api:messages:2.0:SearchRequest"],"count":500}`, nil, 1, 100},
+	{`{"schemas":["urn:ietf:params:scim:api:messages:2.0:SearchRequest"],"sortBy":"userName","sortOrder":"descending"}`, nil, 1, 100},
+	{`{"schemas":["urn:ietf:params:scim:api:messages:2.0:SearchRequest"],"sortOrder":"random"}`, ErrBadRequestInvalidValue, 0, 0},
+	{`{"schemas":["urn:ietf:params:scim:api:messages:2.0:SearchRequest"],"filter":"userName xx \"bjensen\""}`, ErrBadRequestInvalidFilter, 0, 0},
+	{`{"filter":"userName eq \"bjensen\""}`, ErrBadRequestInvalidSyntax, 0, 0},
+}
+
+func TestSearchRequestGetListQuery(t *testing.T) {
+	for _, provider := range searchRequestDataProvider {
+		searchReq := &SearchRequest{}
+		if err := json.Unmarshal([]byte(provider.body), searchReq); err != nil {
+			t.Fatalf("%s: can not decode search request: %s", provider.body, err.Error())
+		}
+		query, err := searchReq.GetListQuery(100)
+		if err != provider.err {
+			t.Errorf("%s: want error %v, got %v", provider.body, provider.err, err)
+			continue
+		}
+		if err == nil && (query.StartIndex != provider.startIndex || query.Count != provider.count) {
+			t.Errorf("%s: want startIndex %d and count %d, got %d and %d", provider.body, provider.startIndex, provider.count, query.StartIndex, query.Count)
+		}
+	}
+}
+
+func TestSearchRequestLongFilter(t *testing.T) {
+	var expressions []string
+	for i := 0; i < 500; i++ {
+		expressions = append(expressions, fmt.Sprintf(`emails.value eq "user%d@example.com"`, i))
+	}
+	searchReq := &SearchRequest{
+		Schemas:    []string{SearchRequestSchema},
+		Filter:     strings.Join(expressions, " or "),
+		Attributes: []string{"userName", "emails"},
+	}
+	query, err := searchReq.GetListQuery(100)
+	if err != nil {
+		t.Fatalf("want no error, got %s", err.Error())
+	}
+	user := &User{UserName: "bjensen", Emails: []MultiValueAttribute{{Value: "USER499@example.com"}}}
+	if !query.Filter.Matches(user) {
+		t.Errorf("want user matching the filter")
+	}
+	if _, err = searchReq.GetProjection(); err != nil {
+		t.Errorf("want no error, got %s", err.Error())
+	}
+}
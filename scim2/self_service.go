@@ -0,0 +1,121 @@
+package scim2
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+/**
+Users without administrative rights can only modify the attributes of their own profile. The other attributes, like
+userName, active, roles, entitlements or groups, are managed by the administrators.
+*/
+
+//MeScope is the scope the access tokens must be granted to read and modify the resource of their owner at "/Me"
+const MeScope = "scim:me"
+
+//selfServiceAttributes are the lowercase names of the user attributes that users can modify themselves
+var selfServiceAttributes = map[string]struct{}{
+	"addresses":         {},
+	"displayname":       {},
+	"emails":            {},
+	"ims":               {},
+	"locale":            {},
+	"name":              {},
+	"nickname":          {},
+	"password":          {},
+	"phonenumbers":      {},
+	"photos":            {},
+	"preferredlanguage": {},
+	"profileurl":        {},
+	"timezone":          {},
+}
+
+//IsSelfService returns true if the operations only modify attributes users can modify themselves
+func (p *PatchOp) IsSelfService() bool {
+	for _, operation := range p.Operations {
+		if len(strings.TrimSpace(operation.Path)) > 0 {
+			if !isSelfServicePath(operation.Path) {
+				return false
+			}
+			continue
+		}
+		attributes, ok := operation.Value.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for name := range attributes {
+			//read only attributes are ignored by operations without path
+			if _, readOnly := userMutability.readOnly[strings.ToLower(name)]; readOnly {
+				continue
+			}
+			if !isSelfServicePath(name) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+//ModifiesPassword returns true if an operation sets or removes the password
+func (p *PatchOp) ModifiesPassword() bool {
+	for _, operation := range p.Operations {
+		if len(strings.TrimSpace(operation.Path)) > 0 {
+			patchPath, err := parsePatchPath(strings.TrimSpace(operation.Path))
+			if err != nil || strings.EqualFold(patchPath.attribute.names[0], "password") {
+				return true
+			}
+			continue
+		}
+		attributes, ok := operation.Value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for name := range attributes {
+			if strings.EqualFold(name, "password") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+//GetSelfServiceUser returns the user with the self-service attributes of the request and the other attributes of the
+//current user
+func GetSelfServiceUser(current *User, request *User) (*User, error) {
+	values, ok := getResourceValues(current)
+	if !ok {
+		return nil, ErrInternalError
+	}
+	requested, ok := getResourceValues(request)
+	if !ok {
+		return nil, ErrBadRequestInvalidValue
+	}
+	for name := range values {
+		if _, found := selfServiceAttributes[strings.ToLower(name)]; found {
+			delete(values, name)
+		}
+	}
+	for name, value := range requested {
+		if _, found := selfServiceAttributes[strings.ToLower(name)]; found {
+			values[name] = value
+		}
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		return nil, ErrBadRequestInvalidValue
+	}
+	user := &User{}
+	if err = json.Unmarshal(data, user); err != nil {
+		return nil, ErrBadRequestInvalidValue
+	}
+	return user, nil
+}
+
+func isSelfServicePath(path string) bool {
+	patchPath, err := parsePatchPath(strings.TrimSpace(path))
+	if err != nil || len(patchPath.attribute.uri) > 0 {
+		return false
+	}
+	_, found := selfServiceAttributes[strings.ToLower(patchPath.attribute.names[0])]
+	return found
+}
@@ -0,0 +1,83 @@
+package scim2
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+type SelfServiceDataProvider struct {
+	operations  string
+	selfService bool
+}
+
+var selfServiceDataProvider = []SelfServiceDataProvider{
+	{`[{"op":"replace","path":"name.givenName","value":"Barbara"}]`, true},
+	{`[{"op":"add","path":"emails","value":[{"value":"bjensen@example.com","type":"home"}]}]`, true},
+	{`[{"op":"remove","path":"phoneNumbers[type eq \"work\"]"}]`, true},
+	{`[{"op":"replace","value":{"id":"2819c223","password":"t1meMa$heen","nickName":"Babs"}}]`, true},
+	{`[{"op":"replace","path":"active","value":false}]`, false},
+	{`[{"op":"replace","path":"nickName","value":"Babs"},{"op":"add","path":"roles","value":["admin"]}]`, false},
+	{`[{"op":"replace","value":{"userName":"admin"}}]`, false},
+	{`[{"op":"add","path":"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:manager","value":"26118915"}]`, false},
+}
+
+func TestPatchIsSelfService(t *testing.T) {
+	for _, provider := range selfServiceDataProvider {
+		patch := &PatchOp{Schemas: []string{PatchOpSchema}}
+		if err := json.Unmarshal([]byte(provider.operations), &patch.Operations); err != nil {
+			t.Fatalf("%s: can not decode operations: %s", provider.operations, err.Error())
+		}
+		if selfService := patch.IsSelfService(); selfService != provider.selfService {
+			t.Errorf("%s: want %v, got %v", provider.operations, provider.selfService, selfService)
+		}
+	}
+}
+
+func TestPatchModifiesPassword(t *testing.T) {
+	dataProvider := map[string]bool{
+		`[{"op":"replace","path":"password","value":"t1meMa$heen"}]`:               true,
+		`[{"op":"remove","path":"Password"}]`:                                      true,
+		`[{"op":"replace","value":{"nickName":"Babs","password":"t1meMa$heen"}}]`:  true,
+		`[{"op":"replace","path":"nickName","value":"Babs"}]`:                      false,
+		`[{"op":"add","path":"emails","value":[{"value":"bjensen@example.com"}]}]`: false,
+	}
+	for operations, modified := range dataProvider {
+		patch := &PatchOp{Schemas: []string{PatchOpSchema}}
+		if err := json.Unmarshal([]byte(operations), &patch.Operations); err != nil {
+			t.Fatalf("%s: can not decode operations: %s", operations, err.Error())
+		}
+		if got := patch.ModifiesPassword(); got != modified {
+			t.Errorf("%s: want %v, got %v", operations, modified, got)
+		}
+	}
+}
+
+func TestGetSelfServiceUser(t *testing.T) {
+	current := &User{
+		ID:       "2819c223",
+		UserName: "bjensen",
+		Active:   true,
+		NickName: "Babs",
+		Roles:    []string{"employee"},
+		Title:    "Tour Guide",
+	}
+	request := &User{
+		UserName:    "admin",
+		DisplayName: "Barbara Jensen",
+		Roles:       []string{"admin"},
+		Password:    "t1meMa$heen",
+	}
+	user, err := GetSelfServiceUser(current, request)
+	if err != nil {
+		t.Fatalf("want no error, got %s", err.Error())
+	}
+	if user.UserName != "bjensen" || !user.Active || len(user.Roles) != 1 || user.Roles[0] != "employee" || user.Title != "Tour Guide" {
+		t.Errorf("attributes managed by administrators were modified: %+v", user)
+	}
+	if user.DisplayName != "Barbara Jensen" || user.Password != "t1meMa$heen" {
+		t.Errorf("self-service attributes were not replaced: %+v", user)
+	}
+	if len(user.NickName) > 0 {
+		t.Errorf("nickName: want removed, got %s", user.NickName)
+	}
+}
@@ -0,0 +1,90 @@
+package scim2
+
+import "bounzr/iam/config"
+
+/**
+RFC7643 5. Service Provider Configuration Schema
+
+The features advertised are the ones implemented by the SCIM endpoints.
+*/
+
+//ServiceProviderConfig describes the SCIM specification features available on the service provider
+type ServiceProviderConfig struct {
+	Schemas               []string               `json:"schemas"`
+	DocumentationURI      string                 `json:"documentationUri,omitempty"`
+	Patch                 Feature                `json:"patch"`
+	Bulk                  BulkFeature            `json:"bulk"`
+	Filter                FilterFeature          `json:"filter"`
+	ChangePassword        Feature                `json:"changePassword"`
+	Sort                  Feature                `json:"sort"`
+	Etag                  Feature                `json:"etag"`
+	AuthenticationSchemes []AuthenticationScheme `json:"authenticationSchemes"`
+	Metadata              *Metadata              `json:"meta,omitempty"`
+}
+
+//Feature tells if an optional feature is supported
+type Feature struct {
+	Supported bool `json:"supported"`
+}
+
+//BulkFeature tells if bulk operations are supported and their limits
+type BulkFeature struct {
+	Supported      bool `json:"supported"`
+	MaxOperations  int  `json:"maxOperations"`
+	MaxPayloadSize int  `json:"maxPayloadSize"`
+}
+
+//FilterFeature tells if filtering is supported and the maximum number of resources returned in a response
+type FilterFeature struct {
+	Supported  bool `json:"supported"`
+	MaxResults int  `json:"maxResults"`
+}
+
+//AuthenticationScheme describes an authentication scheme accepted by the SCIM endpoints
+type AuthenticationScheme struct {
+	Type             string `json:"type"`
+	Name             string `json:"name"`
+	Description      string `json:"description"`
+	SpecURI          string `json:"specUri,omitempty"`
+	DocumentationURI string `json:"documentationUri,omitempty"`
+	Primary          bool   `json:"primary,omitempty"`
+}
+
+//GetServiceProviderConfig returns the configuration of the SCIM endpoints
+func GetServiceProviderConfig() *ServiceProviderConfig {
+	return &ServiceProviderConfig{
+		Schemas: []string{ServiceProviderConfigSchema},
+		Patch:   Feature{Supported: true},
+		Bulk: BulkFeature{
+			Supported:      true,
+			MaxOperations:  config.IAM.Scim.GetBulkMaxOperations(),
+			MaxPayloadSize: config.IAM.Scim.GetBulkMaxPayloadSize(),
+		},
+		Filter: FilterFeature{
+			Supported:  true,
+			MaxResults: config.IAM.Scim.GetMaxResults(),
+		},
+		ChangePassword: Feature{Supported: true},
+		Sort:           Feature{Supported: true},
+		Etag:           Feature{Supported: true},
+		AuthenticationSchemes: []AuthenticationScheme{
+			{
+				Type:        "httpbasic",
+				Name:        "HTTP Basic",
+				Description: "Authentication scheme using the HTTP Basic Standard",
+				SpecURI:     "https://tools.ietf.org/html/rfc7617",
+				Primary:     true,
+			},
+			{
+				Type:        "oauthbearertoken",
+				Name:        "OAuth Bearer Token",
+				Description: "Authentication scheme using the OAuth Bearer Token Standard, only accepted by the /Me endpoint",
+				SpecURI:     "https://tools.ietf.org/html/rfc6750",
+			},
+		},
+		Metadata: &Metadata{
+			Location:     getLocation("ServiceProviderConfig", ""),
+			ResourceType: "ServiceProviderConfig",
+		},
+	}
+}
@@ -1,11 +1,18 @@
 package scim2
 
+import (
+	"encoding/json"
+	"strings"
+)
+
 type User struct {
 	Active            bool                  `json:"active,omitempty"`
 	Addresses         []Address             `json:"addresses,omitempty"`
 	DisplayName       string                `json:"displayName,omitempty"` //The name of the user, suitable for display to end-users
 	Entitlements      []string              `json:"entitlements,omitempty"`
 	Emails            []MultiValueAttribute `json:"emails,omitempty"`
+	EnterpriseUser    *EnterpriseUser       `json:"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User,omitempty"`
+	Extensions        ExtensionValues       `json:"-"`                    //custom schema extensions
 	ExternalId        string                `json:"externalId,omitempty"` //A String that is an identifier for the resource as defined by the provisioning client. This identifier MUST be unique across the SCIM service provider’s entire set of resources
 	Groups            []GroupAssignment     `json:"groups,omitempty"`
 	ID                string                `json:"id"` // A unique identifier for a SCIM resource as defined by the service provider
@@ -28,6 +35,9 @@ type User struct {
 	X509Certificates  []MultiValueAttribute `json:"x509Certificates,omitempty"`
 }
 
+//ExtensionValues are the attribute values of the custom schema extensions by schema URI
+type ExtensionValues map[string]map[string]interface{}
+
 type Address struct {
 	Country       string `json:"country,omitempty"`
 	Formatted     string `json:"formatted,omitempty"`
@@ -61,3 +71,183 @@ func (u *User) GetGroups() []GroupAssignment {
 func (u *User) GetID() string {
 	return u.ID
 }
+
+//jsonUser is the user without its json methods
+type jsonUser User
+
+//MarshalJSON adds the custom schema extensions to the json representation of the user
+func (u User) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(jsonUser(u))
+	if err != nil || len(u.Extensions) == 0 {
+		return data, err
+	}
+	values := make(map[string]interface{})
+	if err = json.Unmarshal(data, &values); err != nil {
+		return nil, err
+	}
+	for schema, extension := range u.Extensions {
+		values[schema] = extension
+	}
+	return json.Marshal(values)
+}
+
+//UnmarshalJSON reads the custom schema extensions, which are the schema URI attributes other than the enterprise
+//user extension
+func (u *User) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, (*jsonUser)(u)); err != nil {
+		return err
+	}
+	values := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+	u.Extensions = nil
+	for name, value := range values {
+		if !strings.HasPrefix(strings.ToLower(name), "urn:") || isReservedSchema(name) {
+			continue
+		}
+		extension := make(map[string]interface{})
+		if err := json.Unmarshal(value, &extension); err != nil {
+			return ErrBadRequestInvalidSyntax
+		}
+		if u.Extensions == nil {
+			u.Extensions = make(ExtensionValues)
+		}
+		u.Extensions[name] = extension
+	}
+	return nil
+}
+
+//GetValues returns the attribute values of the json representation of the user, as decoded by the filters, without
+//encoding the user to json
+func (u *User) GetValues() map[string]interface{} {
+	values := resourceValues{"id": u.ID, "userName": u.UserName}
+	values.addBool("active", u.Active)
+	if len(u.Addresses) > 0 {
+		addresses := make([]interface{}, len(u.Addresses))
+		for i, address := range u.Addresses {
+			value := resourceValues{}
+			value.addString("country", address.Country)
+			value.addString("formatted", address.Formatted)
+			value.addString("locality", address.Locality)
+			value.addString("postalCode", address.PostalCode)
+			value.addBool("primary", address.Primary)
+			value.addString("region", address.Region)
+			value.addString("streetAddress", address.StreetAddress)
+			value.addString("type", address.Type)
+			addresses[i] = map[string]interface{}(value)
+		}
+		values["addresses"] = addresses
+	}
+	values.addString("displayName", u.DisplayName)
+	values.addStrings("entitlements", u.Entitlements)
+	values.addMultiValues("emails", u.Emails)
+	if u.EnterpriseUser != nil {
+		enterprise := resourceValues{}
+		enterprise.addString("employeeNumber", u.EnterpriseUser.EmployeeNumber)
+		enterprise.addString("costCenter", u.EnterpriseUser.CostCenter)
+		enterprise.addString("organization", u.EnterpriseUser.Organization)
+		enterprise.addString("division", u.EnterpriseUser.Division)
+		enterprise.addString("department", u.EnterpriseUser.Department)
+		if u.EnterpriseUser.Manager != nil {
+			manager := resourceValues{}
+			manager.addString("displayName", u.EnterpriseUser.Manager.DisplayName)
+			manager.addString("$ref", u.EnterpriseUser.Manager.Ref)
+			manager.addString("value", u.EnterpriseUser.Manager.Value)
+			enterprise["manager"] = map[string]interface{}(manager)
+		}
+		values[EnterpriseUserSchema] = map[string]interface{}(enterprise)
+	}
+	values.addString("externalId", u.ExternalId)
+	if len(u.Groups) > 0 {
+		groups := make([]interface{}, len(u.Groups))
+		for i, group := range u.Groups {
+			value := resourceValues{}
+			value.addString("display", group.Display)
+			value.addString("$ref", group.Ref)
+			value.addString("value", group.Value)
+			groups[i] = map[string]interface{}(value)
+		}
+		values["groups"] = groups
+	}
+	values.addMultiValues("ims", u.Ims)
+	values.addString("locale", u.Locale)
+	if u.Metadata != nil {
+		meta := resourceValues{}
+		meta.addString("created", u.Metadata.Created)
+		meta.addString("lastModified", u.Metadata.LastModified)
+		meta.addString("location", u.Metadata.Location)
+		meta.addString("resourceType", u.Metadata.ResourceType)
+		meta.addString("version", u.Metadata.Version)
+		values["meta"] = map[string]interface{}(meta)
+	} else {
+		values["meta"] = nil
+	}
+	if u.Name != nil {
+		name := resourceValues{}
+		name.addString("formatted", u.Name.Formatted)
+		name.addString("familyName", u.Name.FamilyName)
+		name.addString("givenName", u.Name.GivenName)
+		name.addString("middleName", u.Name.MiddleName)
+		name.addString("honorificPrefix", u.Name.HonorificPrefix)
+		name.addString("honorificSuffix", u.Name.HonorificSuffix)
+		values["name"] = map[string]interface{}(name)
+	}
+	values.addString("nickName", u.NickName)
+	values.addString("password", u.Password)
+	values.addMultiValues("phoneNumbers", u.PhoneNumbers)
+	values.addMultiValues("photos", u.Photos)
+	values.addString("preferredLanguage", u.PreferredLanguage)
+	values.addString("profileUrl", u.ProfileURL)
+	values.addStrings("roles", u.Roles)
+	values.addStrings("schemas", u.Schemas)
+	values.addString("timezone", u.Timezone)
+	values.addString("title", u.Title)
+	values.addString("userType", u.UserType)
+	values.addMultiValues("x509Certificates", u.X509Certificates)
+	for schema, extension := range u.Extensions {
+		values[schema] = extension
+	}
+	return values
+}
+
+//resourceValues adds the attribute values of a resource leaving out the empty values like the omitempty json tags
+type resourceValues map[string]interface{}
+
+func (v resourceValues) addBool(name string, value bool) {
+	if value {
+		v[name] = value
+	}
+}
+
+func (v resourceValues) addString(name string, value string) {
+	if len(value) > 0 {
+		v[name] = value
+	}
+}
+
+func (v resourceValues) addStrings(name string, value []string) {
+	if len(value) == 0 {
+		return
+	}
+	values := make([]interface{}, len(value))
+	for i, s := range value {
+		values[i] = s
+	}
+	v[name] = values
+}
+
+func (v resourceValues) addMultiValues(name string, value []MultiValueAttribute) {
+	if len(value) == 0 {
+		return
+	}
+	values := make([]interface{}, len(value))
+	for i, attribute := range value {
+		item := resourceValues{}
+		item.addString("type", attribute.Type)
+		item.addString("value", attribute.Value)
+		item.addBool("primary", attribute.Primary)
+		values[i] = map[string]interface{}(item)
+	}
+	v[name] = values
+}
@@ -4,6 +4,7 @@ import (
 	"bounzr/iam/config"
 	"bounzr/iam/logger"
 	"bounzr/iam/pages"
+	"bounzr/iam/password"
 	"bounzr/iam/repository"
 	packageRouter "bounzr/iam/router"
 	"bounzr/iam/token"
@@ -59,9 +60,11 @@ func main() {
 func startFunc(c *cli.Context) error {
 
 	config.Init(configFilePath)
+	password.Init()
+	//tokens and secrets generated by the repositories use the configured entropy and encoding
+	token.Init()
 	repository.Init()
 	packageRouter.Init()
-	token.Init()
 	pages.Init()
 
 	log := logger.GetLogger()
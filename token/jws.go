@@ -0,0 +1,202 @@
+package token
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+
+	"github.com/gofrs/uuid"
+)
+
+//JWS algorithms supported to sign tokens
+const (
+	RS256 = "RS256"
+	ES256 = "ES256"
+)
+
+var (
+	ErrAlgorithmNotSupported = errors.New("signing algorithm not supported")
+	ErrInvalidSignature      = errors.New("token signature is invalid")
+	ErrMalformedToken        = errors.New("token is malformed")
+)
+
+//SigningKey is a private key used to sign JSON Web Tokens
+type SigningKey struct {
+	ID         string //kid
+	Algorithm  string //RS256 or ES256
+	PrivateKey crypto.Signer
+}
+
+type jwsHeader struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid,omitempty"`
+	Type      string `json:"typ,omitempty"`
+}
+
+//NewSigningKey generates a new private key for the given algorithm
+func NewSigningKey(algorithm string) (*SigningKey, error) {
+	id, err := uuid.NewV4()
+	if err != nil {
+		return nil, err
+	}
+	var pk crypto.Signer
+	switch algorithm {
+	case RS256:
+		pk, err = rsa.GenerateKey(rand.Reader, 2048)
+	case ES256:
+		pk, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	default:
+		return nil, ErrAlgorithmNotSupported
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &SigningKey{
+		ID:         id.String(),
+		Algorithm:  algorithm,
+		PrivateKey: pk,
+	}, nil
+}
+
+//ParseSigningKey returns the signing key of a PKCS8 DER encoded private key
+func ParseSigningKey(id, algorithm string, der []byte) (*SigningKey, error) {
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, err
+	}
+	switch pk := key.(type) {
+	case *rsa.PrivateKey:
+		if algorithm != RS256 {
+			return nil, ErrAlgorithmNotSupported
+		}
+		return &SigningKey{ID: id, Algorithm: algorithm, PrivateKey: pk}, nil
+	case *ecdsa.PrivateKey:
+		if algorithm != ES256 {
+			return nil, ErrAlgorithmNotSupported
+		}
+		return &SigningKey{ID: id, Algorithm: algorithm, PrivateKey: pk}, nil
+	}
+	return nil, ErrAlgorithmNotSupported
+}
+
+//MarshalPrivateKey returns the private key PKCS8 DER encoded
+func (k *SigningKey) MarshalPrivateKey() ([]byte, error) {
+	return x509.MarshalPKCS8PrivateKey(k.PrivateKey)
+}
+
+//Sign returns the JWS compact serialization of the given claims
+func (k *SigningKey) Sign(claims interface{}) (string, error) {
+	return k.SignWithType(claims, "JWT")
+}
+
+//SignWithType returns the JWS compact serialization of the given claims using typ as header type
+func (k *SigningKey) SignWithType(claims interface{}, typ string) (string, error) {
+	header, err := json.Marshal(&jwsHeader{Algorithm: k.Algorithm, KeyID: k.ID, Type: typ})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	var signature []byte
+	switch key := k.PrivateKey.(type) {
+	case *rsa.PrivateKey:
+		signature, err = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	case *ecdsa.PrivateKey:
+		var r, s *big.Int
+		r, s, err = ecdsa.Sign(rand.Reader, key, digest[:])
+		if err == nil {
+			size := (key.Curve.Params().BitSize + 7) / 8
+			signature = make([]byte, 2*size)
+			r.FillBytes(signature[:size])
+			s.FillBytes(signature[size:])
+		}
+	default:
+		return "", ErrAlgorithmNotSupported
+	}
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+//GetKeyID returns the kid header of a JWS compact serialization without validating it
+func GetKeyID(token string) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", ErrMalformedToken
+	}
+	headerBytes, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return "", ErrMalformedToken
+	}
+	var header jwsHeader
+	err = json.Unmarshal(headerBytes, &header)
+	if err != nil {
+		return "", ErrMalformedToken
+	}
+	return header.KeyID, nil
+}
+
+//Verify validates the signature of a JWS compact serialization with the public key and returns its payload
+func Verify(token string, publicKey crypto.PublicKey) ([]byte, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformedToken
+	}
+	headerBytes, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, ErrMalformedToken
+	}
+	var header jwsHeader
+	err = json.Unmarshal(headerBytes, &header)
+	if err != nil {
+		return nil, ErrMalformedToken
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrMalformedToken
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		if header.Algorithm != RS256 {
+			return nil, ErrAlgorithmNotSupported
+		}
+		if rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) != nil {
+			return nil, ErrInvalidSignature
+		}
+	case *ecdsa.PublicKey:
+		if header.Algorithm != ES256 {
+			return nil, ErrAlgorithmNotSupported
+		}
+		size := (key.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return nil, ErrInvalidSignature
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(key, digest[:], r, s) {
+			return nil, ErrInvalidSignature
+		}
+	default:
+		return nil, ErrAlgorithmNotSupported
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrMalformedToken
+	}
+	return payload, nil
+}
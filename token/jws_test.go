@@ -0,0 +1,38 @@
+package token
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	for _, alg := range []string{RS256, ES256} {
+		key, err := NewSigningKey(alg)
+		if err != nil {
+			t.Fatalf("want %s key got error %s", alg, err.Error())
+		}
+		claims := map[string]interface{}{"sub": "someone", "iat": 1}
+		jws, err := key.Sign(claims)
+		if err != nil {
+			t.Fatalf("want %s signature got error %s", alg, err.Error())
+		}
+		kid, err := GetKeyID(jws)
+		if err != nil || kid != key.ID {
+			t.Errorf("want kid %s got %s", key.ID, kid)
+		}
+		payload, err := Verify(jws, key.PrivateKey.Public())
+		if err != nil {
+			t.Fatalf("want valid %s signature got error %s", alg, err.Error())
+		}
+		var got map[string]interface{}
+		json.Unmarshal(payload, &got)
+		if got["sub"] != "someone" {
+			t.Errorf("want sub someone got %v", got["sub"])
+		}
+		tampered := jws[:len(jws)-4] + "AAAA"
+		_, err = Verify(tampered, key.PrivateKey.Public())
+		if err == nil {
+			t.Errorf("want %s invalid signature got no error", alg)
+		}
+	}
+}
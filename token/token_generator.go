@@ -4,6 +4,12 @@ type TokenGenerator interface {
 	getToken() []byte
 }
 
+//GetToken returns a new random token used for access tokens, refresh tokens, codes and secrets
 func GetToken() []byte {
 	return tokenGenerator.getToken()
 }
+
+//GetTokenString returns a new random token as string
+func GetTokenString() string {
+	return string(tokenGenerator.getToken())
+}
@@ -0,0 +1,66 @@
+package token
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+//Token encodings supported by the secure token generator
+const (
+	Base64URLEncoding = "base64url"
+	HexEncoding       = "hex"
+)
+
+const (
+	defaultEntropy = 32 //bytes
+	minEntropy     = 16 //bytes
+)
+
+//TokenGeneratorSecure generates tokens from crypto/rand with the given entropy in bytes
+type TokenGeneratorSecure struct {
+	entropy  int
+	encoding string
+}
+
+func NewTokenGeneratorSecure(entropy int, encoding string) TokenGenerator {
+	tgs := &TokenGeneratorSecure{}
+	tgs.Init(entropy, encoding)
+	return tgs
+}
+
+func (tg *TokenGeneratorSecure) Init(entropy int, encoding string) {
+	if entropy < minEntropy {
+		entropy = defaultEntropy
+	}
+	if encoding != HexEncoding {
+		encoding = Base64URLEncoding
+	}
+	tg.entropy = entropy
+	tg.encoding = encoding
+}
+
+func (tg *TokenGeneratorSecure) getToken() []byte {
+	buf := GetRandomBytes(tg.entropy)
+	var token []byte
+	switch tg.encoding {
+	case HexEncoding:
+		token = make([]byte, hex.EncodedLen(len(buf)))
+		hex.Encode(token, buf)
+	default:
+		token = make([]byte, base64.RawURLEncoding.EncodedLen(len(buf)))
+		base64.RawURLEncoding.Encode(token, buf)
+	}
+	return token
+}
+
+//GetRandomBytes returns length random bytes read from crypto/rand
+func GetRandomBytes(length int) []byte {
+	buf := make([]byte, length)
+	_, err := rand.Read(buf)
+	if err != nil {
+		//the system random source must be available to generate any credential
+		panic("crypto/rand is not available: " + err.Error())
+	}
+	return buf
+}
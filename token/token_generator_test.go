@@ -1,12 +1,104 @@
 package token
 
-import "testing"
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"math"
+	"testing"
+)
+
+type TokenGeneratorDataProvider struct {
+	entropy  int
+	encoding string
+	length   int
+	decode   func(s string) ([]byte, error)
+}
+
+var tokenGeneratorDataProvider = []TokenGeneratorDataProvider{
+	{32, Base64URLEncoding, 43, base64.RawURLEncoding.DecodeString},
+	{16, Base64URLEncoding, 22, base64.RawURLEncoding.DecodeString},
+	{32, HexEncoding, 64, hex.DecodeString},
+	{24, HexEncoding, 48, hex.DecodeString},
+	//entropy below the minimum uses the default entropy
+	{8, HexEncoding, 64, hex.DecodeString},
+}
 
 func TestGetToken(t *testing.T) {
 	var tg TokenGenerator
-	tg = NewTokenGeneratorBasic()
+	tg = NewTokenGeneratorSecure(32, Base64URLEncoding)
 	token := tg.getToken()
 	if len(token) == 0 {
 		t.Errorf("Expected token got empty value")
 	}
 }
+
+func TestTokenLength(t *testing.T) {
+	for _, provider := range tokenGeneratorDataProvider {
+		tg := NewTokenGeneratorSecure(provider.entropy, provider.encoding)
+		token := tg.getToken()
+		if len(token) != provider.length {
+			t.Errorf("want length %d got %d for %s", provider.length, len(token), provider.encoding)
+		}
+		raw, err := provider.decode(string(token))
+		if err != nil {
+			t.Errorf("want %s token got %s", provider.encoding, token)
+		}
+		if len(raw) < minEntropy {
+			t.Errorf("want at least %d random bytes got %d", minEntropy, len(raw))
+		}
+	}
+}
+
+func TestTokenUnique(t *testing.T) {
+	tg := NewTokenGeneratorSecure(16, Base64URLEncoding)
+	tokens := make(map[string]struct{})
+	for i := 0; i < 10000; i++ {
+		token := string(tg.getToken())
+		if _, ok := tokens[token]; ok {
+			t.Fatalf("duplicated token %s", token)
+		}
+		tokens[token] = struct{}{}
+	}
+}
+
+//TestTokenDistribution verifies with a chi-squared test that every byte value is drawn uniformly
+func TestTokenDistribution(t *testing.T) {
+	const samples = 4096
+	var counts [256]int
+	for i := 0; i < samples; i++ {
+		for _, b := range GetRandomBytes(32) {
+			counts[b]++
+		}
+	}
+	expected := float64(samples*32) / 256
+	chiSquared := 0.0
+	for _, count := range counts {
+		diff := float64(count) - expected
+		chiSquared += diff * diff / expected
+	}
+	//255 degrees of freedom, p=0.0001 critical value is about 347
+	if chiSquared > 347 || math.IsNaN(chiSquared) {
+		t.Errorf("random bytes are not uniformly distributed, chi-squared=%f", chiSquared)
+	}
+}
+
+func TestTokenCharacterDistribution(t *testing.T) {
+	tg := NewTokenGeneratorSecure(30, Base64URLEncoding)
+	counts := make(map[byte]int)
+	const samples = 2000
+	for i := 0; i < samples; i++ {
+		for _, c := range tg.getToken() {
+			counts[c]++
+		}
+	}
+	//30 bytes are encoded in 40 characters without padding, each one of the 64 characters of the alphabet
+	if len(counts) != 64 {
+		t.Errorf("want 64 different characters got %d", len(counts))
+	}
+	expected := float64(samples*40) / 64
+	for c, count := range counts {
+		if math.Abs(float64(count)-expected) > expected*0.2 {
+			t.Errorf("character %q drawn %d times, expected about %.0f", c, count, expected)
+		}
+	}
+}
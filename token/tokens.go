@@ -1,9 +1,11 @@
 package token
 
+import "bounzr/iam/config"
+
 var (
-	tokenGenerator TokenGenerator
+	tokenGenerator TokenGenerator = NewTokenGeneratorSecure(defaultEntropy, Base64URLEncoding)
 )
 
 func Init() {
-	tokenGenerator = NewTokenGeneratorBasic()
+	tokenGenerator = NewTokenGeneratorSecure(config.IAM.Tokens.Entropy, config.IAM.Tokens.Encoding)
 }